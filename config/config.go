@@ -3,16 +3,401 @@ package config
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
 )
 
+// EnvPath names the environment variable that overrides the config file
+// location, for setups where the default config directory is read-only.
+const EnvPath = "JUST_STREAM_CONFIG"
+
+// noPersist disables every on-disk write for the rest of the process.
+var noPersist atomic.Bool
+
+// DisablePersistence keeps settings in memory for the session: Save becomes
+// a no-op, and every other store that writes to disk must check Persistent
+// before doing so.
+func DisablePersistence() {
+	noPersist.Store(true)
+}
+
+// Persistent reports whether settings and state may be written to disk.
+func Persistent() bool {
+	return !noPersist.Load()
+}
+
+// ReadOnlyError reports that the config file could not be written because
+// its location is read-only or not writable by the current user.
+type ReadOnlyError struct {
+	Path string
+	Err  error
+}
+
+func (e *ReadOnlyError) Error() string {
+	return fmt.Sprintf("config dir %s is read-only; set %s or XDG_CONFIG_HOME to a writable path",
+		filepath.Dir(e.Path), EnvPath)
+}
+
+func (e *ReadOnlyError) Unwrap() error {
+	return e.Err
+}
+
 // Config holds user-facing settings persisted to disk as JSON.
 type Config struct {
 	// MpvPath is an explicit path to the mpv binary.
 	// When empty, the player package falls back to exec.LookPath.
 	MpvPath string `json:"mpv_path,omitempty"`
+
+	// StopOnFileError ends stream-all playback at the first file mpv fails
+	// to play. By default the broken file is skipped.
+	StopOnFileError bool `json:"stop_on_file_error,omitempty"`
+
+	// TitleFormat templates the mpv media title, e.g.
+	// "{show} — {episode} — {name}". Placeholders: {show}, {episode},
+	// {name}, {index}. Empty uses the file name.
+	TitleFormat string `json:"title_format,omitempty"`
+
+	// MemoryLimitMB caps the RAM held by piece data across every torrent
+	// in the session, evicting least-recently-used pieces. 0 is unlimited.
+	MemoryLimitMB int `json:"memory_limit_mb,omitempty"`
+
+	// ListenPort is the torrent client's peer port. 0 picks a random port
+	// once per session and keeps it for every torrent.
+	ListenPort int `json:"listen_port,omitempty"`
+
+	// StallTimeoutSecs is how long the buffer may sit still, with peers
+	// connected, before the download is kicked. 0 uses the default of 30s;
+	// a negative value disables the watchdog.
+	StallTimeoutSecs int `json:"stall_timeout_secs,omitempty"`
+
+	// LowLatencyStart starts playback from a smaller initial buffer and
+	// tells mpv not to prebuffer, trading rebuffer risk for a faster
+	// first frame.
+	LowLatencyStart bool `json:"low_latency_start,omitempty"`
+
+	// Storage selects where piece data lives: StorageMemory (default) or
+	// StorageDisk for machines without the RAM to hold a whole file.
+	Storage string `json:"storage,omitempty"`
+
+	// StorageDir is the parent of the per-session directory used by disk
+	// storage. Empty uses os.TempDir()/just-stream.
+	StorageDir string `json:"storage_dir,omitempty"`
+
+	// KeepDownloads makes disk storage write into DownloadDir and leave the
+	// files there on exit instead of deleting the session directory.
+	// DownloadDir empty uses ~/Downloads/just-stream.
+	KeepDownloads bool   `json:"keep_downloads,omitempty"`
+	DownloadDir   string `json:"download_dir,omitempty"`
+
+	// CacheDir and CacheMaxMB locate and cap the persistent piece cache
+	// enabled with --cache. Empty uses $XDG_CACHE_HOME/just-stream and 0
+	// caps it at 10 GB.
+	CacheDir   string `json:"cache_dir,omitempty"`
+	CacheMaxMB int    `json:"cache_max_mb,omitempty"`
+
+	// Anime4KShaders is the directory holding the Anime4K .glsl files.
+	// Empty uses mpv's shaders directory.
+	Anime4KShaders string `json:"anime4k_shaders,omitempty"`
+
+	// ScreenshotDir is where mpv saves screenshots. Empty leaves mpv's
+	// default, the directory it was started from.
+	ScreenshotDir string `json:"screenshot_dir,omitempty"`
+
+	// IPCDir is where mpv's IPC socket is created on Unix, for systems
+	// whose temp directory is read-only or mounted noexec. Empty uses the
+	// OS temp directory.
+	IPCDir string `json:"ipc_dir,omitempty"`
+
+	// DefaultVolume is mpv's starting volume, 0–130. It follows the last
+	// volume set during playback. Nil leaves mpv's default.
+	DefaultVolume *int `json:"default_volume,omitempty"`
+
+	// Anime4KMode picks the Anime4K preset applied at launch: "A", "B",
+	// "C", or empty/"off" to disable the shaders.
+	Anime4KMode string `json:"anime4k_mode,omitempty"`
+
+	// Player is the media player to launch: "mpv" (default), "vlc", or
+	// "custom". Only mpv supports playlist tracking and remote control.
+	Player string `json:"player,omitempty"`
+
+	// PlayerPath is the vlc binary when Player is "vlc", or the command
+	// line when it is "custom", with {url} where the stream URL goes.
+	PlayerPath string `json:"player_path,omitempty"`
+
+	// ExtraTrackers are announce URLs added to every torrent, for magnets
+	// that carry few or no trackers of their own.
+	ExtraTrackers []string `json:"extra_trackers,omitempty"`
+
+	// UseDefaultTrackers also adds a built-in list of public trackers.
+	UseDefaultTrackers bool `json:"use_default_trackers,omitempty"`
+
+	// MetadataTimeoutSecs is how long to wait for a magnet's metadata
+	// before giving up. 0 uses the default of 60s; a negative value waits
+	// forever.
+	MetadataTimeoutSecs int `json:"metadata_timeout_secs,omitempty"`
+
+	// MaxUploadRate and MaxDownloadRate cap torrent traffic in bytes per
+	// second. 0 is unlimited.
+	MaxUploadRate   int64 `json:"max_upload_rate,omitempty"`
+	MaxDownloadRate int64 `json:"max_download_rate,omitempty"`
+
+	// SeedAfterPlayback keeps sharing downloaded pieces after the player
+	// exits. Unset means true; see SeedsAfterPlayback.
+	SeedAfterPlayback *bool `json:"seed_after_playback,omitempty"`
+
+	// StreamTokenBytes is the length, in random bytes, of the access token
+	// required on stream URLs. 0 uses the default of 16; the minimum is 8.
+	StreamTokenBytes int `json:"stream_token_bytes,omitempty"`
+
+	// HistorySize is how many recently opened torrents to remember. 0
+	// uses DefaultHistorySize.
+	HistorySize int `json:"history_size,omitempty"`
+
+	// PreferredSubLang and PreferredAudioLang pick tracks by language,
+	// e.g. "eng" or "jpn,ja". Empty leaves the choice to the player.
+	PreferredSubLang   string `json:"preferred_sub_lang,omitempty"`
+	PreferredAudioLang string `json:"preferred_audio_lang,omitempty"`
+
+	// ReadaheadBytes and ReadaheadPercent size each stream reader's
+	// readahead: ReadaheadPercent of the file, but at least ReadaheadBytes.
+	// 0 uses the defaults of 8MB and 5%. A bigger buffer helps on slow
+	// connections.
+	ReadaheadBytes   int64 `json:"readahead_bytes,omitempty"`
+	ReadaheadPercent int   `json:"readahead_percent,omitempty"`
+
+	// StartupBoostPercent is the share of a file, from its start, fetched
+	// ahead of everything else when playback begins. 0 uses the default of
+	// 5%. LowLatencyStart overrides it with 1%.
+	StartupBoostPercent int `json:"startup_boost_percent,omitempty"`
+
+	// PreloadCount is how many episodes after the playing one have their
+	// start fetched ahead in stream-all mode. Nil means 1; 0 turns
+	// preloading off.
+	PreloadCount *int `json:"preload_count,omitempty"`
+
+	// MpvArgs are extra flags passed to mpv, e.g. "--hwdec=auto". Flags
+	// just-stream sets itself take precedence over these, except the cache
+	// settings below, which these override.
+	MpvArgs []string `json:"mpv_args,omitempty"`
+
+	// MpvCache turns on mpv's own cache to smooth over network jitter.
+	// Unset means true; see MpvCacheEnabled. MpvCacheSecs and
+	// MpvDemuxerMaxMB size it; 0 uses DefaultMpvCacheSecs and
+	// DefaultMpvDemuxerMaxMB.
+	MpvCache        *bool `json:"mpv_cache,omitempty"`
+	MpvCacheSecs    int   `json:"mpv_cache_secs,omitempty"`
+	MpvDemuxerMaxMB int   `json:"mpv_demuxer_max_mb,omitempty"`
+
+	// StreamListenAddr is the host:port the stream server binds. Empty is
+	// localhost only; "0.0.0.0:0" or a LAN IP lets other devices, e.g. a
+	// TV, play the stream, protected only by the URL's access token.
+	StreamListenAddr string `json:"stream_listen_addr,omitempty"`
+
+	// DLNAHeaders adds the DLNA response headers some smart TVs and
+	// renderers refuse to play without.
+	DLNAHeaders bool `json:"dlna_headers,omitempty"`
+
+	// ProxyBypass lists trackers that are contacted directly even when a
+	// proxy is set, in NO_PROXY style: host names, ".domain" or
+	// "*.domain" suffixes, IPs and CIDR ranges.
+	ProxyBypass []string `json:"proxy_bypass,omitempty"`
+
+	// ProxyUDP relays DHT traffic through a SOCKS5 proxy with UDP
+	// ASSOCIATE. Many proxies don't support it, so DHT stays off behind a
+	// proxy unless this is set, and if the proxy refuses.
+	ProxyUDP bool `json:"proxy_udp,omitempty"`
+
+	// ShowTips rotates short tips on the loading screen while metadata is
+	// fetched. Unset means true; see ShowsTips.
+	ShowTips *bool `json:"show_tips,omitempty"`
+
+	// AutoplayAll makes enter on the file list stream every file from the
+	// cursor on, like A, when the torrent has more than one media file.
+	AutoplayAll bool `json:"autoplay_all,omitempty"`
+
+	// AutoplaySingleFile starts playback as soon as the metadata arrives
+	// when the torrent has exactly one media file, skipping the file list.
+	AutoplaySingleFile bool `json:"autoplay_single_file,omitempty"`
+
+	// MediaExtensions, when set, replaces the built-in list of extensions
+	// shown as media files; ExtraMediaExtensions adds to whichever list is
+	// in use. Entries like "divx" or ".DivX" are normalized to ".divx".
+	MediaExtensions      []string `json:"media_extensions,omitempty"`
+	ExtraMediaExtensions []string `json:"extra_media_extensions,omitempty"`
+
+	// Keybindings maps action names (see KeyActions) to keys such as "n"
+	// or "ctrl+d". Unmapped actions keep their default keys.
+	Keybindings map[string]string `json:"keybindings,omitempty"`
+
+	// Theme names the TUI color preset (see Themes); empty is "default".
+	// ThemeColors overrides single colors of it, mapping a role from
+	// ThemeColorRoles to "#rrggbb" or an ANSI color number 0-255.
+	Theme       string            `json:"theme,omitempty"`
+	ThemeColors map[string]string `json:"theme_colors,omitempty"`
+
+	// Advanced torrent client tuning. 0 keeps the library default.
+	//
+	// PieceHashers is how many pieces per torrent are hash-checked at
+	// once; lower it to cut CPU spikes on large torrents.
+	PieceHashers int `json:"piece_hashers,omitempty"`
+	// MaxConnsPerTorrent caps established peer connections per torrent,
+	// and HalfOpenConnsPerTorrent the connection attempts in flight.
+	MaxConnsPerTorrent      int `json:"max_conns_per_torrent,omitempty"`
+	HalfOpenConnsPerTorrent int `json:"half_open_conns_per_torrent,omitempty"`
+	// DisableAggressiveUpload stops unchoking peers beyond what
+	// reciprocation calls for, saving upstream bandwidth.
+	DisableAggressiveUpload bool `json:"disable_aggressive_upload,omitempty"`
+
+	// env holds the keys ApplyEnv overrode, so Save doesn't write the
+	// environment into the file.
+	env map[string]envOverride
+}
+
+// DefaultPreloadCount is the look-ahead used when PreloadCount is unset.
+const DefaultPreloadCount = 1
+
+// Preload returns how many following episodes to preload.
+func (c *Config) Preload() int {
+	if c.PreloadCount == nil {
+		return DefaultPreloadCount
+	}
+	return max(*c.PreloadCount, 0)
+}
+
+// Defaults for mpv's cache, tuned for HTTP streaming: a generous demuxer
+// buffer rides out peers stalling without holding a whole episode.
+const (
+	DefaultMpvCacheSecs    = 120
+	DefaultMpvDemuxerMaxMB = 256
+)
+
+// MpvCacheEnabled reports whether mpv's cache is turned on. It stays on
+// unless explicitly disabled.
+func (c *Config) MpvCacheEnabled() bool {
+	return c.MpvCache == nil || *c.MpvCache
+}
+
+// ShowsTips reports whether the loading screen shows tips. They stay on
+// unless explicitly disabled.
+func (c *Config) ShowsTips() bool {
+	return c.ShowTips == nil || *c.ShowTips
+}
+
+// SeedsAfterPlayback reports whether to keep seeding once playback ends.
+// Seeding stays on unless explicitly disabled.
+func (c *Config) SeedsAfterPlayback() bool {
+	return c.SeedAfterPlayback == nil || *c.SeedAfterPlayback
+}
+
+// Storage modes accepted by Config.Storage and the --storage flag.
+const (
+	StorageMemory = "memory"
+	StorageDisk   = "disk"
+)
+
+// ValidStorage reports whether mode names a storage backend. Empty is
+// valid and means StorageMemory.
+func ValidStorage(mode string) bool {
+	switch mode {
+	case "", StorageMemory, StorageDisk:
+		return true
+	}
+	return false
+}
+
+// KeyActions are the action names Keybindings accepts.
+var KeyActions = []string{"down", "up", "top", "bottom", "play", "stream_all", "quit", "config"}
+
+// ReservedKeys are the fixed keys of the file list and playback screens.
+// Binding an action to one would hide what the key does there.
+var ReservedKeys = []string{
+	"/", "s", "r", "y", "A", "n", "esc", "ctrl+c",
+	"up", "down", "home", "end",
+	"m", "p", "t", "i", " ", "left", "right",
+	"+", "=", "-", "[", "]", "backspace", "z", "Z", "x", "X",
+}
+
+// CheckKeybindings reports unknown actions, empty keys, reserved keys and
+// keys bound to more than one action.
+func CheckKeybindings(bindings map[string]string) error {
+	actions := make([]string, 0, len(bindings))
+	for action := range bindings {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	byKey := map[string]string{}
+	for _, action := range actions {
+		key := bindings[action]
+		if !slices.Contains(KeyActions, action) {
+			return fmt.Errorf("unknown key action %q (want one of %s)", action, strings.Join(KeyActions, ", "))
+		}
+		if key == "" {
+			return fmt.Errorf("key action %q: empty key", action)
+		}
+		if slices.Contains(ReservedKeys, key) {
+			return fmt.Errorf("key action %q: %q is a fixed key and can't be rebound", action, key)
+		}
+		if other, ok := byKey[key]; ok {
+			return fmt.Errorf("key %q is bound to both %s and %s", key, other, action)
+		}
+		byKey[key] = action
+	}
+	return nil
+}
+
+// Themes are the color presets Config.Theme accepts.
+var Themes = []string{"default", "mono", "solarized"}
+
+// ValidTheme reports whether name is a preset. Empty is valid and means
+// "default".
+func ValidTheme(name string) bool {
+	return name == "" || slices.Contains(Themes, name)
+}
+
+// ThemeColorRoles are the names ThemeColors can override.
+var ThemeColorRoles = []string{
+	"accent", "subtitle", "text", "dim", "help", "status",
+	"error", "warn", "playing", "seeding", "bar_empty",
+}
+
+// CheckThemeColors reports unknown roles and colors that are neither
+// "#rgb"/"#rrggbb" nor an ANSI color number.
+func CheckThemeColors(colors map[string]string) error {
+	roles := make([]string, 0, len(colors))
+	for role := range colors {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	for _, role := range roles {
+		if !slices.Contains(ThemeColorRoles, role) {
+			return fmt.Errorf("unknown theme color %q (want one of %s)", role, strings.Join(ThemeColorRoles, ", "))
+		}
+		if !validColor(colors[role]) {
+			return fmt.Errorf("theme color %s: %q is not #rrggbb or 0-255", role, colors[role])
+		}
+	}
+	return nil
+}
+
+func validColor(c string) bool {
+	if hex, ok := strings.CutPrefix(c, "#"); ok {
+		if len(hex) != 3 && len(hex) != 6 {
+			return false
+		}
+		_, err := strconv.ParseUint(hex, 16, 32)
+		return err == nil
+	}
+	n, err := strconv.Atoi(c)
+	return err == nil && n >= 0 && n <= 255
 }
 
 // configDir returns the platform-appropriate config directory:
@@ -44,8 +429,12 @@ func configDir() (string, error) {
 	return filepath.Join(dir, "just-stream"), nil
 }
 
-// Path returns the full path to the config JSON file.
+// Path returns the full path to the config JSON file. JUST_STREAM_CONFIG
+// takes precedence over the platform default.
 func Path() (string, error) {
+	if p := os.Getenv(EnvPath); p != "" {
+		return p, nil
+	}
 	dir, err := configDir()
 	if err != nil {
 		return "", err
@@ -77,21 +466,43 @@ func Load() (*Config, error) {
 }
 
 // Save writes the config to disk, creating the directory if needed.
+// Permission and read-only filesystem failures are returned as a
+// *ReadOnlyError. Save does nothing when persistence is disabled.
+// Values set from the environment by ApplyEnv are not written.
 func Save(cfg *Config) error {
+	if !Persistent() {
+		return nil
+	}
 	p, err := Path()
 	if err != nil {
 		return err
 	}
 
 	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
-		return err
+		return writeErr(p, err)
 	}
 
-	data, err := json.MarshalIndent(cfg, "", "  ")
+	file, err := cfg.fileView()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
 	if err != nil {
 		return err
 	}
 	data = append(data, '\n')
 
-	return os.WriteFile(p, data, 0o600)
+	return writeErr(p, os.WriteFile(p, data, 0o600))
+}
+
+// writeErr classifies a failed write to path, wrapping permission and
+// read-only filesystem errors in a *ReadOnlyError.
+func writeErr(path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, fs.ErrPermission) || errors.Is(err, syscall.EROFS) {
+		return &ReadOnlyError{Path: path, Err: err}
+	}
+	return err
 }
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckKeybindings(t *testing.T) {
+	tests := []struct {
+		name     string
+		bindings map[string]string
+		wantErr  string
+	}{
+		{"none", nil, ""},
+		{"valid", map[string]string{"down": "ctrl+n", "up": "ctrl+p", "quit": "ctrl+q"}, ""},
+		{"unknown action", map[string]string{"jump": "J"}, "unknown key action"},
+		{"empty key", map[string]string{"down": ""}, "empty key"},
+		{"duplicate", map[string]string{"down": "e", "up": "e"}, "bound to both"},
+		{"fixed file list key", map[string]string{"down": "s"}, "fixed key"},
+		{"fixed playback key", map[string]string{"quit": "t"}, "fixed key"},
+		{"stream from cursor", map[string]string{"stream_all": "A"}, "fixed key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckKeybindings(tt.bindings)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("error %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
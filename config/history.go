@@ -0,0 +1,58 @@
+package config
+
+import (
+	"sync"
+	"time"
+)
+
+const historyFile = "history.json"
+
+// DefaultHistorySize caps the history when Config.HistorySize is 0.
+const DefaultHistorySize = 20
+
+// HistoryEntry is a torrent that was opened successfully.
+type HistoryEntry struct {
+	URI      string    `json:"uri"` // magnet URI or .torrent path
+	Name     string    `json:"name"`
+	InfoHash string    `json:"info_hash"`
+	At       time.Time `json:"at"`
+}
+
+// historyMu serializes read-modify-write cycles on the history file.
+var historyMu sync.Mutex
+
+// LoadHistory returns the history, most recent first.
+func LoadHistory() ([]HistoryEntry, error) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	var entries []HistoryEntry
+	if err := readState(historyFile, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// AddHistory puts e at the top of the history, replacing any entry for
+// the same infohash, and keeps at most limit entries (DefaultHistorySize
+// when limit is 0).
+func AddHistory(e HistoryEntry, limit int) error {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	if limit <= 0 {
+		limit = DefaultHistorySize
+	}
+	var entries []HistoryEntry
+	if err := readState(historyFile, &entries); err != nil {
+		return err
+	}
+	kept := []HistoryEntry{e}
+	for _, old := range entries {
+		if old.InfoHash != e.InfoHash {
+			kept = append(kept, old)
+		}
+	}
+	if len(kept) > limit {
+		kept = kept[:limit]
+	}
+	return writeState(historyFile, kept)
+}
@@ -0,0 +1,264 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// UnknownKeyError reports a key that names no config field.
+type UnknownKeyError struct {
+	Key string
+}
+
+func (e *UnknownKeyError) Error() string {
+	return fmt.Sprintf("unknown config key %q (known keys: %s)", e.Key, strings.Join(Keys(), ", "))
+}
+
+// Keys returns the JSON keys of every config field, sorted.
+func Keys() []string {
+	t := reflect.TypeOf(Config{})
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if k := jsonKey(t.Field(i)); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Get returns the value of the field with the given JSON key. Lists and
+// maps are returned as JSON; unset values as the empty string.
+func (c *Config) Get(key string) (string, error) {
+	v, err := c.field(key)
+	if err != nil {
+		return "", err
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Slice, reflect.Map:
+		if v.Len() == 0 {
+			return "", nil
+		}
+		data, err := json.Marshal(v.Interface())
+		return string(data), err
+	}
+	return "", fmt.Errorf("config key %q: unsupported type %s", key, v.Type())
+}
+
+// Set parses value into the field with the given JSON key. Lists take a
+// JSON array or a comma-separated list, maps a JSON object or a
+// comma-separated list of name=value pairs; an empty value clears the
+// field.
+func (c *Config) Set(key, value string) error {
+	v, err := c.field(key)
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		v.SetZero()
+		return nil
+	}
+	if v.Kind() == reflect.Pointer {
+		p := reflect.New(v.Type().Elem())
+		if err := setValue(p.Elem(), value); err != nil {
+			return fmt.Errorf("config key %q: %w", key, err)
+		}
+		v.Set(p)
+		return nil
+	}
+	if err := setValue(v, value); err != nil {
+		return fmt.Errorf("config key %q: %w", key, err)
+	}
+	return nil
+}
+
+// field returns the settable field with the given JSON key.
+func (c *Config) field(key string) (reflect.Value, error) {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if jsonKey(t.Field(i)) == key {
+			return v.Field(i), nil
+		}
+	}
+	return reflect.Value{}, &UnknownKeyError{Key: key}
+}
+
+func setValue(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", value)
+		}
+		v.SetInt(n)
+	case reflect.Slice:
+		var list []string
+		if strings.HasPrefix(value, "[") {
+			if err := json.Unmarshal([]byte(value), &list); err != nil {
+				return fmt.Errorf("parse list: %w", err)
+			}
+		} else {
+			for _, s := range strings.Split(value, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					list = append(list, s)
+				}
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+	case reflect.Map:
+		m, err := ParsePairs(value)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(m))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// ParsePairs parses a JSON object or a comma-separated list of name=value
+// pairs, e.g. "down=n, up=e".
+func ParsePairs(value string) (map[string]string, error) {
+	m := map[string]string{}
+	if strings.HasPrefix(value, "{") {
+		if err := json.Unmarshal([]byte(value), &m); err != nil {
+			return nil, fmt.Errorf("parse map: %w", err)
+		}
+		return m, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, val, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not name=value", pair)
+		}
+		m[strings.TrimSpace(name)] = strings.TrimSpace(val)
+	}
+	return m, nil
+}
+
+// FormatPairs is the inverse of ParsePairs' list form, sorted by name.
+func FormatPairs(m map[string]string) string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + m[name]
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// jsonKey returns the name a struct field is stored under in config.json.
+func jsonKey(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// EnvPrefix starts the environment variable that overrides each config
+// key, e.g. JUST_STREAM_MPV_PATH for mpv_path.
+const EnvPrefix = "JUST_STREAM_"
+
+// EnvName returns the environment variable that overrides key.
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(key)
+}
+
+// envOverride remembers a key set from the environment: the value it had
+// before, from the file, and the value the environment gave it.
+type envOverride struct {
+	file, env string
+}
+
+// ApplyEnv overrides cfg with every JUST_STREAM_<KEY> variable that is set
+// and non-empty, so headless setups need no config file. Values parse as
+// in Set. Every variable is applied even if one fails; the errors are
+// joined. The overrides are remembered so Save keeps the file's own values
+// for them.
+func ApplyEnv(cfg *Config) error {
+	var errs []error
+	for _, key := range Keys() {
+		v := os.Getenv(EnvName(key))
+		if v == "" {
+			continue
+		}
+		file, err := cfg.Get(key)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", EnvName(key), err))
+			continue
+		}
+		if err := cfg.Set(key, v); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", EnvName(key), err))
+			continue
+		}
+		env, err := cfg.Get(key)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", EnvName(key), err))
+			continue
+		}
+		if cfg.env == nil {
+			cfg.env = map[string]envOverride{}
+		}
+		cfg.env[key] = envOverride{file: file, env: env}
+	}
+	return errors.Join(errs...)
+}
+
+// fileView returns c as it should be written to config.json: every key
+// still holding its environment value goes back to the file's value. Keys
+// changed since, e.g. in the settings screen, keep the new value.
+func (c *Config) fileView() (*Config, error) {
+	if len(c.env) == 0 {
+		return c, nil
+	}
+	file := *c
+	file.env = nil
+	for key, o := range c.env {
+		cur, err := c.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		if cur != o.env {
+			continue
+		}
+		if err := file.Set(key, o.file); err != nil {
+			return nil, err
+		}
+	}
+	return &file, nil
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyEnv(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		file Config
+		want func(*Config) bool
+	}{
+		{
+			name: "unset keeps the file value",
+			file: Config{MpvPath: "/usr/bin/mpv"},
+			want: func(c *Config) bool { return c.MpvPath == "/usr/bin/mpv" },
+		},
+		{
+			name: "empty keeps the file value",
+			env:  map[string]string{"JUST_STREAM_MPV_PATH": ""},
+			file: Config{MpvPath: "/usr/bin/mpv"},
+			want: func(c *Config) bool { return c.MpvPath == "/usr/bin/mpv" },
+		},
+		{
+			name: "set overrides the file value",
+			env:  map[string]string{"JUST_STREAM_MPV_PATH": "/opt/mpv"},
+			file: Config{MpvPath: "/usr/bin/mpv"},
+			want: func(c *Config) bool { return c.MpvPath == "/opt/mpv" },
+		},
+		{
+			name: "set overrides the default",
+			env:  map[string]string{"JUST_STREAM_STORAGE": "disk", "JUST_STREAM_PLAYER": "vlc"},
+			want: func(c *Config) bool { return c.Storage == StorageDisk && c.Player == "vlc" },
+		},
+		{
+			name: "ints, bools and lists parse",
+			env: map[string]string{
+				"JUST_STREAM_LISTEN_PORT":    "6881",
+				"JUST_STREAM_SHOW_TIPS":      "false",
+				"JUST_STREAM_EXTRA_TRACKERS": "udp://a:1, udp://b:2",
+			},
+			want: func(c *Config) bool {
+				return c.ListenPort == 6881 && !c.ShowsTips() &&
+					len(c.ExtraTrackers) == 2 && c.ExtraTrackers[1] == "udp://b:2"
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg := tt.file
+			if err := ApplyEnv(&cfg); err != nil {
+				t.Fatalf("ApplyEnv: %v", err)
+			}
+			if !tt.want(&cfg) {
+				t.Errorf("unexpected config %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestApplyEnvBadValue(t *testing.T) {
+	t.Setenv("JUST_STREAM_LISTEN_PORT", "many")
+	t.Setenv("JUST_STREAM_MPV_PATH", "/opt/mpv")
+	cfg := Config{ListenPort: 6881}
+	if err := ApplyEnv(&cfg); err == nil {
+		t.Error("ApplyEnv accepted a non-numeric port")
+	}
+	if cfg.ListenPort != 6881 || cfg.MpvPath != "/opt/mpv" {
+		t.Errorf("got port %d, mpv path %q; want the bad value skipped and the rest applied", cfg.ListenPort, cfg.MpvPath)
+	}
+}
+
+func TestSaveKeepsFileValuesOfEnvOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	t.Setenv(EnvPath, path)
+	if err := Save(&Config{MpvPath: "/usr/bin/mpv", Player: "mpv"}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("JUST_STREAM_MPV_PATH", "/opt/mpv")
+	t.Setenv("JUST_STREAM_PLAYER", "vlc")
+	t.Setenv("JUST_STREAM_STORAGE", "disk")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyEnv(cfg); err != nil {
+		t.Fatal(err)
+	}
+	// Changed in the settings screen after the override: the new value
+	// is the user's and gets saved.
+	cfg.Player = "custom"
+	cfg.ListenPort = 6881
+	if err := Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MpvPath != "/opt/mpv" {
+		t.Errorf("Save changed the in-memory config: mpv path %q", cfg.MpvPath)
+	}
+
+	saved, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.MpvPath != "/usr/bin/mpv" {
+		t.Errorf("mpv_path = %q, want the file value", saved.MpvPath)
+	}
+	if saved.Storage != "" {
+		t.Errorf("storage = %q, want it left unset", saved.Storage)
+	}
+	if saved.Player != "custom" || saved.ListenPort != 6881 {
+		t.Errorf("player = %q, listen_port = %d; want the edits saved", saved.Player, saved.ListenPort)
+	}
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"sync"
+	"time"
+)
+
+const lastFileFile = "last_file.json"
+
+// maxLastFiles caps how many torrents the last-played file is kept for;
+// the least recently played are dropped.
+const maxLastFiles = 500
+
+// lastFile records the file last played from a torrent, by its index in
+// the torrent's own file order.
+type lastFile struct {
+	Index     int       `json:"index"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// lastFileMu serializes read-modify-write cycles on the last-file state.
+var lastFileMu sync.Mutex
+
+// LastFile returns the index, in the torrent's own file order, of the file
+// last played from the torrent with the given infohash.
+func LastFile(infoHash string) (int, bool) {
+	lastFileMu.Lock()
+	defer lastFileMu.Unlock()
+	entries := map[string]lastFile{}
+	if err := readState(lastFileFile, &entries); err != nil {
+		return 0, false
+	}
+	e, ok := entries[infoHash]
+	return e.Index, ok
+}
+
+// SaveLastFile records fileIndex as the file last played from infoHash.
+func SaveLastFile(infoHash string, fileIndex int) error {
+	lastFileMu.Lock()
+	defer lastFileMu.Unlock()
+	entries := map[string]lastFile{}
+	if err := readState(lastFileFile, &entries); err != nil {
+		return err
+	}
+	entries[infoHash] = lastFile{Index: fileIndex, UpdatedAt: time.Now()}
+	trimOldest(entries, maxLastFiles, func(e lastFile) time.Time { return e.UpdatedAt })
+	return writeState(lastFileFile, entries)
+}
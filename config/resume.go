@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const resumeFile = "resume.json"
+
+// maxPositions caps how many files a position is kept for; the least
+// recently saved are dropped.
+const maxPositions = 500
+
+// FinishedMargin is how close to the end a saved position counts as
+// finished. Finished positions are cleared so playback doesn't resume at
+// the credits.
+const FinishedMargin = 5.0 // seconds
+
+// Position is a saved playback position for one file of a torrent.
+type Position struct {
+	Seconds   float64   `json:"seconds"`
+	Duration  float64   `json:"duration,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// resumeMu serializes read-modify-write cycles on the resume file.
+var resumeMu sync.Mutex
+
+// ResumeKey identifies a file by torrent infohash and its index in the
+// torrent's own file order, which doesn't change with list sorting.
+func ResumeKey(infoHash string, fileIndex int) string {
+	return fmt.Sprintf("%s:%d", infoHash, fileIndex)
+}
+
+// LookupPosition returns the saved position in seconds for key.
+func LookupPosition(key string) (float64, bool) {
+	resumeMu.Lock()
+	defer resumeMu.Unlock()
+	positions := map[string]Position{}
+	if err := readState(resumeFile, &positions); err != nil {
+		return 0, false
+	}
+	p, ok := positions[key]
+	if !ok || p.Seconds <= 0 {
+		return 0, false
+	}
+	return p.Seconds, true
+}
+
+// SavePosition records where playback of key stopped. Positions within
+// FinishedMargin of the duration clear the entry instead. A position of 0
+// or less says nothing about where playback is, e.g. when the player
+// stopped before reporting one, and keeps whatever was saved.
+func SavePosition(key string, seconds, duration float64) error {
+	if seconds <= 0 {
+		return nil
+	}
+	resumeMu.Lock()
+	defer resumeMu.Unlock()
+	positions := map[string]Position{}
+	if err := readState(resumeFile, &positions); err != nil {
+		return err
+	}
+	if duration > 0 && duration-seconds <= FinishedMargin {
+		if _, ok := positions[key]; !ok {
+			return nil
+		}
+		delete(positions, key)
+	} else {
+		positions[key] = Position{Seconds: seconds, Duration: duration, UpdatedAt: time.Now()}
+		trimOldest(positions, maxPositions, func(p Position) time.Time { return p.UpdatedAt })
+	}
+	return writeState(resumeFile, positions)
+}
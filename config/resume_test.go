@@ -0,0 +1,65 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSavePosition(t *testing.T) {
+	t.Setenv(EnvPath, filepath.Join(t.TempDir(), "config.json"))
+	key := ResumeKey("abc", 2)
+
+	if err := SavePosition(key, 600, 1400); err != nil {
+		t.Fatal(err)
+	}
+	if pos, ok := LookupPosition(key); !ok || pos != 600 {
+		t.Fatalf("after saving 600s: %v, %v", pos, ok)
+	}
+
+	// Stopping before the player reported a position keeps the old one.
+	if err := SavePosition(key, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if pos, ok := LookupPosition(key); !ok || pos != 600 {
+		t.Errorf("after saving 0s: %v, %v; want the 600s position kept", pos, ok)
+	}
+
+	// Stopping at the credits clears it.
+	if err := SavePosition(key, 1400-FinishedMargin/2, 1400); err != nil {
+		t.Fatal(err)
+	}
+	if pos, ok := LookupPosition(key); ok {
+		t.Errorf("after finishing: %v saved, want none", pos)
+	}
+}
+
+func TestSavePositionTrimsOldest(t *testing.T) {
+	t.Setenv(EnvPath, filepath.Join(t.TempDir(), "config.json"))
+	positions := map[string]Position{}
+	old := time.Now().Add(-time.Hour)
+	for i := 0; i < maxPositions; i++ {
+		positions[ResumeKey("old", i)] = Position{Seconds: 60, UpdatedAt: old.Add(time.Duration(i) * time.Second)}
+	}
+	if err := writeState(resumeFile, positions); err != nil {
+		t.Fatal(err)
+	}
+
+	key := ResumeKey("new", 0)
+	if err := SavePosition(key, 60, 0); err != nil {
+		t.Fatal(err)
+	}
+	saved := map[string]Position{}
+	if err := readState(resumeFile, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != maxPositions {
+		t.Errorf("%d positions saved, want %d", len(saved), maxPositions)
+	}
+	if _, ok := saved[key]; !ok {
+		t.Error("the new position was trimmed")
+	}
+	if _, ok := saved[ResumeKey("old", 0)]; ok {
+		t.Error("the oldest position was kept")
+	}
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// statePath returns the path of a state file stored next to config.json.
+func statePath(name string) (string, error) {
+	p, err := Path()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(p), name), nil
+}
+
+// readState decodes the named state file into v. A missing file leaves v
+// untouched and is not an error.
+func readState(name string, v any) error {
+	p, err := statePath(name)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// writeState encodes v into the named state file. It does nothing when
+// persistence is disabled.
+func writeState(name string, v any) error {
+	if !Persistent() {
+		return nil
+	}
+	p, err := statePath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return writeErr(p, err)
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	return writeErr(p, os.WriteFile(p, data, 0o600))
+}
+
+// trimOldest drops the least recently updated entries of a per-torrent
+// state map until at most limit remain.
+func trimOldest[V any](entries map[string]V, limit int, updated func(V) time.Time) {
+	if len(entries) <= limit {
+		return
+	}
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return updated(entries[keys[i]]).After(updated(entries[keys[j]])) })
+	for _, k := range keys[limit:] {
+		delete(entries, k)
+	}
+}
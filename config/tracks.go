@@ -0,0 +1,55 @@
+package config
+
+import (
+	"sync"
+	"time"
+)
+
+const trackChoiceFile = "tracks.json"
+
+// maxTrackChoices caps how many torrents track choices are kept for; the
+// least recently changed are dropped.
+const maxTrackChoices = 500
+
+// TrackChoice is the audio and subtitle track picked for a torrent. Track
+// IDs differ between files, so the choice is kept by language and applies
+// to every episode.
+type TrackChoice struct {
+	AudioLang string    `json:"audio_lang,omitempty"`
+	SubLang   string    `json:"sub_lang,omitempty"`
+	SubsOff   bool      `json:"subs_off,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// trackChoiceMu serializes read-modify-write cycles on the track choices.
+var trackChoiceMu sync.Mutex
+
+// LookupTrackChoice returns the tracks picked for the torrent with the
+// given infohash.
+func LookupTrackChoice(infoHash string) (TrackChoice, bool) {
+	trackChoiceMu.Lock()
+	defer trackChoiceMu.Unlock()
+	entries := map[string]TrackChoice{}
+	if err := readState(trackChoiceFile, &entries); err != nil {
+		return TrackChoice{}, false
+	}
+	c, ok := entries[infoHash]
+	return c, ok
+}
+
+// UpdateTrackChoice applies update to the choice stored for infoHash and
+// saves it.
+func UpdateTrackChoice(infoHash string, update func(*TrackChoice)) error {
+	trackChoiceMu.Lock()
+	defer trackChoiceMu.Unlock()
+	entries := map[string]TrackChoice{}
+	if err := readState(trackChoiceFile, &entries); err != nil {
+		return err
+	}
+	c := entries[infoHash]
+	update(&c)
+	c.UpdatedAt = time.Now()
+	entries[infoHash] = c
+	trimOldest(entries, maxTrackChoices, func(c TrackChoice) time.Time { return c.UpdatedAt })
+	return writeState(trackChoiceFile, entries)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/enrell/just-stream/config"
+	"github.com/enrell/just-stream/tui"
+)
+
+const configUsage = `usage:
+  just-stream config path
+  just-stream config get <key>
+  just-stream config set <key> <value>
+  just-stream config keys`
+
+// runConfigCommand handles `just-stream config ...` for scripting and
+// dotfile management, and returns the process exit code.
+func runConfigCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, configUsage)
+		return 2
+	}
+
+	switch args[0] {
+	case "path":
+		p, err := config.Path()
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, p)
+		return 0
+	case "keys":
+		fmt.Fprintln(stdout, strings.Join(config.Keys(), "\n"))
+		return 0
+	case "get":
+		if len(args) != 2 {
+			fmt.Fprintln(stderr, configUsage)
+			return 2
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: load config: %v\n", err)
+			return 1
+		}
+		v, err := cfg.Get(args[1])
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, v)
+		return 0
+	case "set":
+		if len(args) != 3 {
+			fmt.Fprintln(stderr, configUsage)
+			return 2
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: load config: %v\n", err)
+			return 1
+		}
+		if err := cfg.Set(args[1], args[2]); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		if err := validateConfig(cfg); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		if err := config.Save(cfg); err != nil {
+			fmt.Fprintf(stderr, "Error: save config: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	fmt.Fprintf(stderr, "Error: unknown config command %q\n%s\n", args[0], configUsage)
+	return 2
+}
+
+// validateConfig applies the checks the TUI settings screen enforces, so
+// a scripted set can't store a value just-stream rejects.
+func validateConfig(cfg *config.Config) error {
+	// In a fixed order so the same config always reports the same key, and
+	// ahead of the field parsers for a message that names the default.
+	for _, c := range []struct {
+		key, zero string
+		n         int
+	}{
+		{"piece_hashers", "the library default", cfg.PieceHashers},
+		{"max_conns_per_torrent", "the library default", cfg.MaxConnsPerTorrent},
+		{"half_open_conns_per_torrent", "the library default", cfg.HalfOpenConnsPerTorrent},
+		{"mpv_cache_secs", strconv.Itoa(config.DefaultMpvCacheSecs), cfg.MpvCacheSecs},
+		{"mpv_demuxer_max_mb", strconv.Itoa(config.DefaultMpvDemuxerMaxMB), cfg.MpvDemuxerMaxMB},
+	} {
+		if c.n < 0 {
+			return fmt.Errorf("%s must not be negative (0 uses %s)", c.key, c.zero)
+		}
+	}
+	return tui.CheckConfig(cfg)
+}
@@ -1,26 +1,110 @@
 package main
 
 import (
+	"bufio"
+	"cmp"
+	"context"
 	"flag"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 
-"github.com/enrell/just-stream/config"
+	"github.com/enrell/just-stream/config"
+	"github.com/enrell/just-stream/player"
 	memstorage "github.com/enrell/just-stream/storage"
+	"github.com/enrell/just-stream/stream"
 	"github.com/enrell/just-stream/tui"
 )
 
 func main() {
+	// Subcommands come before flag parsing; without one the TUI starts
+	// as usual.
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "config":
+			os.Exit(runConfigCommand(os.Args[2:], os.Stdout, os.Stderr))
+		case "version":
+			printVersion(os.Stdout)
+			os.Exit(0)
+		}
+	}
+
 	proxyFlag := flag.String("proxy", "", "proxy URL (socks5://host:port or http://host:port)")
 	flag.StringVar(proxyFlag, "x", "", "proxy URL (shorthand for -proxy)")
+	debugFlag := flag.Bool("debug", false, "show extra diagnostics on the playback screen")
+	noPersistFlag := flag.Bool("no-persist", false, "keep settings in memory only; write nothing to disk")
+	playerFlag := flag.String("player", "", "media player: mpv, vlc or custom (default from config, else mpv)")
+	maxUpFlag := flag.Int64("max-up", -1, "upload limit in bytes/s, 0 for unlimited (default from config)")
+	maxDownFlag := flag.Int64("max-down", -1, "download limit in bytes/s, 0 for unlimited (default from config)")
+	storageFlag := flag.String("storage", "", "where to keep piece data: memory or disk (default from config, else memory)")
+	storageDirFlag := flag.String("storage-dir", "", "parent directory for disk storage (default $TMPDIR/just-stream)")
+	listenFlag := flag.String("listen", "", "stream server address, e.g. 0.0.0.0:0 to cast on the LAN or unix:/path for a Unix socket (default from config, else 127.0.0.1:0)")
+	versionFlag := flag.Bool("version", false, "print version and build information, then exit")
+	cacheFlag := flag.Bool("cache", false, "keep downloaded pieces in a disk cache so re-watching skips the download (memory storage only)")
+	logFlag := flag.String("log", "", "write a debug log to this file (default $JUST_STREAM_LOG, else no log)")
+	listFlag := flag.Bool("list", false, "print the torrent's media files and exit without playing")
+	jsonFlag := flag.Bool("json", false, "with --list, print the files as JSON")
 	flag.Parse()
 
-	// Accept magnet link as positional argument to skip the input screen.
+	if *versionFlag {
+		printVersion(os.Stdout)
+		os.Exit(0)
+	}
+
+	logPath := *logFlag
+	if logPath == "" {
+		logPath = os.Getenv(envLog)
+	}
+	logFile, err := setupLogging(logPath, *debugFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not open log: %v\n", err)
+	}
+	// os.Exit skips deferred calls, so every exit below goes through
+	// exit to close the log first.
+	exit := func(code int) {
+		if logFile != nil {
+			slog.Info("exiting", "code", code)
+			_ = logFile.Sync()
+			_ = logFile.Close()
+		}
+		os.Exit(code)
+	}
+
+	if *noPersistFlag {
+		config.DisablePersistence()
+	}
+
+	// Accept magnet links or .torrent files as positional arguments to
+	// skip the input screen. The rest join the first one's session.
+	for _, arg := range flag.Args() {
+		if strings.EqualFold(filepath.Ext(arg), ".torrent") && !tui.IsTorrentFile(arg) {
+			fmt.Fprintf(os.Stderr, "Error: %s: no such .torrent file\n", arg)
+			exit(1)
+		}
+	}
 	var magnetURI string
+	var queued []string
 	if flag.NArg() > 0 {
 		magnetURI = flag.Arg(0)
+		queued = flag.Args()[1:]
+	}
+
+	// Otherwise take it from stdin when piped, e.g.
+	// echo 'magnet:?...' | just-stream
+	piped := stdinPiped()
+	if magnetURI == "" && piped {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			fmt.Fprintf(os.Stderr, "Error: read stdin: %v\n", err)
+			exit(1)
+		}
+		magnetURI = strings.TrimSpace(line)
 	}
 
 	// Also respect ALL_PROXY / all_proxy env var as fallback.
@@ -38,20 +122,192 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Warning: could not load config: %v\n", err)
 		cfg = &config.Config{}
 	}
+	// Environment beats the file, which beats the defaults.
+	if err := config.ApplyEnv(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring bad environment override: %v\n", err)
+	}
 
-	memStore := memstorage.NewMemory()
+	if err := config.CheckKeybindings(cfg.Keybindings); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: using the default keys: %v\n", err)
+	}
+	if !config.ValidTheme(cfg.Theme) {
+		fmt.Fprintf(os.Stderr, "Warning: unknown theme %q, using the default\n", cfg.Theme)
+	}
+	if err := config.CheckThemeColors(cfg.ThemeColors); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring theme colors: %v\n", err)
+	}
 
-	model := tui.NewModel(memStore, magnetURI, proxyURL, cfg)
+	storageMode := cfg.Storage
+	if *storageFlag != "" {
+		storageMode = *storageFlag
+	}
+	storageDir := cfg.StorageDir
+	if *storageDirFlag != "" {
+		storageDir = *storageDirFlag
+	}
+	if !player.ValidName(*playerFlag) {
+		fmt.Fprintf(os.Stderr, "Error: unknown player %q (want mpv, vlc or custom)\n", *playerFlag)
+		exit(2)
+	}
+	if !config.ValidStorage(storageMode) {
+		fmt.Fprintf(os.Stderr, "Error: unknown storage %q (want memory or disk)\n", storageMode)
+		exit(2)
+	}
+
+	listenAddr := *listenFlag
+	if listenAddr == "" {
+		listenAddr = cfg.StreamListenAddr
+	}
+	if listenAddr != "" {
+		if err := stream.CheckListenAddr(listenAddr); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: bad listen address %q: %v\n", listenAddr, err)
+			exit(2)
+		}
+		if !stream.IsLoopback(listenAddr) {
+			fmt.Fprintf(os.Stderr, "Warning: streams on %s are reachable from the network; anyone who gets a stream URL can watch\n", listenAddr)
+		}
+	}
+
+	playerName := cmp.Or(*playerFlag, cfg.Player, player.NameMPV)
+	if strings.HasPrefix(listenAddr, stream.UnixPrefix) && playerName != player.NameCustom {
+		fmt.Fprintf(os.Stderr, "Warning: %s can't read streams from a Unix socket; use a custom player that can (e.g. curl --unix-socket)\n", playerName)
+	}
+	if playerName == player.NameMPV {
+		if err := player.CheckIPCDir(cfg.IPCDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: mpv can't be controlled from the TUI (no playlist tracking, resume or controls): %v\nSet ipc_dir to a writable directory, e.g. just-stream config set ipc_dir ~/.cache\n", err)
+		}
+	}
+
+	opts := tui.Options{
+		Magnet:   magnetURI,
+		Queue:    queued,
+		ProxyURL: proxyURL,
+		Config:   cfg,
+		Debug:    *debugFlag,
+		Player:   *playerFlag,
+		// Only the flag overrides; the configured address is read from
+		// Config so settings changes apply to the next playback.
+		ListenAddr: *listenFlag,
+	}
+	if *maxUpFlag >= 0 {
+		opts.MaxUploadRate = maxUpFlag
+	}
+	if *maxDownFlag >= 0 {
+		opts.MaxDownloadRate = maxDownFlag
+	}
+
+	if *jsonFlag && !*listFlag {
+		fmt.Fprintln(os.Stderr, "Warning: --json only applies to --list")
+	}
+	if *listFlag {
+		if magnetURI == "" || len(queued) > 0 {
+			fmt.Fprintln(os.Stderr, "Error: --list takes exactly one magnet link or .torrent file")
+			exit(2)
+		}
+		// Only the metadata is fetched, so no storage is set up.
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		err := tui.ListFiles(ctx, opts, os.Stdout, *jsonFlag)
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+	var diskStore *memstorage.DiskStorage
+	if storageMode == config.StorageDisk {
+		if cfg.KeepDownloads {
+			diskStore, err = memstorage.NewDownloadDisk(cfg.DownloadDir)
+		} else {
+			diskStore, err = memstorage.NewDisk(storageDir)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		opts.Storage = diskStore
+	} else {
+		opts.MemStore = memstorage.NewMemoryWithLimit(int64(cfg.MemoryLimitMB) << 20)
+		if *cacheFlag && !config.Persistent() {
+			fmt.Fprintln(os.Stderr, "Warning: --cache is ignored with --no-persist")
+		} else if *cacheFlag {
+			cache, err := memstorage.NewPieceCache(cfg.CacheDir, int64(cfg.CacheMaxMB)<<20)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				exit(1)
+			}
+			slog.Info("piece cache enabled", "dir", cache.Dir())
+			opts.MemStore.SetCache(cache)
+		}
+	}
+	if *cacheFlag && diskStore != nil {
+		fmt.Fprintln(os.Stderr, "Warning: --cache has no effect with disk storage")
+	}
+	if cfg.KeepDownloads && diskStore == nil {
+		fmt.Fprintln(os.Stderr, "Warning: keep_downloads only applies to disk storage (--storage disk)")
+	}
+
+	model := tui.NewModel(opts)
 
 	// Give the model access to the program so background callbacks
 	// (e.g. mpv playlist-pos changes) can send messages.
 	// SetProgram writes to the shared pointer, which all Model value-copies
 	// share, so this works with Bubble Tea's value-copy update pattern.
-	p := tea.NewProgram(model, tea.WithAltScreen())
+	progOpts := []tea.ProgramOption{tea.WithAltScreen()}
+	if piped {
+		// stdin is not a terminal; read keys from the controlling TTY.
+		progOpts = append(progOpts, tea.WithInputTTY())
+	}
+	p := tea.NewProgram(model, progOpts...)
 	model.SetProgram(p)
 
-	if _, err := p.Run(); err != nil {
+	_, err = p.Run()
+
+	// The TUI closes the torrent client on quit, so the disk storage's
+	// files are no longer open and its directory can go.
+	if diskStore != nil {
+		if cerr := diskStore.Close(); cerr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not remove %s: %v\n", diskStore.Dir(), cerr)
+		}
+		if diskStore.Keep() {
+			fmt.Fprintf(os.Stderr, "Downloads kept in %s\n", diskStore.Dir())
+		}
+	}
+
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		exit(1)
+	}
+	exit(0)
+}
+
+// stdinPiped reports whether stdin is a pipe or file rather than a
+// terminal.
+func stdinPiped() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice == 0
+}
+
+// envLog names the log file when --log is not given.
+const envLog = "JUST_STREAM_LOG"
+
+// setupLogging sends slog output to path, at debug level when debug is
+// set. With no path the default logger discards everything: the TUI owns
+// the terminal, so nothing may be written to stderr while it runs.
+func setupLogging(path string, debug bool) (*os.File, error) {
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	level := slog.LevelInfo
+	if debug {
+		level = slog.LevelDebug
 	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(f, &slog.HandlerOptions{Level: level})))
+	slog.Info("just-stream starting")
+	return f, nil
 }
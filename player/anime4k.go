@@ -0,0 +1,117 @@
+package player
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Anime4K shader chains, following the upstream mpv install guide for
+// Anime4K v4 (high-end GPU presets). The files are looked up in the
+// configured shader directory.
+var anime4KModes = map[string][]string{
+	"A": {
+		"Anime4K_Clamp_Highlights.glsl",
+		"Anime4K_Restore_CNN_VL.glsl",
+		"Anime4K_Upscale_CNN_x2_VL.glsl",
+		"Anime4K_AutoDownscalePre_x2.glsl",
+		"Anime4K_AutoDownscalePre_x4.glsl",
+		"Anime4K_Upscale_CNN_x2_M.glsl",
+	},
+	"B": {
+		"Anime4K_Clamp_Highlights.glsl",
+		"Anime4K_Restore_CNN_Soft_VL.glsl",
+		"Anime4K_Upscale_CNN_x2_VL.glsl",
+		"Anime4K_AutoDownscalePre_x2.glsl",
+		"Anime4K_AutoDownscalePre_x4.glsl",
+		"Anime4K_Upscale_CNN_x2_M.glsl",
+	},
+	"C": {
+		"Anime4K_Clamp_Highlights.glsl",
+		"Anime4K_Upscale_Denoise_CNN_x2_VL.glsl",
+		"Anime4K_AutoDownscalePre_x2.glsl",
+		"Anime4K_AutoDownscalePre_x4.glsl",
+		"Anime4K_Upscale_CNN_x2_M.glsl",
+	},
+}
+
+// anime4KKeys are the mode-switching bindings from the upstream guide.
+var anime4KKeys = map[string]string{
+	"A": "CTRL+1",
+	"B": "CTRL+2",
+	"C": "CTRL+3",
+}
+
+// ValidAnime4KMode reports whether mode is "A", "B", "C", or off ("" or
+// "off"), ignoring case.
+func ValidAnime4KMode(mode string) bool {
+	if anime4KOff(mode) {
+		return true
+	}
+	_, ok := anime4KModes[strings.ToUpper(mode)]
+	return ok
+}
+
+func anime4KOff(mode string) bool {
+	return mode == "" || strings.EqualFold(mode, "off")
+}
+
+// DefaultShaderDir is mpv's own shader directory, where the README tells
+// users to unpack Anime4K.
+func DefaultShaderDir() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("APPDATA"), "mpv", "shaders")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "mpv", "shaders")
+}
+
+// anime4KShaders returns the shader files of mode in dir, or an error
+// naming the first one that is missing.
+func anime4KShaders(dir, mode string) ([]string, error) {
+	names, ok := anime4KModes[strings.ToUpper(mode)]
+	if !ok {
+		return nil, fmt.Errorf("unknown Anime4K mode %q (want A, B, C or off)", mode)
+	}
+	if dir == "" {
+		dir = DefaultShaderDir()
+	}
+	paths := make([]string, len(names))
+	for i, name := range names {
+		paths[i] = filepath.Join(dir, name)
+		if _, err := os.Stat(paths[i]); err != nil {
+			return nil, fmt.Errorf("Anime4K shader %s not found in %s", name, dir)
+		}
+	}
+	return paths, nil
+}
+
+// shaderList joins shader paths the way --glsl-shaders expects.
+func shaderList(paths []string) string {
+	return strings.Join(paths, string(os.PathListSeparator))
+}
+
+// quoteCommandArg quotes s as one argument of an mpv input command.
+// Inside double quotes mpv reads backslash escapes, so backslashes in
+// Windows paths and quotes in file names are escaped.
+func quoteCommandArg(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// bindAnime4KKeys binds CTRL+1..3 to switch between the Anime4K modes
+// whose shaders are installed, and CTRL+0 to turn them off. Older mpv
+// versions without the keybind command simply ignore it.
+func (m *MPV) bindAnime4KKeys(dir string) {
+	for mode, key := range anime4KKeys {
+		paths, err := anime4KShaders(dir, mode)
+		if err != nil {
+			continue
+		}
+		cmd := fmt.Sprintf(`change-list glsl-shaders set %s; show-text "Anime4K: Mode %s"`,
+			quoteCommandArg(shaderList(paths)), mode)
+		_ = m.sendCommand("keybind", key, cmd)
+	}
+	_ = m.sendCommand("keybind", "CTRL+0", `change-list glsl-shaders clr ""; show-text "GLSL shaders cleared"`)
+}
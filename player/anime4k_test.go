@@ -0,0 +1,20 @@
+package player
+
+import "testing"
+
+func TestQuoteCommandArg(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"/usr/share/shaders/A.glsl:/usr/share/shaders/B.glsl", `"/usr/share/shaders/A.glsl:/usr/share/shaders/B.glsl"`},
+		{`C:\Users\me\shaders\A.glsl;C:\Users\me\shaders\B.glsl`, `"C:\\Users\\me\\shaders\\A.glsl;C:\\Users\\me\\shaders\\B.glsl"`},
+		{`/home/me/"quoted" dir/A.glsl`, `"/home/me/\"quoted\" dir/A.glsl"`},
+		{`/odd\"mix`, `"/odd\\\"mix"`},
+		{"", `""`},
+	}
+	for _, tt := range tests {
+		if got := quoteCommandArg(tt.in); got != tt.want {
+			t.Errorf("quoteCommandArg(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
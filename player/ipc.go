@@ -0,0 +1,119 @@
+package player
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// mpv answers every JSON IPC command with a line carrying the command's
+// request_id. The event loop reads all lines from the socket; those
+// without an "event" are replies, which deliverReply routes to the caller
+// waiting on that request_id, if any.
+
+// ipcReply is mpv's response to a command sent with a reply channel.
+type ipcReply struct {
+	data interface{}
+	err  string // "success" on success
+}
+
+// replyTimeout bounds how long query waits for mpv to answer.
+const replyTimeout = 2 * time.Second
+
+// sendCommand sends a JSON IPC command to mpv without waiting for the
+// reply.
+func (m *MPV) sendCommand(args ...interface{}) error {
+	_, err := m.send(nil, args)
+	return err
+}
+
+// query sends a command and waits for mpv's reply data.
+func (m *MPV) query(args ...interface{}) (interface{}, error) {
+	reply := make(chan ipcReply, 1)
+	id, err := m.send(reply, args)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case r := <-reply:
+		if r.err != "success" {
+			return nil, fmt.Errorf("mpv: %s", r.err)
+		}
+		return r.data, nil
+	case <-time.After(replyTimeout):
+		m.mu.Lock()
+		delete(m.pending, id)
+		m.mu.Unlock()
+		return nil, errors.New("mpv did not reply")
+	}
+}
+
+// getProperty returns the current value of an mpv property.
+func (m *MPV) getProperty(name string) (interface{}, error) {
+	return m.query("get_property", name)
+}
+
+// send writes a command and returns its request_id. When reply is
+// non-nil, the event loop delivers mpv's response to it.
+func (m *MPV) send(reply chan ipcReply, args []interface{}) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.conn == nil {
+		return 0, errNoIPC
+	}
+
+	m.reqID++
+	cmd := map[string]interface{}{
+		"command":    args,
+		"request_id": m.reqID,
+	}
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return 0, err
+	}
+	data = append(data, '\n')
+
+	if wc, ok := m.conn.(interface{ SetWriteDeadline(time.Time) error }); ok {
+		_ = wc.SetWriteDeadline(time.Now().Add(2 * time.Second))
+	}
+	if _, err = m.conn.Write(data); err != nil {
+		return 0, err
+	}
+	if reply != nil {
+		if m.pending == nil {
+			m.pending = make(map[int]chan ipcReply)
+		}
+		m.pending[m.reqID] = reply
+	}
+	return m.reqID, nil
+}
+
+// deliverReply hands a command response to whoever is waiting for it.
+func (m *MPV) deliverReply(msg map[string]interface{}) {
+	id, ok := msg["request_id"].(float64)
+	if !ok {
+		return
+	}
+	m.mu.Lock()
+	reply := m.pending[int(id)]
+	delete(m.pending, int(id))
+	m.mu.Unlock()
+	if reply != nil {
+		errStr, _ := msg["error"].(string)
+		reply <- ipcReply{data: msg["data"], err: errStr}
+	}
+}
+
+// failPending fails every waiting request, so callers
+// don't sit out replyTimeout after mpv has gone.
+func (m *MPV) failPending() {
+	m.mu.Lock()
+	pending := m.pending
+	m.pending = nil
+	m.mu.Unlock()
+	for _, reply := range pending {
+		reply <- ipcReply{err: "IPC connection closed"}
+	}
+}
@@ -10,9 +10,41 @@ import (
 	"path/filepath"
 )
 
-// ipcPath returns a Unix domain socket path in the OS temp directory.
-func ipcPath() string {
-	return filepath.Join(os.TempDir(), fmt.Sprintf("just-stream-mpv-%d.sock", os.Getpid()))
+// ipcPath returns a Unix domain socket path in dir, or the OS temp
+// directory when dir is empty, unique to this process and Launch call.
+func ipcPath(dir string) string {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, fmt.Sprintf("just-stream-mpv-%d-%d.sock", os.Getpid(), ipcSeq.Add(1)))
+}
+
+// maxSocketPath is the longest Unix socket path every platform accepts;
+// sun_path is 104 bytes on macOS and the BSDs, 108 on Linux, including the
+// terminating NUL.
+const maxSocketPath = 103
+
+// CheckIPCDir reports why mpv's IPC socket can't be created in dir (the
+// OS temp directory when empty): it must be a writable directory with a
+// short enough path. Without the socket mpv still plays, but the TUI can
+// neither track nor control it.
+func CheckIPCDir(dir string) error {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	// A socket bound to the name proves more than a writable file would:
+	// some mounts allow files but not sockets.
+	probe := ipcPath(dir)
+	if len(probe) > maxSocketPath {
+		return fmt.Errorf("IPC directory %s: path too long for a socket (%d > %d bytes)", dir, len(probe), maxSocketPath)
+	}
+	ln, err := net.Listen("unix", probe)
+	if err != nil {
+		return fmt.Errorf("IPC directory %s: %w", dir, err)
+	}
+	ln.Close()
+	_ = os.Remove(probe)
+	return nil
 }
 
 // ipcDial connects to a Unix domain socket.
@@ -11,9 +11,16 @@ import (
 	"github.com/Microsoft/go-winio"
 )
 
-// ipcPath returns a Windows named pipe path.
-func ipcPath() string {
-	return fmt.Sprintf(`\\.\pipe\just-stream-mpv-%d`, os.Getpid())
+// ipcPath returns a Windows named pipe path, unique to this process and
+// Launch call.
+// dir is ignored: named pipes don't live in the file system.
+func ipcPath(dir string) string {
+	return fmt.Sprintf(`\\.\pipe\just-stream-mpv-%d-%d`, os.Getpid(), ipcSeq.Add(1))
+}
+
+// CheckIPCDir always succeeds on Windows, where IPC uses named pipes.
+func CheckIPCDir(dir string) error {
+	return nil
 }
 
 // ipcDial connects to a Windows named pipe.
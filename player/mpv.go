@@ -2,16 +2,30 @@ package player
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// errNoIPC is returned by commands sent before or without an IPC
+// connection.
+var errNoIPC = errors.New("no IPC connection")
+
+// ipcSeq numbers Launch calls so a restarted mpv never reuses the socket
+// or pipe of one that is still shutting down.
+var ipcSeq atomic.Uint64
+
 // MPV controls an mpv process via its JSON IPC protocol.
 type MPV struct {
 	cmd     *exec.Cmd
@@ -19,11 +33,49 @@ type MPV struct {
 	conn    io.ReadWriteCloser
 	mu      sync.Mutex
 	reqID   int
+	pending map[int]chan ipcReply // requests awaiting a reply, by request_id
+
+	// exited is closed once the current process has been reaped, with
+	// its exit status in waitErr. The process is only ever waited on by
+	// the goroutine started in start.
+	exited  chan struct{}
+	waitErr error
+
+	// done is closed by cleanup to stop the IPC goroutines; cleanupOnce
+	// makes cleanup safe to call from Wait, Kill and the reaper alike.
+	done        chan struct{}
+	cleanupOnce sync.Once
 
 	// Playlist position tracking
 	posMu       sync.Mutex
 	playlistPos int
 	onPosChange func(pos int) // callback when playlist-pos changes
+	onStart     func()        // callback on first playback-restart
+	onFileError func(pos int, detail string)
+	onTimePos   func(pos, dur float64)
+	lastTimePos time.Time // when onTimePos last ran, for throttling
+	started     bool
+
+	// Sync offsets in seconds, mirrored from mpv's observed properties.
+	subDelay   float64
+	audioDelay float64
+
+	// Playback time of the current file in seconds.
+	timePos  float64
+	duration float64
+	paused   bool
+
+	// volume mirrors mpv's volume property once volumeKnown is set.
+	volume      float64
+	volumeKnown bool
+
+	// speed mirrors mpv's playback speed; 0 until mpv reports it.
+	speed float64
+
+	// Resume seek applied once the start entry has loaded; see LaunchOpts.StartPos.
+	seekIndex    int
+	seekPos      float64
+	loadingEntry int // playlist index of the file mpv is loading
 }
 
 // LaunchOpts configures the mpv launch.
@@ -38,11 +90,71 @@ type LaunchOpts struct {
 	OnPlaylistPos func(pos int)
 	// MpvPath overrides exec.LookPath when non-empty.
 	MpvPath string
+	// OnPlaybackStart is called the first time mpv starts rendering
+	// playback, for measuring startup latency.
+	OnPlaybackStart func()
+	// OnFileError is called with the playlist index of an entry mpv
+	// failed to play, along with mpv's error description.
+	OnFileError func(pos int, detail string)
+	// StartPos resumes the StartIndex entry at this many seconds.
+	StartPos float64
+	// LowLatency tunes mpv's cache to start playing as soon as the
+	// beginning of the file is available instead of prebuffering.
+	LowLatency bool
+	// Anime4KMode enables an Anime4K shader preset: "A", "B" or "C".
+	// Empty or "off" leaves mpv's shaders alone.
+	Anime4KMode string
+	// Anime4KDir holds the Anime4K .glsl files. Empty uses
+	// DefaultShaderDir.
+	Anime4KDir string
+	// SubLang and AudioLang are comma-separated language preferences
+	// such as "eng,en". mpv falls back to its default choice when no
+	// track matches.
+	SubLang   string
+	AudioLang string
+	// NoSubs starts with subtitles off.
+	NoSubs bool
+	// OnTimePos is called with the playback position and duration of the
+	// current file in seconds, at most once per timePosInterval so a
+	// frame-accurate time-pos doesn't flood the caller.
+	OnTimePos func(pos, dur float64)
+	// ExtraArgs are additional mpv flags such as "--hwdec=auto". They go
+	// after the built-in flags, and any that set the same option as a
+	// built-in one are dropped so the IPC server and playlist keep working.
+	ExtraArgs []string
+	// Cache turns on mpv's cache, holding CacheSecs seconds ahead in at
+	// most DemuxerMaxMB megabytes; 0 leaves mpv's default for either.
+	// ExtraArgs setting the same options win over these.
+	Cache        bool
+	CacheSecs    int
+	DemuxerMaxMB int
+	// ScreenshotDir is where screenshots are saved. Empty leaves mpv's
+	// default.
+	ScreenshotDir string
+	// Volume is the starting volume, clamped to 0–MaxVolume. Nil leaves
+	// mpv's default.
+	Volume *int
+	// IPCDir is where the IPC socket is created on Unix. Empty uses the
+	// OS temp directory. See CheckIPCDir.
+	IPCDir string
 }
 
-// Launch starts mpv with an IPC endpoint, loading the given URLs as a playlist.
-func Launch(opts LaunchOpts) (*MPV, error) {
-	mpvPath := opts.MpvPath
+// MaxVolume is mpv's default volume-max: volumes above 100 amplify.
+const MaxVolume = 130
+
+// ClampVolume limits v to the range mpv accepts by default.
+func ClampVolume(v int) int {
+	return min(max(v, 0), MaxVolume)
+}
+
+// ErrMPVNotFound is returned when no mpv path is configured and none of
+// the usual locations has one.
+var ErrMPVNotFound = errors.New("mpv not found in PATH or common locations (C:\\Program Files\\mpv, scoop, mpv.net, etc). Please install mpv or set its path in the settings")
+
+// FindMPV returns path, or when it is empty, the first mpv found in PATH
+// or a common install location.
+func FindMPV(path string) (string, error) {
+	mpvPath := path
 	if mpvPath == "" {
 		var err error
 		mpvPath, err = exec.LookPath("mpv")
@@ -79,24 +191,161 @@ func Launch(opts LaunchOpts) (*MPV, error) {
 				}
 			}
 			if mpvPath == "" {
-				return nil, fmt.Errorf("mpv not found in PATH or common locations (C:\\Program Files\\mpv, scoop, mpv.net, etc). Please install mpv or set MPV_PATH environment variable")
+				return "", ErrMPVNotFound
 			}
 		}
 	}
+	return mpvPath, nil
+}
+
+// MPVVersion runs mpv --version for the mpv FindMPV picks for path and
+// returns the first line, e.g. "mpv v0.38.0 Copyright © ...".
+func MPVVersion(path string) (string, error) {
+	mpvPath, err := FindMPV(path)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, mpvPath, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("run %s --version: %w", mpvPath, err)
+	}
+	line, _, _ := strings.Cut(string(out), "\n")
+	return strings.TrimSpace(line), nil
+}
+
+// Launch starts mpv with an IPC endpoint, loading the given URLs as a playlist.
+func Launch(opts LaunchOpts) (*MPV, error) {
+	mpvPath, err := FindMPV(opts.MpvPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the shaders before starting mpv so a missing file is reported
+	// instead of mpv silently playing without them.
+	var shaders []string
+	if !anime4KOff(opts.Anime4KMode) {
+		var err error
+		if shaders, err = anime4KShaders(opts.Anime4KDir, opts.Anime4KMode); err != nil {
+			return nil, err
+		}
+	}
 
-	addr := ipcPath()
+	addr := ipcPath(opts.IPCDir)
 	ipcPreClean(addr)
 
 	m := &MPV{
+		done:        make(chan struct{}),
 		ipcAddr:     addr,
 		playlistPos: opts.StartIndex,
 		onPosChange: opts.OnPlaylistPos,
+		onStart:     opts.OnPlaybackStart,
+		onFileError: opts.OnFileError,
+		onTimePos:   opts.OnTimePos,
+	}
+	if opts.StartPos > 0 && len(opts.URLs) > 1 {
+		// --start would apply to every playlist entry, so multi-file
+		// playlists seek the start entry once it has loaded instead.
+		m.seekIndex = opts.StartIndex
+		m.seekPos = opts.StartPos
+	}
+
+	if err := m.start(mpvPath, opts, shaders); err != nil {
+		return nil, err
+	}
+
+	if !m.connectIPC() {
+		slog.Warn("mpv IPC unavailable; playback runs without tracking or controls", "ipc", m.ipcAddr)
+		go m.reap()
+		return m, nil
+	}
+	go m.reap()
+
+	if shaders != nil {
+		m.bindAnime4KKeys(opts.Anime4KDir)
+	}
+
+	if len(opts.URLs) > 1 {
+		go m.appendPlaylist(opts)
+	} else {
+		go m.eventLoop()
+	}
+	return m, nil
+}
+
+// extraArgs returns the user's extra flags minus those that set an option
+// builtin already sets, so the built-in value wins on conflict.
+func extraArgs(extra, builtin []string) []string {
+	taken := make(map[string]bool, len(builtin))
+	for _, a := range builtin {
+		taken[optionName(a)] = true
+	}
+	var out []string
+	for _, a := range extra {
+		if a == "" || taken[optionName(a)] {
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// cacheArgs returns the flags for opts' cache settings.
+func cacheArgs(opts LaunchOpts) []string {
+	if !opts.Cache {
+		return nil
+	}
+	args := []string{"--cache=yes"}
+	if opts.CacheSecs > 0 {
+		args = append(args, fmt.Sprintf("--cache-secs=%d", opts.CacheSecs))
+	}
+	if opts.DemuxerMaxMB > 0 {
+		args = append(args, fmt.Sprintf("--demuxer-max-bytes=%dMiB", opts.DemuxerMaxMB))
 	}
+	return args
+}
 
+// optionName returns the mpv option a flag sets: "--no-terminal" and
+// "--terminal=yes" both set "terminal".
+func optionName(arg string) string {
+	name := strings.TrimLeft(arg, "-")
+	if i := strings.IndexByte(name, '='); i >= 0 {
+		name = name[:i]
+	}
+	return strings.TrimPrefix(name, "no-")
+}
+
+// start launches the mpv process with its IPC server bound to m.ipcAddr.
+func (m *MPV) start(mpvPath string, opts LaunchOpts, shaders []string) error {
 	args := []string{
 		"--no-terminal",
 		"--force-seekable=yes",
-		fmt.Sprintf("--input-ipc-server=%s", addr),
+		fmt.Sprintf("--input-ipc-server=%s", m.ipcAddr),
+	}
+	if opts.LowLatency {
+		args = append(args,
+			"--cache=yes",
+			"--cache-pause-initial=no",
+			"--demuxer-readahead-secs=1",
+		)
+	}
+	if len(shaders) > 0 {
+		args = append(args, "--glsl-shaders="+shaderList(shaders))
+	}
+	if opts.NoSubs {
+		args = append(args, "--sid=no")
+	} else if opts.SubLang != "" {
+		args = append(args, "--slang="+opts.SubLang)
+	}
+	if opts.AudioLang != "" {
+		args = append(args, "--alang="+opts.AudioLang)
+	}
+	if opts.Volume != nil {
+		args = append(args, fmt.Sprintf("--volume=%d", ClampVolume(*opts.Volume)))
+	}
+	if opts.ScreenshotDir != "" {
+		args = append(args, "--screenshot-directory="+opts.ScreenshotDir)
 	}
 
 	// First URL goes as a direct argument, rest are appended via IPC.
@@ -104,40 +353,84 @@ func Launch(opts LaunchOpts) (*MPV, error) {
 		if opts.StartIndex < len(opts.Titles) && opts.Titles[opts.StartIndex] != "" {
 			args = append(args, fmt.Sprintf("--force-media-title=%s", opts.Titles[opts.StartIndex]))
 		}
+		if opts.StartPos > 0 && len(opts.URLs) == 1 {
+			args = append(args, fmt.Sprintf("--start=%.3f", opts.StartPos))
+		}
+	}
+	args = append(args, extraArgs(opts.ExtraArgs, args)...)
+	args = append(args, extraArgs(cacheArgs(opts), args)...)
+	if len(opts.URLs) > 0 {
 		args = append(args, opts.URLs[0])
 	}
 
-	m.cmd = exec.Command(mpvPath, args...)
-	m.cmd.Stdout = os.Stdout
-	m.cmd.Stderr = os.Stderr
+	cmd := exec.Command(mpvPath, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
 
-	if err := m.cmd.Start(); err != nil {
-		return nil, fmt.Errorf("start mpv: %w", err)
+	if err := cmd.Start(); err != nil {
+		slog.Error("mpv failed to start", "path", mpvPath, "err", err)
+		return fmt.Errorf("start mpv: %w", err)
 	}
+	slog.Info("mpv launched", "path", mpvPath, "pid", cmd.Process.Pid, "entries", len(opts.URLs), "ipc", m.ipcAddr)
+	exited := make(chan struct{})
+	m.cmd = cmd
+	m.exited = exited
+	go func() {
+		m.waitErr = cmd.Wait()
+		slog.Info("mpv exited", "pid", cmd.Process.Pid, "err", m.waitErr)
+		close(exited)
+	}()
+	return nil
+}
 
-	// Poll until the IPC endpoint is ready.
+// reap cleans up once mpv exits, however it exits: quit from the TUI,
+// closed from the window manager, or crashed. The IPC socket is removed
+// even if neither Wait nor Kill is ever called.
+func (m *MPV) reap() {
+	<-m.exited
+	m.cleanup()
+}
+
+// ipcDialFailures is how many dials in a row connectIPC lets fail on an
+// endpoint that exists before giving up.
+const ipcDialFailures = 3
+
+// connectIPC polls until the IPC endpoint accepts a connection. It waits
+// up to about five seconds for mpv to create the endpoint, but gives up
+// early once the endpoint exists and keeps refusing connections: a socket
+// or pipe that can't be dialed then won't start working later.
+func (m *MPV) connectIPC() bool {
+	failures := 0
 	for i := 0; i < 50; i++ {
-		time.Sleep(100 * time.Millisecond)
-		conn, err := ipcDial(addr)
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-m.exited:
+			return false // mpv died before opening the socket
+		}
+		conn, err := ipcDial(m.ipcAddr)
 		if err == nil {
 			m.conn = conn
-
-			if len(opts.URLs) > 1 {
-				go m.appendPlaylist(opts)
-			} else {
-				go m.eventLoop()
-			}
-			return m, nil
+			return true
+		}
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // not created yet
+		}
+		if failures++; failures >= ipcDialFailures {
+			slog.Warn("mpv IPC endpoint refuses connections", "ipc", m.ipcAddr, "err", err)
+			return false
 		}
 	}
-
-	return m, nil
+	return false
 }
 
 // appendPlaylist adds the remaining URLs to mpv's playlist via IPC,
 // then seeks to the correct start position.
 func (m *MPV) appendPlaylist(opts LaunchOpts) {
-	time.Sleep(200 * time.Millisecond)
+	select {
+	case <-time.After(200 * time.Millisecond):
+	case <-m.done:
+		return
+	}
 
 	for i := 1; i < len(opts.URLs); i++ {
 		_ = m.sendCommand("loadfile", opts.URLs[i], "append")
@@ -160,16 +453,92 @@ func (m *MPV) appendPlaylist(opts LaunchOpts) {
 	m.eventLoop()
 }
 
-// eventLoop reads IPC messages from mpv and dispatches events.
+// eventLoop reads IPC messages from mpv and dispatches events,
+// reconnecting if the connection drops while mpv is still running.
 func (m *MPV) eventLoop() {
-	if m.conn == nil {
-		return
+	for {
+		m.mu.Lock()
+		conn := m.conn
+		m.mu.Unlock()
+		if conn == nil {
+			return
+		}
+		m.observe()
+		m.readEvents(conn)
+		if !m.reconnectIPC(conn) {
+			return
+		}
+	}
+}
+
+// ipcReconnectAttempts is how many times a dropped IPC connection is
+// redialed, with a growing pause, while mpv keeps running.
+const ipcReconnectAttempts = 5
+
+// reconnectIPC replaces a dropped IPC connection if mpv is still running,
+// and reports whether it did. A connection closed by cleanup, or dropped
+// because mpv exited, is not replaced.
+func (m *MPV) reconnectIPC(old io.ReadWriteCloser) bool {
+	m.mu.Lock()
+	if m.conn == old {
+		_ = old.Close()
+		m.conn = nil
+	}
+	m.mu.Unlock()
+
+	for i := 1; i <= ipcReconnectAttempts; i++ {
+		select {
+		case <-time.After(time.Duration(i) * 200 * time.Millisecond):
+		case <-m.exited:
+			return false
+		case <-m.done:
+			return false
+		}
+		conn, err := ipcDial(m.ipcAddr)
+		if err != nil {
+			slog.Debug("mpv IPC redial failed", "ipc", m.ipcAddr, "attempt", i, "err", err)
+			continue
+		}
+		m.mu.Lock()
+		select {
+		case <-m.done:
+			m.mu.Unlock()
+			_ = conn.Close()
+			return false
+		default:
+		}
+		m.conn = conn
+		m.mu.Unlock()
+		slog.Warn("mpv IPC connection dropped; reconnected", "ipc", m.ipcAddr, "attempt", i)
+		return true
 	}
+	slog.Warn("mpv IPC connection lost; playback continues without tracking or controls", "ipc", m.ipcAddr)
+	return false
+}
 
+// observe subscribes to the properties the event loop mirrors. mpv sends
+// each one's current value straight away, so a reconnected loop catches up
+// on anything missed while it was down.
+func (m *MPV) observe() {
 	_ = m.sendCommand("observe_property", 1, "playlist-pos")
+	_ = m.sendCommand("observe_property", 2, "sub-delay")
+	_ = m.sendCommand("observe_property", 3, "audio-delay")
+	_ = m.sendCommand("observe_property", 4, "time-pos")
+	_ = m.sendCommand("observe_property", 5, "duration")
+	_ = m.sendCommand("observe_property", 6, "pause")
+	_ = m.sendCommand("observe_property", 7, "volume")
+	_ = m.sendCommand("observe_property", 8, "speed")
+}
+
+// readEvents handles lines from conn until it fails or is closed.
+func (m *MPV) readEvents(conn io.Reader) {
+	// cleanup closes conn, which ends the scan when mpv goes away.
+	scanner := bufio.NewScanner(conn)
+	// Replies such as track-list can be much larger than events.
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
 
-	scanner := bufio.NewScanner(m.conn)
-	scanner.Buffer(make([]byte, 64*1024), 64*1024)
+	// Nobody will answer requests still in flight once the scan ends.
+	defer m.failPending()
 
 	for scanner.Scan() {
 		line := scanner.Bytes()
@@ -178,24 +547,150 @@ func (m *MPV) eventLoop() {
 			continue
 		}
 
-		if event, ok := msg["event"].(string); ok && event == "property-change" {
-			name, _ := msg["name"].(string)
-			if name == "playlist-pos" {
-				if data, ok := msg["data"].(float64); ok {
-					pos := int(data)
-					m.posMu.Lock()
-					m.playlistPos = pos
-					cb := m.onPosChange
-					m.posMu.Unlock()
-					if cb != nil {
-						cb(pos)
-					}
-				}
+		event, _ := msg["event"].(string)
+		if event == "" {
+			m.deliverReply(msg)
+			continue
+		}
+		switch event {
+		case "playback-restart":
+			m.posMu.Lock()
+			first := !m.started
+			m.started = true
+			cb := m.onStart
+			m.posMu.Unlock()
+			if first && cb != nil {
+				cb()
+			}
+		case "start-file":
+			if id, ok := msg["playlist_entry_id"].(float64); ok {
+				m.posMu.Lock()
+				m.loadingEntry = int(id) - 1
+				m.posMu.Unlock()
 			}
+		case "file-loaded":
+			m.applyResumeSeek()
+		case "end-file":
+			m.handleEndFile(msg)
+		case "property-change":
+			m.handlePropertyChange(msg)
+		}
+	}
+}
+
+// handleEndFile reports playlist entries that mpv failed to play.
+func (m *MPV) handleEndFile(msg map[string]interface{}) {
+	if reason, _ := msg["reason"].(string); reason != "error" {
+		return
+	}
+	// Entry IDs start at 1 and follow the order URLs were loaded in,
+	// which matches the playlist index since entries are never removed.
+	id, ok := msg["playlist_entry_id"].(float64)
+	if !ok || m.onFileError == nil {
+		return
+	}
+	detail, _ := msg["file_error"].(string)
+	m.onFileError(int(id)-1, detail)
+}
+
+// handlePropertyChange records observed property values.
+func (m *MPV) handlePropertyChange(msg map[string]interface{}) {
+	name, _ := msg["name"].(string)
+	if name == "pause" {
+		if paused, ok := msg["data"].(bool); ok {
+			m.posMu.Lock()
+			m.paused = paused
+			m.posMu.Unlock()
+		}
+		return
+	}
+	data, ok := msg["data"].(float64)
+	if !ok {
+		return
+	}
+	switch name {
+	case "playlist-pos":
+		pos := int(data)
+		m.posMu.Lock()
+		m.playlistPos = pos
+		cb := m.onPosChange
+		m.posMu.Unlock()
+		if cb != nil {
+			cb(pos)
 		}
+	case "sub-delay":
+		m.posMu.Lock()
+		m.subDelay = data
+		m.posMu.Unlock()
+	case "audio-delay":
+		m.posMu.Lock()
+		m.audioDelay = data
+		m.posMu.Unlock()
+	case "time-pos":
+		m.posMu.Lock()
+		m.timePos = data
+		m.posMu.Unlock()
+		m.notifyTimePos(false)
+	case "duration":
+		m.posMu.Lock()
+		m.duration = data
+		m.posMu.Unlock()
+		// A new duration means a new file; report it right away.
+		m.notifyTimePos(true)
+	case "volume":
+		m.posMu.Lock()
+		m.volume, m.volumeKnown = data, true
+		m.posMu.Unlock()
+	case "speed":
+		m.posMu.Lock()
+		m.speed = data
+		m.posMu.Unlock()
+	}
+}
+
+// timePosInterval throttles OnTimePos.
+const timePosInterval = time.Second
+
+// notifyTimePos runs the OnTimePos callback, unless it already ran within
+// timePosInterval and force is unset.
+func (m *MPV) notifyTimePos(force bool) {
+	m.posMu.Lock()
+	cb := m.onTimePos
+	now := time.Now()
+	due := cb != nil && (force || now.Sub(m.lastTimePos) >= timePosInterval)
+	if due {
+		m.lastTimePos = now
+	}
+	pos, dur := m.timePos, m.duration
+	m.posMu.Unlock()
+	if due {
+		cb(pos, dur)
+	}
+}
+
+// applyResumeSeek seeks to the resume position once the start entry of a
+// playlist has loaded.
+func (m *MPV) applyResumeSeek() {
+	m.posMu.Lock()
+	pos := m.seekPos
+	due := pos > 0 && m.loadingEntry == m.seekIndex
+	if due {
+		m.seekPos = 0
+	}
+	m.posMu.Unlock()
+	if due {
+		_ = m.sendCommand("seek", pos, "absolute")
 	}
 }
 
+// TimePos returns the last observed playback position and duration of the
+// current file, in seconds. Both stay at their last values after mpv exits.
+func (m *MPV) TimePos() (pos, duration float64) {
+	m.posMu.Lock()
+	defer m.posMu.Unlock()
+	return m.timePos, m.duration
+}
+
 // PlaylistPos returns the current playlist position.
 func (m *MPV) PlaylistPos() int {
 	m.posMu.Lock()
@@ -203,33 +698,108 @@ func (m *MPV) PlaylistPos() int {
 	return m.playlistPos
 }
 
-// sendCommand sends a JSON IPC command to mpv.
-func (m *MPV) sendCommand(args ...interface{}) error {
+// Delays returns the current subtitle and audio delay in seconds.
+func (m *MPV) Delays() (sub, audio float64) {
+	m.posMu.Lock()
+	defer m.posMu.Unlock()
+	return m.subDelay, m.audioDelay
+}
+
+// Volume returns mpv's volume in percent, and false until mpv has
+// reported it. It keeps the last value after mpv exits.
+func (m *MPV) Volume() (float64, bool) {
+	m.posMu.Lock()
+	defer m.posMu.Unlock()
+	return m.volume, m.volumeKnown
+}
+
+// SetVolume sets the volume in percent, clamped to 0–MaxVolume.
+func (m *MPV) SetVolume(v int) error {
+	return m.sendCommand("set_property", "volume", ClampVolume(v))
+}
+
+// Speed returns the playback speed, and false until mpv has reported it.
+func (m *MPV) Speed() (float64, bool) {
+	m.posMu.Lock()
+	defer m.posMu.Unlock()
+	return m.speed, m.speed > 0
+}
+
+// SetSpeed sets the playback speed, 1.0 being normal.
+func (m *MPV) SetSpeed(x float64) error {
+	return m.sendCommand("set_property", "speed", x)
+}
+
+// AddSubDelay shifts subtitle timing by the given number of seconds.
+func (m *MPV) AddSubDelay(seconds float64) error {
+	return m.sendCommand("add", "sub-delay", seconds)
+}
+
+// AddAudioDelay shifts audio timing by the given number of seconds.
+func (m *MPV) AddAudioDelay(seconds float64) error {
+	return m.sendCommand("add", "audio-delay", seconds)
+}
+
+// Screenshot saves the current frame, subtitles included, and waits for
+// mpv to confirm it was written.
+func (m *MPV) Screenshot() error {
+	_, err := m.query("screenshot")
+	return err
+}
+
+// HasIPC reports whether the IPC connection is up. Without it mpv plays
+// normally but can't be tracked or controlled.
+func (m *MPV) HasIPC() bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	return m.conn != nil
+}
 
-	if m.conn == nil {
-		return fmt.Errorf("no IPC connection")
-	}
+// Paused reports whether playback is paused.
+func (m *MPV) Paused() bool {
+	m.posMu.Lock()
+	defer m.posMu.Unlock()
+	return m.paused
+}
 
-	m.reqID++
-	cmd := map[string]interface{}{
-		"command":    args,
-		"request_id": m.reqID,
-	}
-	data, err := json.Marshal(cmd)
-	if err != nil {
-		return err
+// Pause pauses playback. It is a no-op without an IPC connection.
+func (m *MPV) Pause() error {
+	return ignoreNoIPC(m.sendCommand("set_property", "pause", true))
+}
+
+// Resume continues paused playback. It is a no-op without an IPC
+// connection.
+func (m *MPV) Resume() error {
+	return ignoreNoIPC(m.sendCommand("set_property", "pause", false))
+}
+
+// Seek moves playback by seconds. mode is an mpv seek flag such as
+// "relative" (the default when empty) or "absolute". It is a no-op
+// without an IPC connection.
+func (m *MPV) Seek(seconds float64, mode string) error {
+	if mode == "" {
+		mode = "relative"
 	}
-	data = append(data, '\n')
+	return ignoreNoIPC(m.sendCommand("seek", seconds, mode))
+}
 
-	if wc, ok := m.conn.(interface{ SetWriteDeadline(time.Time) error }); ok {
-		_ = wc.SetWriteDeadline(time.Now().Add(2 * time.Second))
+func ignoreNoIPC(err error) error {
+	if errors.Is(err, errNoIPC) {
+		return nil
 	}
-	_, err = m.conn.Write(data)
 	return err
 }
 
+// PlaylistNext advances to the next playlist entry.
+func (m *MPV) PlaylistNext() error {
+	return m.sendCommand("playlist-next", "force")
+}
+
+// Quit asks mpv to exit; Wait returns once it has.
+func (m *MPV) Quit() error {
+	return m.sendCommand("quit")
+}
+
 // SetMediaTitle updates the force-media-title property.
 func (m *MPV) SetMediaTitle(title string) error {
 	return m.sendCommand("set_property", "force-media-title", title)
@@ -237,22 +807,18 @@ func (m *MPV) SetMediaTitle(title string) error {
 
 // Wait blocks until the mpv process exits.
 func (m *MPV) Wait() error {
-	err := m.cmd.Wait()
+	<-m.exited
 	m.cleanup()
-	return err
+	return m.waitErr
 }
 
-// Kill terminates the mpv process.
+// Kill terminates the mpv process, asking it to quit first when the IPC
+// connection is up.
 func (m *MPV) Kill() {
-	if m.conn != nil {
+	if m.HasIPC() {
 		_ = m.sendCommand("quit")
-		done := make(chan struct{})
-		go func() {
-			_ = m.cmd.Wait()
-			close(done)
-		}()
 		select {
-		case <-done:
+		case <-m.exited:
 		case <-time.After(500 * time.Millisecond):
 			if m.cmd.Process != nil {
 				_ = m.cmd.Process.Kill()
@@ -264,12 +830,17 @@ func (m *MPV) Kill() {
 	m.cleanup()
 }
 
+// cleanup stops the IPC goroutines, closes the connection and removes the
+// socket. It runs once; later calls return immediately.
 func (m *MPV) cleanup() {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	if m.conn != nil {
-		_ = m.conn.Close()
-		m.conn = nil
-	}
-	ipcPostClean(m.ipcAddr)
+	m.cleanupOnce.Do(func() {
+		close(m.done)
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.conn != nil {
+			_ = m.conn.Close()
+			m.conn = nil
+		}
+		ipcPostClean(m.ipcAddr)
+	})
 }
@@ -0,0 +1,205 @@
+package player
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeMPVEnv makes the test binary act as mpv: run with it set, TestMain
+// calls fakeMPV instead of the tests, so Launch can start the test binary
+// itself as a stand-in player.
+const fakeMPVEnv = "JUST_STREAM_FAKE_MPV"
+
+// fakeMPVArgsEnv names a file the fake mpv writes its arguments to, as a
+// JSON array.
+const fakeMPVArgsEnv = "JUST_STREAM_FAKE_MPV_ARGS"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(fakeMPVEnv); mode != "" {
+		fakeMPV(mode)
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// fakeMPV plays mpv for the tests. In "exit" mode it exits at once. In
+// "ipc" mode it serves the IPC socket named by --input-ipc-server,
+// answers every command with success and exits on quit. In "dead" mode
+// it puts a plain file where the socket belongs, which exists but can't
+// be dialed, and runs until killed.
+func fakeMPV(mode string) {
+	// Never outlive a test that forgot to stop us.
+	time.AfterFunc(30*time.Second, func() { os.Exit(2) })
+
+	if path := os.Getenv(fakeMPVArgsEnv); path != "" {
+		data, _ := json.Marshal(os.Args[1:])
+		if os.WriteFile(path, data, 0o600) != nil {
+			os.Exit(3)
+		}
+	}
+
+	var addr string
+	for _, a := range os.Args[1:] {
+		if v, ok := strings.CutPrefix(a, "--input-ipc-server="); ok {
+			addr = v
+		}
+	}
+	switch mode {
+	case "exit":
+		os.Exit(0)
+	case "dead":
+		if os.WriteFile(addr, nil, 0o600) != nil {
+			os.Exit(3)
+		}
+		time.Sleep(time.Hour)
+	case "ipc":
+		ln, err := net.Listen("unix", addr)
+		if err != nil {
+			os.Exit(3)
+		}
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				os.Exit(3)
+			}
+			go serveFakeIPC(conn)
+		}
+	}
+	os.Exit(3)
+}
+
+func serveFakeIPC(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var req struct {
+			Command   []interface{} `json:"command"`
+			RequestID int           `json:"request_id"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &req) != nil {
+			continue
+		}
+		if len(req.Command) > 0 && req.Command[0] == "quit" {
+			os.Exit(0)
+		}
+		reply, _ := json.Marshal(map[string]interface{}{"request_id": req.RequestID, "error": "success"})
+		if _, err := conn.Write(append(reply, '\n')); err != nil {
+			return
+		}
+	}
+}
+
+// launchFake starts the test binary as mpv in the given fakeMPV mode.
+func launchFake(t *testing.T, mode string, opts LaunchOpts) *MPV {
+	t.Helper()
+	t.Setenv(fakeMPVEnv, mode)
+	opts.MpvPath = os.Args[0]
+	if opts.IPCDir == "" {
+		opts.IPCDir = t.TempDir()
+	}
+	if opts.URLs == nil {
+		opts.URLs = []string{"http://127.0.0.1:1/stream/0"}
+	}
+	m, err := Launch(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(m.Kill)
+	return m
+}
+
+// waitDone fails unless cleanup has run.
+func waitDone(t *testing.T, m *MPV) {
+	t.Helper()
+	select {
+	case <-m.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("cleanup did not run")
+	}
+}
+
+func TestClampVolume(t *testing.T) {
+	tests := []struct {
+		in, want int
+	}{
+		{-10, 0},
+		{0, 0},
+		{55, 55},
+		{100, 100},
+		{MaxVolume, MaxVolume},
+		{MaxVolume + 1, MaxVolume},
+		{1000, MaxVolume},
+	}
+	for _, tt := range tests {
+		if got := ClampVolume(tt.in); got != tt.want {
+			t.Errorf("ClampVolume(%d) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLaunchExtraArgs(t *testing.T) {
+	argsFile := filepath.Join(t.TempDir(), "args.json")
+	t.Setenv(fakeMPVArgsEnv, argsFile)
+	const url = "http://127.0.0.1:1/stream/0"
+	m := launchFake(t, "exit", LaunchOpts{
+		URLs: []string{url},
+		ExtraArgs: []string{
+			"--hwdec=auto",
+			"--input-ipc-server=/tmp/elsewhere.sock", // would cut the TUI off
+			"--terminal",                             // conflicts with --no-terminal
+			"",
+			"--vo=gpu-next",
+			"--cache-secs=60", // beats the configured cache seconds
+		},
+		Cache:     true,
+		CacheSecs: 30,
+	})
+	_ = m.Wait()
+
+	data, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"--no-terminal",
+		"--force-seekable=yes",
+		"--input-ipc-server=" + m.ipcAddr,
+		"--hwdec=auto",
+		"--vo=gpu-next",
+		"--cache-secs=60",
+		"--cache=yes",
+		url,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("mpv args:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestOptionName(t *testing.T) {
+	tests := []struct {
+		arg, want string
+	}{
+		{"--hwdec=auto", "hwdec"},
+		{"--no-terminal", "terminal"},
+		{"--terminal=yes", "terminal"},
+		{"--input-ipc-server=/tmp/x.sock", "input-ipc-server"},
+		{"-fs", "fs"},
+		{"--glsl-shaders=a.glsl:b.glsl", "glsl-shaders"},
+	}
+	for _, tt := range tests {
+		if got := optionName(tt.arg); got != tt.want {
+			t.Errorf("optionName(%q) = %q, want %q", tt.arg, got, tt.want)
+		}
+	}
+}
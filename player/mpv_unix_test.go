@@ -0,0 +1,95 @@
+//go:build !windows
+
+package player
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// socketGone fails if mpv's IPC socket file is still there.
+func socketGone(t *testing.T, m *MPV) {
+	t.Helper()
+	if _, err := os.Stat(m.ipcAddr); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("IPC socket %s left behind (stat: %v)", m.ipcAddr, err)
+	}
+}
+
+func TestCleanupAfterExternalKill(t *testing.T) {
+	m := launchFake(t, "ipc", LaunchOpts{})
+	if !m.HasIPC() {
+		t.Fatal("no IPC connection to the fake mpv")
+	}
+
+	// Closed from the window manager: the process dies and leaves its
+	// socket behind, and nobody calls Wait or Kill.
+	if err := m.cmd.Process.Kill(); err != nil {
+		t.Fatal(err)
+	}
+	waitDone(t, m)
+	socketGone(t, m)
+	if m.HasIPC() {
+		t.Error("IPC connection still open after mpv died")
+	}
+
+	// Every later call returns at once, however often it is made.
+	done := make(chan [2]error, 1)
+	go func() {
+		first := m.Wait()
+		m.Kill()
+		m.cleanup()
+		second := m.Wait()
+		m.Kill()
+		done <- [2]error{first, second}
+	}()
+	select {
+	case errs := <-done:
+		if errs[0] == nil {
+			t.Error("Wait after a kill returned no error")
+		}
+		if errs[1] != errs[0] {
+			t.Errorf("second Wait = %v, want %v", errs[1], errs[0])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait or Kill blocked after mpv exited")
+	}
+}
+
+func TestKillQuitsOverIPC(t *testing.T) {
+	m := launchFake(t, "ipc", LaunchOpts{})
+	if !m.HasIPC() {
+		t.Fatal("no IPC connection to the fake mpv")
+	}
+
+	m.Kill()
+	waitDone(t, m)
+	socketGone(t, m)
+
+	// Kill may have had to kill a slow quit, so only check that the
+	// process is reaped and later calls are no-ops.
+	done := make(chan struct{})
+	go func() {
+		_ = m.Wait()
+		m.Kill()
+		m.cleanup()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait or Kill blocked after Kill")
+	}
+}
+
+func TestLaunchGivesUpOnDeadSocket(t *testing.T) {
+	start := time.Now()
+	m := launchFake(t, "dead", LaunchOpts{})
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Launch took %v to give up on a socket that refuses connections", elapsed)
+	}
+	if m.HasIPC() {
+		t.Error("IPC connected to a dead socket")
+	}
+}
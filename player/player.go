@@ -0,0 +1,95 @@
+package player
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Player starts a media player on a set of stream URLs.
+type Player interface {
+	Launch(opts LaunchOpts) (Controller, error)
+}
+
+// Controller is a running player. Players without a control channel
+// implement the remote-control methods as no-ops and report HasIPC false;
+// the LaunchOpts callbacks are then never called.
+type Controller interface {
+	// HasIPC reports whether the player can be queried and controlled.
+	HasIPC() bool
+
+	PlaylistPos() int
+	TimePos() (pos, duration float64)
+	Delays() (sub, audio float64)
+	Paused() bool
+	// Volume returns the volume in percent, and false while unknown.
+	Volume() (float64, bool)
+	// Speed returns the playback speed, and false while unknown.
+	Speed() (float64, bool)
+
+	Pause() error
+	Resume() error
+	Seek(seconds float64, mode string) error
+	AddSubDelay(seconds float64) error
+	AddAudioDelay(seconds float64) error
+	SetVolume(v int) error
+	SetSpeed(x float64) error
+	PlaylistNext() error
+	SetMediaTitle(title string) error
+	// Screenshot saves the current frame.
+	Screenshot() error
+	// Tracks lists the playing file's tracks, and SelectTrack switches
+	// the audio or subtitle one.
+	Tracks() ([]Track, error)
+	SelectTrack(kind string, id int) error
+
+	// Quit asks the player to exit; Wait returns once it has.
+	Quit() error
+	// Wait blocks until the player exits.
+	Wait() error
+	// Kill terminates the player.
+	Kill()
+}
+
+// Player names accepted by New.
+const (
+	NameMPV    = "mpv"
+	NameVLC    = "vlc"
+	NameCustom = "custom"
+)
+
+// New returns the player called name. path is the VLC binary for "vlc"
+// and the command template for "custom"; mpv uses LaunchOpts.MpvPath.
+func New(name, path string) (Player, error) {
+	switch strings.ToLower(name) {
+	case "", NameMPV:
+		return MPVPlayer{}, nil
+	case NameVLC:
+		return VLC{Path: path}, nil
+	case NameCustom:
+		if !strings.Contains(path, urlPlaceholder) {
+			return nil, fmt.Errorf("custom player command must contain %s", urlPlaceholder)
+		}
+		return Custom{Template: path}, nil
+	}
+	return nil, fmt.Errorf("unknown player %q (want mpv, vlc or custom)", name)
+}
+
+// ValidName reports whether name is accepted by New.
+func ValidName(name string) bool {
+	switch strings.ToLower(name) {
+	case "", NameMPV, NameVLC, NameCustom:
+		return true
+	}
+	return false
+}
+
+// MPVPlayer launches mpv and controls it over JSON IPC.
+type MPVPlayer struct{}
+
+func (MPVPlayer) Launch(opts LaunchOpts) (Controller, error) {
+	m, err := Launch(opts)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
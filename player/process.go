@@ -0,0 +1,148 @@
+package player
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// urlPlaceholder marks where a custom command takes the stream URL.
+const urlPlaceholder = "{url}"
+
+// VLC launches VLC with the stream URLs as its playlist. VLC has no
+// control channel, so playback can't be tracked or steered from the TUI.
+type VLC struct {
+	// Path overrides exec.LookPath("vlc") when non-empty.
+	Path string
+}
+
+func (v VLC) Launch(opts LaunchOpts) (Controller, error) {
+	path := v.Path
+	if path == "" {
+		var err error
+		if path, err = exec.LookPath("vlc"); err != nil {
+			return nil, fmt.Errorf("vlc not found in PATH; set the player path in settings")
+		}
+	}
+
+	args := []string{"--play-and-exit"}
+	if opts.SubLang != "" {
+		args = append(args, "--sub-language="+opts.SubLang)
+	}
+	if opts.AudioLang != "" {
+		args = append(args, "--audio-language="+opts.AudioLang)
+	}
+	// Start at StartIndex by leaving earlier entries out; per-item
+	// options after each URL carry its title and the resume position.
+	for i := opts.StartIndex; i < len(opts.URLs); i++ {
+		args = append(args, opts.URLs[i])
+		if i < len(opts.Titles) && opts.Titles[i] != "" {
+			args = append(args, ":meta-title="+opts.Titles[i])
+		}
+		if i == opts.StartIndex && opts.StartPos > 0 {
+			args = append(args, fmt.Sprintf(":start-time=%.3f", opts.StartPos))
+		}
+	}
+	return startProcess(path, args)
+}
+
+// Custom runs a user-supplied command line. Template is split on spaces;
+// an argument that is exactly {url} expands to every URL from the start
+// index on, and {url} inside a larger argument becomes the start URL.
+type Custom struct {
+	Template string
+}
+
+func (c Custom) Launch(opts LaunchOpts) (Controller, error) {
+	fields := strings.Fields(c.Template)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("custom player command is empty")
+	}
+	var start string
+	var rest []string
+	if opts.StartIndex < len(opts.URLs) {
+		start = opts.URLs[opts.StartIndex]
+		rest = opts.URLs[opts.StartIndex:]
+	}
+
+	var args []string
+	for _, f := range fields[1:] {
+		if f == urlPlaceholder {
+			args = append(args, rest...)
+			continue
+		}
+		args = append(args, strings.ReplaceAll(f, urlPlaceholder, start))
+	}
+	return startProcess(strings.ReplaceAll(fields[0], urlPlaceholder, start), args)
+}
+
+// process is a Controller for players without a control channel. It can
+// only wait for or kill the process.
+type process struct {
+	cmd *exec.Cmd
+}
+
+func startProcess(path string, args []string) (*process, error) {
+	cmd := exec.Command(path, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		slog.Error("player failed to start", "path", path, "err", err)
+		return nil, fmt.Errorf("start %s: %w", path, err)
+	}
+	slog.Info("player launched", "path", path, "pid", cmd.Process.Pid)
+	return &process{cmd: cmd}, nil
+}
+
+func (p *process) HasIPC() bool                            { return false }
+func (p *process) PlaylistPos() int                        { return 0 }
+func (p *process) TimePos() (pos, duration float64)        { return 0, 0 }
+func (p *process) Delays() (sub, audio float64)            { return 0, 0 }
+func (p *process) Paused() bool                            { return false }
+func (p *process) Volume() (float64, bool)                 { return 0, false }
+func (p *process) Speed() (float64, bool)                  { return 0, false }
+func (p *process) Pause() error                            { return nil }
+func (p *process) Resume() error                           { return nil }
+func (p *process) Seek(seconds float64, mode string) error { return nil }
+func (p *process) AddSubDelay(seconds float64) error       { return nil }
+func (p *process) AddAudioDelay(seconds float64) error     { return nil }
+func (p *process) SetVolume(v int) error                   { return nil }
+func (p *process) SetSpeed(x float64) error                { return nil }
+func (p *process) PlaylistNext() error                     { return nil }
+func (p *process) SetMediaTitle(title string) error        { return nil }
+
+// Screenshot fails rather than pretending: there is no way to reach the
+// player.
+func (p *process) Screenshot() error {
+	return errors.New("screenshots are only supported with mpv")
+}
+
+func (p *process) Tracks() ([]Track, error) {
+	return nil, errors.New("track selection is only supported with mpv")
+}
+
+func (p *process) SelectTrack(kind string, id int) error {
+	return errors.New("track selection is only supported with mpv")
+}
+
+func (p *process) Quit() error {
+	if p.cmd.Process == nil {
+		return nil
+	}
+	return p.cmd.Process.Kill()
+}
+
+func (p *process) Wait() error {
+	err := p.cmd.Wait()
+	slog.Info("player exited", "pid", p.cmd.Process.Pid, "err", err)
+	return err
+}
+
+func (p *process) Kill() {
+	if p.cmd.Process != nil {
+		_ = p.cmd.Process.Kill()
+	}
+}
@@ -0,0 +1,55 @@
+package player
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Track is one entry of mpv's track-list property.
+type Track struct {
+	ID       int    `json:"id"`
+	Type     string `json:"type"` // "video", "audio" or "sub"
+	Lang     string `json:"lang"`
+	Title    string `json:"title"`
+	Codec    string `json:"codec"`
+	Default  bool   `json:"default"`
+	External bool   `json:"external"`
+	Selected bool   `json:"selected"`
+}
+
+// Tracks returns the tracks of the file mpv is playing. A file without
+// tracks, or one mpv hasn't loaded yet, yields an empty list.
+func (m *MPV) Tracks() ([]Track, error) {
+	data, err := m.getProperty("track-list")
+	if err != nil {
+		return nil, err
+	}
+	// Round-trip through JSON to map mpv's loosely typed reply onto Track.
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var tracks []Track
+	if err := json.Unmarshal(raw, &tracks); err != nil {
+		return nil, fmt.Errorf("decode track-list: %w", err)
+	}
+	return tracks, nil
+}
+
+// SelectTrack switches the audio ("audio") or subtitle ("sub") track to
+// the one with the given ID. ID 0 turns subtitles off.
+func (m *MPV) SelectTrack(kind string, id int) error {
+	var prop string
+	switch kind {
+	case "audio":
+		prop = "aid"
+	case "sub":
+		prop = "sid"
+	default:
+		return fmt.Errorf("cannot select %s tracks", kind)
+	}
+	if id == 0 {
+		return m.sendCommand("set_property", prop, "no")
+	}
+	return m.sendCommand("set_property", prop, id)
+}
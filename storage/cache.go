@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+// DefaultCacheMaxBytes caps the piece cache when no size is configured.
+const DefaultCacheMaxBytes = 10 << 30
+
+// pieceExt marks a complete cached piece; files being written carry a
+// temporary suffix until renamed, so a crash never leaves a torn piece.
+const pieceExt = ".piece"
+
+// PieceCache keeps completed pieces on disk between sessions, one
+// directory per torrent under dir, so re-streaming an episode reads it
+// back instead of downloading it again. When the cache outgrows its cap,
+// whole torrents are removed, least recently used first.
+type PieceCache struct {
+	dir      string
+	maxBytes int64
+
+	mu   sync.Mutex
+	size int64                  // bytes of cached pieces on disk
+	open map[metainfo.Hash]bool // torrents in use, never evicted
+}
+
+// DefaultCacheDir returns $XDG_CACHE_HOME/just-stream, or the platform's
+// user cache directory when XDG_CACHE_HOME is unset.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("find cache dir: %w", err)
+	}
+	return filepath.Join(dir, "just-stream"), nil
+}
+
+// NewPieceCache opens the cache in dir (DefaultCacheDir when empty),
+// holding at most maxBytes (DefaultCacheMaxBytes when 0).
+func NewPieceCache(dir string, maxBytes int64) (*PieceCache, error) {
+	if dir == "" {
+		var err error
+		if dir, err = DefaultCacheDir(); err != nil {
+			return nil, err
+		}
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultCacheMaxBytes
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create cache dir: %w", err)
+	}
+	c := &PieceCache{dir: dir, maxBytes: maxBytes, open: make(map[metainfo.Hash]bool)}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if !strings.HasSuffix(path, pieceExt) {
+			// Left behind by an interrupted write.
+			_ = os.Remove(path)
+			return nil
+		}
+		if fi, err := d.Info(); err == nil {
+			c.size += fi.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan cache dir: %w", err)
+	}
+	return c, nil
+}
+
+// Dir returns the cache directory.
+func (c *PieceCache) Dir() string {
+	return c.dir
+}
+
+func (c *PieceCache) torrentDir(ih metainfo.Hash) string {
+	return filepath.Join(c.dir, ih.HexString())
+}
+
+func (c *PieceCache) piecePath(ih metainfo.Hash, index int) string {
+	return filepath.Join(c.torrentDir(ih), strconv.Itoa(index)+pieceExt)
+}
+
+// openTorrent marks a torrent in use and returns the indices of its cached
+// pieces. Its directory's modification time records the use for eviction.
+func (c *PieceCache) openTorrent(ih metainfo.Hash) map[int]bool {
+	c.mu.Lock()
+	c.open[ih] = true
+	c.mu.Unlock()
+
+	dir := c.torrentDir(ih)
+	now := time.Now()
+	_ = os.Chtimes(dir, now, now)
+	cached := make(map[int]bool)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return cached
+	}
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), pieceExt)
+		if !ok {
+			continue
+		}
+		if i, err := strconv.Atoi(name); err == nil {
+			cached[i] = true
+		}
+	}
+	return cached
+}
+
+// closeTorrent makes a torrent evictable again.
+func (c *PieceCache) closeTorrent(ih metainfo.Hash) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.open, ih)
+}
+
+// store writes a complete piece, then trims the cache to its cap.
+func (c *PieceCache) store(ih metainfo.Hash, index int, data []byte) error {
+	dir := c.torrentDir(ih)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("cache piece %d: %w", index, err)
+	}
+	tmp, err := os.CreateTemp(dir, "write-")
+	if err != nil {
+		return fmt.Errorf("cache piece %d: %w", index, err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.piecePath(ih, index))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("cache piece %d: %w", index, err)
+	}
+	c.mu.Lock()
+	c.size += int64(len(data))
+	c.mu.Unlock()
+	c.trim()
+	return nil
+}
+
+// load reads a cached piece and checks it against the piece hash, so a
+// damaged file is dropped rather than played.
+func (c *PieceCache) load(ih metainfo.Hash, index int, want metainfo.Hash) ([]byte, error) {
+	path := c.piecePath(ih, index)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if sum := sha1.Sum(data); !bytes.Equal(sum[:], want[:]) {
+		c.remove(ih, index)
+		return nil, fmt.Errorf("cached piece %d is corrupt", index)
+	}
+	return data, nil
+}
+
+// remove deletes a cached piece.
+func (c *PieceCache) remove(ih metainfo.Hash, index int) {
+	path := c.piecePath(ih, index)
+	fi, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	if os.Remove(path) == nil {
+		c.mu.Lock()
+		c.size -= fi.Size()
+		c.mu.Unlock()
+	}
+}
+
+// trim removes whole torrents, least recently used first, until the cache
+// fits its cap. Torrents open in this session are kept.
+func (c *PieceCache) trim() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.size <= c.maxBytes {
+		return
+	}
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	type victim struct {
+		ih   metainfo.Hash
+		used time.Time
+	}
+	var victims []victim
+	for _, e := range entries {
+		var ih metainfo.Hash
+		if !e.IsDir() || ih.FromHexString(e.Name()) != nil || c.open[ih] {
+			continue
+		}
+		if fi, err := e.Info(); err == nil {
+			victims = append(victims, victim{ih, fi.ModTime()})
+		}
+	}
+	sort.Slice(victims, func(i, j int) bool { return victims[i].used.Before(victims[j].used) })
+	for _, v := range victims {
+		if c.size <= c.maxBytes {
+			return
+		}
+		dir := c.torrentDir(v.ih)
+		freed := dirSize(dir)
+		if err := os.RemoveAll(dir); err != nil {
+			slog.Warn("evict cached torrent", "infohash", v.ih.HexString(), "err", err)
+			continue
+		}
+		c.size -= freed
+		slog.Debug("evicted cached torrent", "infohash", v.ih.HexString(), "bytes", freed)
+	}
+}
+
+func dirSize(dir string) int64 {
+	var n int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if fi, err := d.Info(); err == nil {
+				n += fi.Size()
+			}
+		}
+		return nil
+	})
+	return n
+}
@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/anacrolix/torrent/storage"
+)
+
+// DiskStorage implements storage.ClientImpl on top of the anacrolix file
+// storage, for machines that can't hold a whole file in RAM. Files are laid
+// out as in the torrent, under the torrent's name. By default they go to a
+// per-session directory that Close removes again; kept downloads survive.
+type DiskStorage struct {
+	storage.ClientImplCloser
+	dir  string
+	keep bool
+}
+
+// DefaultDiskDir is the parent directory used when none is configured.
+func DefaultDiskDir() string {
+	return filepath.Join(os.TempDir(), "just-stream")
+}
+
+// DefaultDownloadDir is where kept downloads go when no directory is
+// configured: ~/Downloads/just-stream, or DefaultDiskDir without a home.
+func DefaultDownloadDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return DefaultDiskDir()
+	}
+	return filepath.Join(home, "Downloads", "just-stream")
+}
+
+// NewDisk creates a fresh session directory under baseDir (DefaultDiskDir
+// when empty) and stores piece data there. Completion state is kept in
+// memory since the directory never outlives the session.
+func NewDisk(baseDir string) (*DiskStorage, error) {
+	if baseDir == "" {
+		baseDir = DefaultDiskDir()
+	}
+	if err := os.MkdirAll(baseDir, 0o700); err != nil {
+		return nil, fmt.Errorf("create storage dir: %w", err)
+	}
+	dir, err := os.MkdirTemp(baseDir, "session-")
+	if err != nil {
+		return nil, fmt.Errorf("create storage dir: %w", err)
+	}
+	return newDisk(dir, false), nil
+}
+
+// NewDownloadDisk stores files directly under dir (DefaultDownloadDir when
+// empty) and leaves them there on Close, so the download can be kept.
+//
+// Reopening a torrent in the same dir resumes it. Completion stays in
+// memory on purpose: finished files are recognized by name, and pieces of
+// an unfinished .part file report unknown completion, so the client
+// hash-checks them when the torrent is added. A persistent completion
+// store would be worse, since the file storage marks every piece of a
+// .part file incomplete on open and they would be downloaded again.
+func NewDownloadDisk(dir string) (*DiskStorage, error) {
+	if dir == "" {
+		dir = DefaultDownloadDir()
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create download dir: %w", err)
+	}
+	return newDisk(dir, true), nil
+}
+
+func newDisk(dir string, keep bool) *DiskStorage {
+	return &DiskStorage{
+		ClientImplCloser: storage.NewFileOpts(storage.NewFileClientOpts{
+			ClientBaseDir:   dir,
+			FilePathMaker:   filePath,
+			PieceCompletion: storage.NewMapPieceCompletion(),
+		}),
+		dir:  dir,
+		keep: keep,
+	}
+}
+
+// Dir returns the directory holding the downloaded files.
+func (ds *DiskStorage) Dir() string {
+	return ds.dir
+}
+
+// Keep reports whether the files outlive Close.
+func (ds *DiskStorage) Keep() bool {
+	return ds.keep
+}
+
+// Close closes the underlying storage and, unless downloads are kept,
+// deletes the session directory. The torrent client must be closed first
+// so no file is still open.
+func (ds *DiskStorage) Close() error {
+	err := ds.ClientImplCloser.Close()
+	if ds.keep {
+		return err
+	}
+	if rmErr := os.RemoveAll(ds.dir); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// filePath places a torrent file at <torrent name>/<path in torrent>, or at
+// <torrent name> for single-file torrents, with every component sanitized
+// so a hostile torrent can't write outside the storage directory.
+func filePath(opts storage.FilePathMakerOpts) string {
+	var parts []string
+	if name := opts.Info.BestName(); name != metainfo.NoName {
+		parts = append(parts, safeName(name))
+	}
+	for _, p := range opts.File.BestPath() {
+		parts = append(parts, safeName(p))
+	}
+	return filepath.Join(parts...)
+}
+
+// safeName turns one path component from a torrent into a file name that
+// is valid on Linux and Windows and can't traverse directories.
+func safeName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r < 0x20, r == 0x7f:
+			return -1
+		case strings.ContainsRune(`/\:*?"<>|`, r):
+			return '_'
+		}
+		return r
+	}, s)
+	// Windows drops trailing dots and spaces, which would let "..  " alias "..".
+	s = strings.TrimRight(s, ". ")
+	if s == "" {
+		return "_"
+	}
+	base, _, _ := strings.Cut(s, ".")
+	switch strings.ToUpper(base) {
+	case "CON", "PRN", "AUX", "NUL",
+		"COM1", "COM2", "COM3", "COM4", "COM5", "COM6", "COM7", "COM8", "COM9",
+		"LPT1", "LPT2", "LPT3", "LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9":
+		return "_" + s
+	}
+	return s
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha1"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+func TestKeptDownloadResumes(t *testing.T) {
+	dir := t.TempDir()
+	pieces := testPieces(4)
+	// Two files of two pieces each: a.mkv finishes, b.mkv only gets its
+	// first piece and stays a .part file.
+	info := &metainfo.Info{
+		Name:        "show",
+		PieceLength: testPieceLen,
+		Files: []metainfo.FileInfo{
+			{Path: []string{"a.mkv"}, Length: 2 * testPieceLen},
+			{Path: []string{"b.mkv"}, Length: 2 * testPieceLen},
+		},
+	}
+	for _, p := range pieces {
+		sum := sha1.Sum(p)
+		info.Pieces = append(info.Pieces, sum[:]...)
+	}
+	infoBytes, err := bencode.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mi := &metainfo.MetaInfo{InfoBytes: infoBytes}
+
+	ds, err := NewDownloadDisk(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tor, err := ds.OpenTorrent(context.Background(), info, mi.HashInfoBytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []int{0, 1, 2} {
+		p := tor.Piece(info.Piece(i))
+		if _, err := p.WriteAt(pieces[i], 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.MarkComplete(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ds.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new session adding the torrent with the same download directory
+	// has the kept pieces without downloading them.
+	ds, err = NewDownloadDisk(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ds.Close()
+	cfg := torrent.NewDefaultClientConfig()
+	cfg.DataDir = t.TempDir()
+	cfg.DefaultStorage = ds
+	cfg.ListenPort = 0
+	cfg.NoDHT = true
+	cfg.DisableTCP = true
+	cfg.DisableUTP = true
+	cfg.NoDefaultPortForwarding = true
+	client, err := torrent.NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	tt, err := client.AddTorrent(mi)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		done := tt.PieceState(0).Complete && tt.PieceState(1).Complete && tt.PieceState(2).Complete
+		if done {
+			break
+		}
+		if time.Now().After(deadline) {
+			for i := range pieces {
+				t.Logf("piece %d: %+v", i, tt.PieceState(i))
+			}
+			t.Fatal("kept pieces not recognized after reopening")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if tt.PieceState(3).Complete {
+		t.Error("piece never written reports complete")
+	}
+}
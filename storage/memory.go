@@ -1,8 +1,12 @@
 package storage
 
 import (
+	"container/list"
 	"context"
+	"fmt"
 	"io"
+	"log/slog"
+	"slices"
 	"sync"
 
 	"github.com/anacrolix/torrent/metainfo"
@@ -10,15 +14,109 @@ import (
 )
 
 // MemoryStorage implements storage.ClientImpl, storing all piece data in RAM.
+//
+// Resident pieces of every torrent share one byte budget. When a write
+// would exceed it, the least-recently-used pieces across all torrents are
+// evicted and reported through the eviction callback so the client can
+// re-check their completion.
 type MemoryStorage struct {
 	mu       sync.Mutex
 	torrents map[metainfo.Hash]*MemTorrent
+
+	// lruMu guards the budget accounting below and memPiece.elem.
+	lruMu   sync.Mutex
+	lru     *list.List // resident *memPiece, most recently used at the front
+	inUse   int64
+	limit   int64 // 0 means unlimited
+	onEvict func(infoHash metainfo.Hash, piece int)
+
+	// protected holds, per torrent, piece ranges [start, end) that are
+	// never evicted: the file currently being played and the start of
+	// those preloaded after it.
+	protected map[metainfo.Hash][][2]int
+
+	// cache, when set, keeps completed pieces on disk across sessions.
+	cache *PieceCache
 }
 
 func NewMemory() *MemoryStorage {
+	return NewMemoryWithLimit(0)
+}
+
+// NewMemoryWithLimit returns a storage that keeps at most limit bytes of
+// piece data resident across all torrents. A limit of 0 is unlimited.
+func NewMemoryWithLimit(limit int64) *MemoryStorage {
 	return &MemoryStorage{
-		torrents: make(map[metainfo.Hash]*MemTorrent),
+		torrents:  make(map[metainfo.Hash]*MemTorrent),
+		lru:       list.New(),
+		limit:     limit,
+		protected: make(map[metainfo.Hash][][2]int),
+	}
+}
+
+// Protect exempts the piece ranges [start, end) of a torrent from eviction
+// and FreePieces, replacing any ranges protected before. The player keeps
+// the file being watched protected so eviction never pulls data out from
+// under it.
+func (ms *MemoryStorage) Protect(infoHash metainfo.Hash, ranges ...[2]int) {
+	ms.lruMu.Lock()
+	defer ms.lruMu.Unlock()
+	ms.protected[infoHash] = ranges
+}
+
+// isProtected reports whether a piece is in a protected range. Callers
+// must hold lruMu.
+func (ms *MemoryStorage) isProtected(infoHash metainfo.Hash, index int) bool {
+	return inRanges(ms.protected[infoHash], index)
+}
+
+func inRanges(ranges [][2]int, index int) bool {
+	for _, r := range ranges {
+		if index >= r[0] && index < r[1] {
+			return true
+		}
 	}
+	return false
+}
+
+// Unprotect makes every piece of a torrent evictable again.
+func (ms *MemoryStorage) Unprotect(infoHash metainfo.Hash) {
+	ms.lruMu.Lock()
+	defer ms.lruMu.Unlock()
+	delete(ms.protected, infoHash)
+}
+
+// BytesInUse returns how many bytes of piece data are resident.
+func (ms *MemoryStorage) BytesInUse() int64 {
+	ms.lruMu.Lock()
+	defer ms.lruMu.Unlock()
+	return ms.inUse
+}
+
+// SetOnEvict registers a callback invoked, on its own goroutine, for every
+// piece dropped to stay within the memory limit. The torrent client still
+// considers such pieces complete until told otherwise, so the callback
+// should make it refresh their completion state.
+func (ms *MemoryStorage) SetOnEvict(fn func(infoHash metainfo.Hash, piece int)) {
+	ms.lruMu.Lock()
+	defer ms.lruMu.Unlock()
+	ms.onEvict = fn
+}
+
+// SetCache writes completed pieces through to c and reads them back from
+// it, in this session and later ones. It must be called before any torrent
+// is opened.
+func (ms *MemoryStorage) SetCache(c *PieceCache) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.cache = c
+}
+
+// Limit returns the memory budget in bytes; 0 means unlimited.
+func (ms *MemoryStorage) Limit() int64 {
+	ms.lruMu.Lock()
+	defer ms.lruMu.Unlock()
+	return ms.limit
 }
 
 func (ms *MemoryStorage) OpenTorrent(_ context.Context, info *metainfo.Info, infoHash metainfo.Hash) (storage.TorrentImpl, error) {
@@ -30,6 +128,12 @@ func (ms *MemoryStorage) OpenTorrent(_ context.Context, info *metainfo.Info, inf
 		pieceLen:  info.PieceLength,
 		numPieces: info.NumPieces(),
 		info:      info,
+		infoHash:  infoHash,
+		store:     ms,
+	}
+	if ms.cache != nil && info.HasV1() {
+		t.cache = ms.cache
+		t.cached = ms.cache.openTorrent(infoHash)
 	}
 	ms.torrents[infoHash] = t
 	return storage.TorrentImpl{
@@ -52,6 +156,84 @@ func (ms *MemoryStorage) Close() error {
 	return nil
 }
 
+// touch marks a resident piece as most recently used.
+func (ms *MemoryStorage) touch(mp *memPiece) {
+	ms.lruMu.Lock()
+	defer ms.lruMu.Unlock()
+	if mp.elem != nil {
+		ms.lru.MoveToFront(mp.elem)
+	}
+}
+
+// reserve accounts for mp becoming resident, evicting least-recently-used
+// pieces of any torrent until it fits within the limit. If nothing else
+// can be evicted the piece is admitted over budget rather than failing
+// the write. Reserving a piece that is already resident just touches it.
+func (ms *MemoryStorage) reserve(mp *memPiece) {
+	ms.lruMu.Lock()
+	if mp.elem != nil {
+		ms.lru.MoveToFront(mp.elem)
+		ms.lruMu.Unlock()
+		return
+	}
+	var victims []*memPiece
+	for ms.limit > 0 && ms.inUse+mp.len > ms.limit {
+		victim := ms.evictionCandidate(mp)
+		if victim == nil {
+			break
+		}
+		ms.lru.Remove(victim.elem)
+		victim.elem = nil
+		ms.inUse -= victim.len
+		victims = append(victims, victim)
+	}
+	mp.elem = ms.lru.PushFront(mp)
+	ms.inUse += mp.len
+	onEvict := ms.onEvict
+	ms.lruMu.Unlock()
+
+	for _, v := range victims {
+		v.drop()
+		if onEvict != nil {
+			go onEvict(v.torrent.infoHash, v.index)
+		}
+	}
+}
+
+// evictionCandidate returns the least-recently-used resident piece other
+// than keep that isn't in a protected range. Callers must hold lruMu.
+func (ms *MemoryStorage) evictionCandidate(keep *memPiece) *memPiece {
+	for e := ms.lru.Back(); e != nil; e = e.Prev() {
+		mp := e.Value.(*memPiece)
+		if mp == keep {
+			continue
+		}
+		if ms.isProtected(mp.torrent.infoHash, mp.index) {
+			continue
+		}
+		return mp
+	}
+	return nil
+}
+
+// resident reports whether mp is counted against the budget.
+func (ms *MemoryStorage) resident(mp *memPiece) bool {
+	ms.lruMu.Lock()
+	defer ms.lruMu.Unlock()
+	return mp.elem != nil
+}
+
+// release removes a piece from the budget after its data was freed.
+func (ms *MemoryStorage) release(mp *memPiece) {
+	ms.lruMu.Lock()
+	defer ms.lruMu.Unlock()
+	if mp.elem != nil {
+		ms.lru.Remove(mp.elem)
+		mp.elem = nil
+		ms.inUse -= mp.len
+	}
+}
+
 // MemTorrent holds all in-memory pieces for one torrent.
 type MemTorrent struct {
 	mu        sync.Mutex
@@ -59,6 +241,13 @@ type MemTorrent struct {
 	pieceLen  int64
 	numPieces int
 	info      *metainfo.Info
+	infoHash  metainfo.Hash
+	store     *MemoryStorage
+
+	// cache is the store's piece cache, nil when disabled; cached holds
+	// the pieces it had when the torrent was opened.
+	cache  *PieceCache
+	cached map[int]bool
 }
 
 func (mt *MemTorrent) Piece(p metainfo.Piece) storage.PieceImpl {
@@ -70,28 +259,76 @@ func (mt *MemTorrent) Piece(p metainfo.Piece) storage.PieceImpl {
 		return mp
 	}
 
-	length := p.Length()
+	// Data is allocated on first write, so pieces the client only asks
+	// about for completion don't count against the memory limit.
 	mp := &memPiece{
-		data: make([]byte, length),
-		len:  length,
+		len:     p.Length(),
+		index:   idx,
+		torrent: mt,
+		cached:  mt.cached[idx],
 	}
 	mt.pieces[idx] = mp
 	return mp
 }
 
-// FreePieces releases memory for the given piece range [start, end).
-// Used to reclaim RAM after an episode finishes playing.
+// FreePieces releases memory for the given piece range [start, end),
+// except protected pieces, such as one an episode shares with the next.
+// Used to reclaim RAM after an episode finishes playing. Freed pieces are
+// reported through the eviction callback like any other eviction.
 func (mt *MemTorrent) FreePieces(start, end int) {
+	mt.store.lruMu.Lock()
+	protected := slices.Clone(mt.store.protected[mt.infoHash])
+	mt.store.lruMu.Unlock()
+
 	mt.mu.Lock()
-	defer mt.mu.Unlock()
+	var freed []int
 	for i := start; i < end; i++ {
-		delete(mt.pieces, i)
+		if inRanges(protected, i) {
+			continue
+		}
+		if mp, ok := mt.pieces[i]; ok {
+			mp.drop()
+			mt.store.release(mp)
+			delete(mt.pieces, i)
+			freed = append(freed, i)
+		}
+	}
+	mt.mu.Unlock()
+
+	mt.store.lruMu.Lock()
+	onEvict := mt.store.onEvict
+	mt.store.lruMu.Unlock()
+	if onEvict != nil && len(freed) > 0 {
+		go func() {
+			for _, i := range freed {
+				onEvict(mt.infoHash, i)
+			}
+		}()
+	}
+}
+
+// setCached records whether piece i is in the cache, so the piece keeps
+// that state if it is freed and opened again.
+func (mt *MemTorrent) setCached(i int, cached bool) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if cached {
+		mt.cached[i] = true
+	} else {
+		delete(mt.cached, i)
 	}
 }
 
 func (mt *MemTorrent) Close() error {
 	mt.mu.Lock()
 	defer mt.mu.Unlock()
+	if mt.cache != nil {
+		mt.cache.closeTorrent(mt.infoHash)
+	}
+	for _, mp := range mt.pieces {
+		mp.drop()
+		mt.store.release(mp)
+	}
 	mt.pieces = nil
 	return nil
 }
@@ -99,15 +336,36 @@ func (mt *MemTorrent) Close() error {
 // memPiece stores one piece's data in a byte slice.
 type memPiece struct {
 	mu       sync.RWMutex
-	data     []byte
+	data     []byte // nil until written, and again after eviction
 	len      int64
 	complete bool
+	cached   bool // a verified copy is in the piece cache
+
+	index   int
+	torrent *MemTorrent
+	elem    *list.Element // position in the storage LRU; guarded by lruMu
 }
 
+// ReadAt copies piece data. The client only reads chunks it has written
+// (or the whole piece, to hash it), so the zeros in a partly downloaded
+// piece are never served; a stream reader that seeks ahead waits in the
+// client for the chunks instead.
 func (mp *memPiece) ReadAt(p []byte, off int64) (int, error) {
 	mp.mu.RLock()
+	if mp.data == nil && mp.cached {
+		mp.mu.RUnlock()
+		if err := mp.loadCached(); err != nil {
+			return 0, err
+		}
+		mp.mu.RLock()
+	}
 	defer mp.mu.RUnlock()
 
+	if mp.data == nil {
+		// Evicted or never written: don't hand out zeros as piece data.
+		return 0, io.ErrUnexpectedEOF
+	}
+	mp.torrent.store.touch(mp)
 	if off >= int64(len(mp.data)) {
 		return 0, io.EOF
 	}
@@ -119,9 +377,23 @@ func (mp *memPiece) ReadAt(p []byte, off int64) (int, error) {
 }
 
 func (mp *memPiece) WriteAt(p []byte, off int64) (int, error) {
-	mp.mu.Lock()
+	for {
+		// Reserve before taking the write lock: eviction locks other
+		// pieces and must never wait on this one.
+		mp.torrent.store.reserve(mp)
+		mp.mu.Lock()
+		if mp.torrent.store.resident(mp) {
+			break
+		}
+		// Evicted in between; writing now would hold data the budget
+		// doesn't count.
+		mp.mu.Unlock()
+	}
 	defer mp.mu.Unlock()
 
+	if mp.data == nil {
+		mp.data = make([]byte, mp.len)
+	}
 	end := off + int64(len(p))
 	if end > int64(len(mp.data)) {
 		grown := make([]byte, end)
@@ -132,17 +404,84 @@ func (mp *memPiece) WriteAt(p []byte, off int64) (int, error) {
 	return n, nil
 }
 
-func (mp *memPiece) MarkComplete() error {
+// loadCached reads the piece back from the cache. A copy that fails its
+// hash check is discarded and the piece reported incomplete, so the
+// client downloads it again.
+func (mp *memPiece) loadCached() error {
+	mt := mp.torrent
+	want := mt.info.Piece(mp.index).V1Hash()
+	data, err := mt.cache.load(mt.infoHash, mp.index, want.Value)
+	if err != nil {
+		mp.mu.Lock()
+		mp.cached = false
+		mp.mu.Unlock()
+		mt.setCached(mp.index, false)
+		return fmt.Errorf("read cached piece: %w", err)
+	}
+	for {
+		mp.mu.Lock()
+		if mp.data != nil {
+			// Loaded or written meanwhile.
+			mp.mu.Unlock()
+			return nil
+		}
+		if mt.store.resident(mp) {
+			mp.data = data
+			mp.mu.Unlock()
+			return nil
+		}
+		mp.mu.Unlock()
+		// Reserve without the write lock, as in WriteAt, then check
+		// again that the data is still needed and the piece still
+		// counted.
+		mt.store.reserve(mp)
+	}
+}
+
+// drop frees the piece's data and marks it incomplete. It waits for any
+// ReadAt in progress, so a reader never sees the data vanish mid-copy.
+func (mp *memPiece) drop() {
 	mp.mu.Lock()
 	defer mp.mu.Unlock()
+	mp.data = nil
+	mp.complete = false
+}
+
+func (mp *memPiece) MarkComplete() error {
+	mp.mu.Lock()
 	mp.complete = true
+	data := mp.data
+	write := mp.torrent.cache != nil && !mp.cached && int64(len(data)) == mp.len
+	mp.mu.Unlock()
+
+	if write {
+		// The client doesn't write to a piece it considers complete, so
+		// the data can be written out without holding the lock.
+		if err := mp.torrent.cache.store(mp.torrent.infoHash, mp.index, data); err != nil {
+			slog.Warn("cache piece", "index", mp.index, "err", err)
+			return nil
+		}
+		mp.mu.Lock()
+		mp.cached = true
+		mp.mu.Unlock()
+		mp.torrent.setCached(mp.index, true)
+	}
 	return nil
 }
 
 func (mp *memPiece) MarkNotComplete() error {
 	mp.mu.Lock()
-	defer mp.mu.Unlock()
 	mp.complete = false
+	uncache := mp.cached
+	mp.cached = false
+	mp.mu.Unlock()
+
+	// As in MarkComplete, the torrent lock is taken without the piece
+	// lock: FreePieces and Close hold it while dropping pieces.
+	if uncache {
+		mp.torrent.cache.remove(mp.torrent.infoHash, mp.index)
+		mp.torrent.setCached(mp.index, false)
+	}
 	return nil
 }
 
@@ -150,7 +489,7 @@ func (mp *memPiece) Completion() storage.Completion {
 	mp.mu.RLock()
 	defer mp.mu.RUnlock()
 	return storage.Completion{
-		Complete: mp.complete,
+		Complete: mp.complete || mp.cached,
 		Ok:       true,
 	}
 }
@@ -0,0 +1,295 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"errors"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/anacrolix/torrent/storage"
+)
+
+const testPieceLen = 16
+
+// testPieces returns n pieces of testPieceLen bytes, each filled with its
+// index.
+func testPieces(n int) [][]byte {
+	pieces := make([][]byte, n)
+	for i := range pieces {
+		pieces[i] = bytes.Repeat([]byte{byte(i)}, testPieceLen)
+	}
+	return pieces
+}
+
+// openTestTorrent opens a torrent made of pieces in ms.
+func openTestTorrent(t *testing.T, ms *MemoryStorage, hash byte, pieces [][]byte) (*metainfo.Info, storage.TorrentImpl) {
+	t.Helper()
+	info := &metainfo.Info{
+		Name:        "test",
+		PieceLength: testPieceLen,
+		Length:      int64(len(pieces)) * testPieceLen,
+	}
+	for _, p := range pieces {
+		sum := sha1.Sum(p)
+		info.Pieces = append(info.Pieces, sum[:]...)
+	}
+	impl, err := ms.OpenTorrent(context.Background(), info, metainfo.Hash{hash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info, impl
+}
+
+func TestReadAtUndownloadedPiece(t *testing.T) {
+	ms := NewMemory()
+	info, tor := openTestTorrent(t, ms, 1, testPieces(2))
+	p := tor.Piece(info.Piece(0))
+
+	buf := make([]byte, testPieceLen)
+	if n, err := p.ReadAt(buf, 0); !errors.Is(err, io.ErrUnexpectedEOF) || n != 0 {
+		t.Errorf("ReadAt of an unwritten piece = %d, %v; want 0, ErrUnexpectedEOF", n, err)
+	}
+
+	chunk := bytes.Repeat([]byte{0xab}, 8)
+	if _, err := p.WriteAt(chunk, 8); err != nil {
+		t.Fatal(err)
+	}
+	n, err := p.ReadAt(buf[:8], 8)
+	if err != nil || n != 8 || !bytes.Equal(buf[:8], chunk) {
+		t.Errorf("ReadAt of the written chunk = %d, %v, % x", n, err, buf[:8])
+	}
+	if p.Completion().Complete {
+		t.Error("a partly written piece reports complete")
+	}
+}
+
+// checkBudget fails unless every piece of mts holding data is counted
+// against the budget and the count matches the resident pieces. mts must
+// be all the torrents open in ms.
+func checkBudget(t *testing.T, ms *MemoryStorage, mts ...*MemTorrent) {
+	t.Helper()
+	var counted int64
+	for _, mt := range mts {
+		mt.mu.Lock()
+		for i, mp := range mt.pieces {
+			mp.mu.RLock()
+			hasData := mp.data != nil
+			mp.mu.RUnlock()
+			resident := ms.resident(mp)
+			if hasData && !resident {
+				t.Errorf("torrent %x piece %d holds data but isn't counted", mt.infoHash[:1], i)
+			}
+			if resident {
+				counted += mp.len
+			}
+		}
+		mt.mu.Unlock()
+	}
+	if got := ms.BytesInUse(); got != counted {
+		t.Errorf("BytesInUse = %d, but resident pieces add up to %d", got, counted)
+	}
+	if limit := ms.Limit(); limit > 0 && counted > limit {
+		t.Errorf("%d bytes resident, over the %d byte limit", counted, limit)
+	}
+}
+
+func TestConcurrentWritesStayInBudget(t *testing.T) {
+	const n = 16
+	ms := NewMemoryWithLimit(4 * testPieceLen)
+	pieces := testPieces(n)
+	info, tor := openTestTorrent(t, ms, 1, pieces)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				idx := (i*7 + w) % n
+				p := tor.Piece(info.Piece(idx))
+				if _, err := p.WriteAt(pieces[idx][:8], 0); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	checkBudget(t, ms, ms.GetTorrent(metainfo.Hash{1}))
+}
+
+// residentPieces lists the pieces of mt that hold data.
+func residentPieces(mt *MemTorrent) []int {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	var idx []int
+	for i, mp := range mt.pieces {
+		mp.mu.RLock()
+		if mp.data != nil {
+			idx = append(idx, i)
+		}
+		mp.mu.RUnlock()
+	}
+	slices.Sort(idx)
+	return idx
+}
+
+func TestTwoTorrentsShareBudget(t *testing.T) {
+	const limit = 4 * testPieceLen
+	ms := NewMemoryWithLimit(limit)
+	pieces := testPieces(4)
+	infoA, torA := openTestTorrent(t, ms, 1, pieces)
+	infoB, torB := openTestTorrent(t, ms, 2, pieces)
+	mtA, mtB := ms.GetTorrent(metainfo.Hash{1}), ms.GetTorrent(metainfo.Hash{2})
+
+	for i := range pieces {
+		if _, err := torA.Piece(infoA.Piece(i)).WriteAt(pieces[i], 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Reading piece 0 makes it A's most recently used.
+	if _, err := torA.Piece(infoA.Piece(0)).ReadAt(make([]byte, testPieceLen), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// B's writes push out A's least recently used pieces, 1 and 2.
+	for i := 0; i < 2; i++ {
+		if _, err := torB.Piece(infoB.Piece(i)).WriteAt(pieces[i], 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := residentPieces(mtA), []int{0, 3}; !slices.Equal(got, want) {
+		t.Errorf("torrent A keeps pieces %v, want %v", got, want)
+	}
+	if got, want := residentPieces(mtB), []int{0, 1}; !slices.Equal(got, want) {
+		t.Errorf("torrent B keeps pieces %v, want %v", got, want)
+	}
+	if got := ms.BytesInUse(); got != limit {
+		t.Errorf("BytesInUse = %d, want %d", got, limit)
+	}
+	checkBudget(t, ms, mtA, mtB)
+
+	// Both torrents writing at once still share one budget.
+	var wg sync.WaitGroup
+	for _, tt := range []struct {
+		info *metainfo.Info
+		tor  storage.TorrentImpl
+	}{{infoA, torA}, {infoB, torB}} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				idx := i % len(pieces)
+				if _, err := tt.tor.Piece(tt.info.Piece(idx)).WriteAt(pieces[idx][:8], 0); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if got := ms.BytesInUse(); got > limit {
+		t.Errorf("BytesInUse = %d, over the %d byte limit", got, limit)
+	}
+	checkBudget(t, ms, mtA, mtB)
+}
+
+func TestCachedPieceReloadsWithinBudget(t *testing.T) {
+	cache, err := NewPieceCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms := NewMemoryWithLimit(testPieceLen)
+	ms.SetCache(cache)
+	pieces := testPieces(2)
+	info, tor := openTestTorrent(t, ms, 1, pieces)
+
+	p0 := tor.Piece(info.Piece(0))
+	if _, err := p0.WriteAt(pieces[0], 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := p0.MarkComplete(); err != nil {
+		t.Fatal(err)
+	}
+	// Piece 1 only fits by evicting piece 0, which stays in the cache.
+	if _, err := tor.Piece(info.Piece(1)).WriteAt(pieces[1], 0); err != nil {
+		t.Fatal(err)
+	}
+	if !p0.Completion().Complete {
+		t.Fatal("an evicted but cached piece reports incomplete")
+	}
+
+	buf := make([]byte, testPieceLen)
+	if _, err := p0.ReadAt(buf, 0); err != nil {
+		t.Fatalf("ReadAt of a cached piece: %v", err)
+	}
+	if !bytes.Equal(buf, pieces[0]) {
+		t.Errorf("cached piece read back as % x", buf)
+	}
+	// Reading it again uses the loaded copy and reserves nothing more.
+	if _, err := p0.ReadAt(buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := ms.BytesInUse(); got != testPieceLen {
+		t.Errorf("BytesInUse = %d, want one piece", got)
+	}
+	checkBudget(t, ms, ms.GetTorrent(metainfo.Hash{1}))
+}
+
+func TestMarkNotCompleteWhileFreeing(t *testing.T) {
+	cache, err := NewPieceCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms := NewMemory()
+	ms.SetCache(cache)
+	const n = 8
+	pieces := testPieces(n)
+	info, tor := openTestTorrent(t, ms, 1, pieces)
+	mt := ms.GetTorrent(metainfo.Hash{1})
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			// Pieces failing their hash check after being cached. Each
+			// worker owns its pieces, as the client never writes a piece
+			// it is marking.
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				idx := w*2 + i%2
+				p := tor.Piece(info.Piece(idx))
+				if _, err := p.WriteAt(pieces[idx], 0); err != nil {
+					t.Error(err)
+					return
+				}
+				_ = p.MarkComplete()
+				_ = p.MarkNotComplete()
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		// An episode being freed meanwhile.
+		defer wg.Done()
+		for i := 0; i < 500; i++ {
+			mt.FreePieces(0, n)
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("MarkNotComplete and FreePieces deadlocked")
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package stream
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isAddrInUse reports whether err is a failed bind to a taken port.
+func isAddrInUse(err error) bool {
+	return errors.Is(err, syscall.EADDRINUSE)
+}
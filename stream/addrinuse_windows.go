@@ -0,0 +1,16 @@
+//go:build windows
+
+package stream
+
+import (
+	"errors"
+	"syscall"
+)
+
+// wsaeaddrinuse is Winsock's WSAEADDRINUSE, which syscall doesn't name.
+const wsaeaddrinuse = syscall.Errno(10048)
+
+// isAddrInUse reports whether err is a failed bind to a taken port.
+func isAddrInUse(err error) bool {
+	return errors.Is(err, wsaeaddrinuse) || errors.Is(err, syscall.EADDRINUSE)
+}
@@ -0,0 +1,85 @@
+//go:build !windows
+
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// maxSocketPath is the longest Unix socket path every supported system
+// accepts (sun_path is 104 bytes on macOS, with the terminating NUL).
+const maxSocketPath = 103
+
+// checkUnixPath reports whether path can name the stream socket.
+func checkUnixPath(path string) error {
+	if path == "" {
+		return errors.New("no socket path given")
+	}
+	if len(path) > maxSocketPath {
+		return fmt.Errorf("path too long for a socket (%d > %d bytes)", len(path), maxSocketPath)
+	}
+	return nil
+}
+
+// listenUnix binds a Unix domain socket at path, readable by this user
+// only. A socket left behind by a crashed session is replaced; one that
+// still answers is not, nor is any other file.
+//
+// The socket is bound inside a fresh 0700 directory, restricted, then
+// linked into place: a chmod after binding at path would leave a window
+// where other users could connect, and the umask is process-wide.
+func listenUnix(path string) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, &BindError{Addr: path, InUse: true}
+		}
+		_ = os.Remove(path)
+	}
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".just-stream-")
+	if err != nil {
+		return nil, &BindError{Addr: path, Err: err}
+	}
+	defer os.RemoveAll(dir)
+
+	tmp := filepath.Join(dir, "stream.sock")
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmp, Net: "unix"})
+	if err != nil {
+		return nil, &BindError{Addr: path, Err: err}
+	}
+	// The listener is closed by path below, not by its bind name.
+	ln.SetUnlinkOnClose(false)
+	if err := os.Chmod(tmp, 0o600); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("restrict stream socket: %w", err)
+	}
+	if err := os.Link(tmp, path); err != nil {
+		ln.Close()
+		return nil, &BindError{Addr: path, InUse: errors.Is(err, os.ErrExist), Err: err}
+	}
+	return &unixListener{UnixListener: ln, path: path}, nil
+}
+
+// unixListener reports and removes the socket under the path it was
+// linked to.
+type unixListener struct {
+	*net.UnixListener
+	path string
+	once sync.Once
+}
+
+func (l *unixListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: l.path, Net: "unix"}
+}
+
+func (l *unixListener) Close() error {
+	err := l.UnixListener.Close()
+	l.once.Do(func() { _ = os.Remove(l.path) })
+	return err
+}
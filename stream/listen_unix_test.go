@@ -0,0 +1,87 @@
+//go:build !windows
+
+package stream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStreamOverUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stream.sock")
+	s, err := NewServerWithOptions(ServerOptions{ListenAddr: UnixPrefix + path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := testData(1000)
+	s.files = []streamFile{&fakeFile{name: "ep.mkv", data: data}}
+	go s.Serve()
+
+	if s.Network() != "unix" || s.Addr() != path {
+		t.Errorf("listening on %s %s, want unix %s", s.Network(), s.Addr(), path)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0o600 {
+		t.Errorf("socket mode %o, want 600", perm)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("socket directory holds %d entries, want just the socket", len(entries))
+	}
+
+	// A second server must not take over a socket that still answers.
+	_, err = NewServerWithOptions(ServerOptions{ListenAddr: UnixPrefix + path})
+	var bindErr *BindError
+	if !errors.As(err, &bindErr) || !bindErr.InUse {
+		t.Errorf("second server on the socket: %v, want in use", err)
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get(s.FileURL(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(body, data) {
+		t.Errorf("status %d with %d bytes, want 200 with the file", resp.StatusCode, len(body))
+	}
+	client.CloseIdleConnections()
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("socket left behind after Close: %v", err)
+	}
+}
+
+func TestListenUnixKeepsOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("keep"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewServerWithOptions(ServerOptions{ListenAddr: UnixPrefix + path}); err == nil {
+		t.Fatal("listening over a regular file succeeded")
+	}
+	if got, err := os.ReadFile(path); err != nil || string(got) != "keep" {
+		t.Errorf("file now %q, %v; want it untouched", got, err)
+	}
+}
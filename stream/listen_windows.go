@@ -0,0 +1,22 @@
+//go:build windows
+
+package stream
+
+import (
+	"errors"
+	"net"
+)
+
+// errNoUnix is returned for a unix: listen address on Windows, where the
+// server stays on TCP.
+var errNoUnix = errors.New("unix socket streaming is not supported on Windows")
+
+// checkUnixPath rejects every path; see errNoUnix.
+func checkUnixPath(path string) error {
+	return errNoUnix
+}
+
+// listenUnix is unsupported on Windows.
+func listenUnix(path string) (net.Listener, error) {
+	return nil, errNoUnix
+}
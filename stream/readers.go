@@ -0,0 +1,127 @@
+package stream
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/anacrolix/torrent"
+)
+
+// Players often hold more than one connection to a file: mpv probes the
+// container (the header, then the index at the end of an .mkv or .mp4)
+// alongside the connection it plays from. Every reader's readahead raises
+// piece priorities, so a probe reading ahead by the full amount competes
+// with playback for the same bandwidth. The server therefore sorts
+// readers per file:
+//
+//   - A request is a probe when it is a HEAD, a closed Range of at most
+//     probeRangeBytes, or a Range starting in the tail window: the last
+//     tailProbeBytes of the file, but no more than 1/tailProbeShare of it.
+//     Probes get probeReadahead.
+//   - Any other request is a playback reader. The newest one gets the full
+//     readahead; older ones still open, e.g. after a seek whose old
+//     connection hasn't closed yet, drop to probeReadahead until the
+//     newer ones finish.
+//
+// mpv reads the index with an open-ended Range, just like a seek, so a
+// seek into the tail window is taken for a probe too and plays out with
+// the small readahead. Capping the window at a share of the file keeps
+// that to the last few percent of a small file, where only the credits
+// usually are.
+
+// Probe detection thresholds and the readahead given to probes.
+const (
+	probeRangeBytes = 4 << 20
+	tailProbeBytes  = 16 << 20
+	tailProbeShare  = 16
+	probeReadahead  = 256 << 10
+)
+
+// streamReader is an open reader registered with the server.
+type streamReader struct {
+	r        torrent.Reader
+	playback bool  // not a probe
+	full     int64 // readahead when it is the file's main reader
+}
+
+// isProbe reports whether a request for a file of the given length looks
+// like a player probing the container rather than playing it.
+func isProbe(method, rangeHeader string, length int64) bool {
+	if method == "HEAD" {
+		return true
+	}
+	spec, ok := strings.CutPrefix(strings.TrimSpace(rangeHeader), "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return false
+	}
+	first, last, ok := strings.Cut(spec, "-")
+	if !ok {
+		return false
+	}
+	if first == "" {
+		// A suffix range: the last n bytes.
+		n, err := strconv.ParseInt(last, 10, 64)
+		return err == nil && n <= tailProbeBytes
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return false
+	}
+	if last != "" {
+		end, err := strconv.ParseInt(last, 10, 64)
+		if err == nil && end >= start && end-start+1 <= probeRangeBytes {
+			return true
+		}
+	}
+	return start < length && length-start <= tailWindow(length)
+}
+
+// tailWindow returns how much of the end of a file of the given length
+// counts as the container index for probe detection.
+func tailWindow(length int64) int64 {
+	return min(tailProbeBytes, length/tailProbeShare)
+}
+
+// addReader registers sr for file idx and sets its readahead, demoting
+// the file's other playback readers if sr is one.
+func (s *Server) addReader(idx int, sr *streamReader) {
+	s.readersMu.Lock()
+	defer s.readersMu.Unlock()
+	if s.readers == nil {
+		s.readers = make(map[int][]*streamReader)
+	}
+	if sr.playback {
+		for _, o := range s.readers[idx] {
+			if o.playback {
+				o.r.SetReadahead(probeReadahead)
+			}
+		}
+		sr.r.SetReadahead(sr.full)
+	} else {
+		sr.r.SetReadahead(min(sr.full, probeReadahead))
+	}
+	s.readers[idx] = append(s.readers[idx], sr)
+}
+
+// removeReader unregisters sr and gives the newest remaining playback
+// reader of the file its full readahead back.
+func (s *Server) removeReader(idx int, sr *streamReader) {
+	s.readersMu.Lock()
+	defer s.readersMu.Unlock()
+	list := slices.DeleteFunc(s.readers[idx], func(o *streamReader) bool { return o == sr })
+	if len(list) == 0 {
+		delete(s.readers, idx)
+		return
+	}
+	s.readers[idx] = list
+	if !sr.playback {
+		return
+	}
+	for i := len(list) - 1; i >= 0; i-- {
+		if list[i].playback {
+			list[i].r.SetReadahead(list[i].full)
+			return
+		}
+	}
+}
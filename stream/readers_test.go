@@ -0,0 +1,98 @@
+package stream
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestIsProbe(t *testing.T) {
+	const (
+		big   = 2 << 30   // a 2 GB episode: the tail window is tailProbeBytes
+		small = 100 << 20 // a 100 MB file: the tail window is 1/16 of it
+	)
+	tests := []struct {
+		name   string
+		method string
+		rng    string
+		length int64
+		want   bool
+	}{
+		{"HEAD", "HEAD", "", big, true},
+		{"plain GET", "GET", "", big, false},
+		{"from the start", "GET", "bytes=0-", big, false},
+		{"suffix", "GET", "bytes=-65536", big, true},
+		{"long suffix", "GET", "bytes=-33554432", big, false},
+		{"short closed range", "GET", "bytes=0-4095", big, true},
+		{"closed range at the probe limit", "GET", "bytes=1000-4195303", big, true},
+		{"long closed range", "GET", "bytes=0-8388607", big, false},
+		{"tail of the file", "GET", "bytes=2146435072-", big, true},
+		{"seek to the middle", "GET", "bytes=1073741824-", big, false},
+		{"seek short of the tail window", "GET", "bytes=2130706431-", big, false},
+		{"tail of a small file", "GET", "bytes=102236160-", small, true},
+		{"seek near the end of a small file", "GET", "bytes=94371840-", small, false},
+		{"multiple ranges", "GET", "bytes=0-99,200-299", big, false},
+		{"malformed", "GET", "bytes=abc-", big, false},
+		{"not bytes", "GET", "items=0-5", big, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isProbe(tt.method, tt.rng, tt.length); got != tt.want {
+				t.Errorf("isProbe(%q, %q, %d) = %v, want %v", tt.method, tt.rng, tt.length, got, tt.want)
+			}
+		})
+	}
+}
+
+func newTestReader() *fakeReader {
+	return &fakeReader{ReadSeeker: bytes.NewReader(nil)}
+}
+
+func TestNewestPlaybackReaderGetsFullReadahead(t *testing.T) {
+	const full = 8 << 20
+	var s Server
+	first := &streamReader{r: newTestReader(), playback: true, full: full}
+	s.addReader(0, first)
+	if got := first.r.(*fakeReader).getReadahead(); got != full {
+		t.Fatalf("only reader: readahead %d, want %d", got, full)
+	}
+
+	// A probe doesn't take the readahead from playback.
+	probe := &streamReader{r: newTestReader(), full: full}
+	s.addReader(0, probe)
+	if got := probe.r.(*fakeReader).getReadahead(); got != probeReadahead {
+		t.Errorf("probe: readahead %d, want %d", got, probeReadahead)
+	}
+	if got := first.r.(*fakeReader).getReadahead(); got != full {
+		t.Errorf("first reader after a probe: readahead %d, want %d", got, full)
+	}
+
+	// A seek opens a newer playback reader, which takes over.
+	second := &streamReader{r: newTestReader(), playback: true, full: full}
+	s.addReader(0, second)
+	if got := second.r.(*fakeReader).getReadahead(); got != full {
+		t.Errorf("newest reader: readahead %d, want %d", got, full)
+	}
+	if got := first.r.(*fakeReader).getReadahead(); got != probeReadahead {
+		t.Errorf("older reader: readahead %d, want %d", got, probeReadahead)
+	}
+
+	// Readers of other files are left alone.
+	other := &streamReader{r: newTestReader(), playback: true, full: full}
+	s.addReader(1, other)
+	if got := second.r.(*fakeReader).getReadahead(); got != full {
+		t.Errorf("reader of file 0 after one of file 1: readahead %d, want %d", got, full)
+	}
+
+	// Closing the newest gives the previous one its readahead back.
+	s.removeReader(0, second)
+	if got := first.r.(*fakeReader).getReadahead(); got != full {
+		t.Errorf("previous reader after the newest closed: readahead %d, want %d", got, full)
+	}
+
+	s.removeReader(0, probe)
+	s.removeReader(0, first)
+	s.removeReader(1, other)
+	if len(s.readers) != 0 {
+		t.Errorf("%d files still have readers registered", len(s.readers))
+	}
+}
@@ -1,9 +1,17 @@
 package stream
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net"
 	"net/http"
+	"path"
 	"strconv"
 	"strings"
 	"sync"
@@ -13,27 +21,190 @@ import (
 )
 
 // Server serves torrent files over HTTP with range-request support.
-// Each file is available at /stream/<index> for mpv playlist integration.
+// Each file is available at /stream/<index> for mpv playlist integration,
+// and a JSON summary of the torrent at /status.
 type Server struct {
 	mu       sync.RWMutex
-	files    []*torrent.File
+	files    []streamFile
 	listener net.Listener
 	srv      *http.Server
+
+	// memLimit is the storage RAM budget in bytes; 0 means unlimited.
+	memLimit int64
+
+	// Readahead per reader: readaheadPercent of the file, but at least
+	// readaheadBytes. Zero values use the defaults.
+	readaheadBytes   int64
+	readaheadPercent int
+
+	// firstByte is when the server first delivered file data, for
+	// measuring startup latency.
+	firstByte time.Time
+
+	// urlHost is the host:port put in stream URLs: the bound address, or
+	// the machine's routable IP when bound to all interfaces.
+	urlHost string
+
+	// token must accompany every stream request so other local
+	// processes can't read the stream.
+	token string
+
+	// dlna adds DLNA headers to stream responses.
+	dlna bool
+
+	// bindWarning explains a fallback from the requested listen address.
+	bindWarning string
+
+	// Playback state reported at /status; see SetPlaybackState.
+	current          int
+	downRate, upRate float64
+
+	// Open readers per file index, oldest first, for coordinating their
+	// readahead.
+	readersMu sync.Mutex
+	readers   map[int][]*streamReader
+}
+
+// streamFile is the part of a *torrent.File the server uses.
+type streamFile interface {
+	DisplayPath() string
+	Length() int64
+	BytesCompleted() int64
+	NewReader() torrent.Reader
+	Torrent() *torrent.Torrent
+}
+
+// ServerOptions configures NewServerWithOptions.
+type ServerOptions struct {
+	// TokenBytes is the length of the random access token before hex
+	// encoding. 0 uses DefaultTokenBytes; shorter than MinTokenBytes is
+	// raised to it.
+	TokenBytes int
+	// ListenAddr is the host:port to bind. Empty uses DefaultListenAddr,
+	// reachable from this machine only; a LAN address (or 0.0.0.0:0)
+	// lets other devices such as a TV play the stream. "unix:" and a path
+	// serves on a Unix domain socket only this user can open (not on
+	// Windows).
+	ListenAddr string
+	// DLNAHeaders adds the transferMode.dlna.org and
+	// contentFeatures.dlna.org headers DLNA renderers expect, and answers
+	// TimeSeekRange.dlna.org requests.
+	DLNAHeaders bool
 }
 
+// DefaultListenAddr binds a random localhost port.
+const DefaultListenAddr = "127.0.0.1:0"
+
+// UnixPrefix starts a ListenAddr that names a Unix socket path.
+const UnixPrefix = "unix:"
+
+// CheckListenAddr reports whether addr can be used as
+// ServerOptions.ListenAddr: host:port, or "unix:" and a socket path.
+func CheckListenAddr(addr string) error {
+	if path, ok := strings.CutPrefix(addr, UnixPrefix); ok {
+		return checkUnixPath(path)
+	}
+	_, _, err := net.SplitHostPort(addr)
+	return err
+}
+
+// BindError reports that the stream server could not listen on Addr.
+// InUse is set when the port was taken by another process.
+type BindError struct {
+	Addr  string
+	InUse bool
+	Err   error
+}
+
+func (e *BindError) Error() string {
+	if e.InUse {
+		what := "port"
+		if _, _, err := net.SplitHostPort(e.Addr); err != nil {
+			what = "socket"
+		}
+		return fmt.Sprintf("listen on %s: %s already in use", e.Addr, what)
+	}
+	return fmt.Sprintf("listen on %s: %v", e.Addr, e.Err)
+}
+
+func (e *BindError) Unwrap() error { return e.Err }
+
+// Access token length bounds, in random bytes.
+const (
+	DefaultTokenBytes = 16
+	MinTokenBytes     = 8
+)
+
+// Default readahead: 5% of the file or 8 MB, whichever is larger.
+const (
+	DefaultReadaheadBytes   = 8 * 1024 * 1024
+	DefaultReadaheadPercent = 5
+)
+
+// readaheadBudgetDivisor bounds a reader's readahead to this fraction of
+// the memory budget, leaving room for the piece being played and for
+// pieces the client has in flight.
+const readaheadBudgetDivisor = 4
+
 // NewServer creates a streaming HTTP server bound to a random localhost port.
 func NewServer() (*Server, error) {
-	ln, err := net.Listen("tcp", "127.0.0.1:0")
-	if err != nil {
-		return nil, fmt.Errorf("listen: %w", err)
+	return NewServerWithOptions(ServerOptions{})
+}
+
+// NewServerWithOptions is NewServer with explicit options.
+func NewServerWithOptions(opts ServerOptions) (*Server, error) {
+	n := opts.TokenBytes
+	if n == 0 {
+		n = DefaultTokenBytes
+	}
+	if n < MinTokenBytes {
+		n = MinTokenBytes
+	}
+	raw := make([]byte, n)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("generate token: %w", err)
+	}
+
+	var (
+		ln      net.Listener
+		host    string
+		warning string
+		err     error
+	)
+	if path, ok := strings.CutPrefix(opts.ListenAddr, UnixPrefix); ok {
+		if err := checkUnixPath(path); err != nil {
+			return nil, fmt.Errorf("stream socket %q: %w", path, err)
+		}
+		ln, err = listenUnix(path)
+		if err != nil {
+			return nil, err
+		}
+		// Clients dial the socket; the host only fills the URL.
+		host = "localhost"
+	} else {
+		addr := opts.ListenAddr
+		if addr == "" {
+			addr = DefaultListenAddr
+		}
+		ln, warning, err = listen(addr)
+		if err != nil {
+			return nil, err
+		}
+		host = urlHost(ln.Addr().(*net.TCPAddr))
 	}
 
 	s := &Server{
-		listener: ln,
+		listener:    ln,
+		urlHost:     host,
+		token:       hex.EncodeToString(raw),
+		dlna:        opts.DLNAHeaders,
+		bindWarning: warning,
+		current:     -1,
 	}
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/stream/", s.handleStream)
+	mux.HandleFunc("/status", s.handleStatus)
 
 	s.srv = &http.Server{
 		Handler:      mux,
@@ -44,34 +215,179 @@ func NewServer() (*Server, error) {
 	return s, nil
 }
 
+// listen binds addr. When a fixed port is taken, it falls back to a random
+// port on the same host and says so in warning; stream URLs carry the
+// port, so players follow along. Failures are *BindError.
+func listen(addr string) (ln net.Listener, warning string, err error) {
+	ln, err = net.Listen("tcp", addr)
+	if err == nil {
+		return ln, "", nil
+	}
+	bindErr := &BindError{Addr: addr, InUse: isAddrInUse(err), Err: err}
+	host, port, splitErr := net.SplitHostPort(addr)
+	if !bindErr.InUse || splitErr != nil || port == "0" {
+		return nil, "", bindErr
+	}
+	fallback := net.JoinHostPort(host, "0")
+	ln, err = net.Listen("tcp", fallback)
+	if err != nil {
+		return nil, "", bindErr
+	}
+	slog.Warn("stream port in use, using a random port", "addr", addr, "bound", ln.Addr().String())
+	warning = fmt.Sprintf("Port %s is in use; streaming on %s instead", port, ln.Addr())
+	return ln, warning, nil
+}
+
+// BindWarning describes a fallback from the requested listen address,
+// or returns "" if the server bound it as asked.
+func (s *Server) BindWarning() string {
+	return s.bindWarning
+}
+
+// urlHost returns the host:port other devices can reach a listener bound
+// to addr at. An unspecified address (0.0.0.0 or ::) is replaced with the
+// IP of the interface that routes outward, falling back to loopback.
+func urlHost(addr *net.TCPAddr) string {
+	ip := addr.IP
+	if ip.IsUnspecified() {
+		ip = outboundIP()
+	}
+	return net.JoinHostPort(ip.String(), strconv.Itoa(addr.Port))
+}
+
+// outboundIP returns the local IP used to reach the wider network. Dialing
+// UDP only picks a route; no packet is sent.
+func outboundIP() net.IP {
+	conn, err := net.Dial("udp", "192.0.2.1:9")
+	if err != nil {
+		return net.IPv4(127, 0, 0, 1)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP
+}
+
+// IsLoopback reports whether a listen address only accepts connections
+// from this machine. Host names other than localhost count as not
+// loopback; a Unix socket counts as loopback.
+func IsLoopback(addr string) bool {
+	if strings.HasPrefix(addr, UnixPrefix) {
+		return true
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
 // SetFiles sets all the torrent files available for streaming.
 func (s *Server) SetFiles(files []*torrent.File) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.files = files
+	s.files = make([]streamFile, len(files))
+	for i, f := range files {
+		s.files[i] = f
+	}
+}
+
+// SetMemoryLimit tells the server how much RAM the storage layer may hold,
+// so readahead can be kept inside it. A limit of 0 disables clamping.
+func (s *Server) SetMemoryLimit(limit int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.memLimit = limit
 }
 
-// FileURL returns the stream URL for a specific file index.
+// SetReadahead sets how far each stream reader reads ahead: percent of
+// the file, but at least minBytes. Zero values keep the defaults.
+func (s *Server) SetReadahead(minBytes int64, percent int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readaheadBytes = minBytes
+	s.readaheadPercent = percent
+}
+
+// FirstByteAt returns when the server first delivered file data, or the
+// zero time if nothing has been served yet.
+func (s *Server) FirstByteAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.firstByte
+}
+
+func (s *Server) markFirstByte() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.firstByte.IsZero() {
+		s.firstByte = time.Now()
+	}
+}
+
+// FileURL returns the stream URL for a specific file index, including the
+// access token.
 func (s *Server) FileURL(idx int) string {
-	return fmt.Sprintf("http://%s/stream/%d", s.listener.Addr().String(), idx)
+	return fmt.Sprintf("http://%s/stream/%d?token=%s", s.urlHost, idx, s.token)
 }
 
-// Addr returns the listener address.
+// authorized reports whether r carries the access token, either as the
+// token query parameter or in an "Authorization: Bearer" header. The
+// scheme is matched case-insensitively, as HTTP requires.
+func (s *Server) authorized(r *http.Request) bool {
+	got := r.URL.Query().Get("token")
+	if got == "" {
+		scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return false
+		}
+		got = strings.TrimSpace(token)
+	}
+	return subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) == 1
+}
+
+// Addr returns the listener address: host:port, or the socket path.
 func (s *Server) Addr() string {
 	return s.listener.Addr().String()
 }
 
+// Network returns "tcp" or "unix". Stream URLs of a unix server name
+// localhost; clients must connect to the socket at Addr, e.g. with
+// curl --unix-socket.
+func (s *Server) Network() string {
+	return s.listener.Addr().Network()
+}
+
 // Serve starts the HTTP server (blocks until closed).
 func (s *Server) Serve() error {
 	return s.srv.Serve(s.listener)
 }
 
-// Close shuts down the HTTP server.
+// Close shuts down the HTTP server, dropping active connections.
 func (s *Server) Close() error {
 	return s.srv.Close()
 }
 
+// Shutdown stops accepting connections and waits for in-flight responses
+// to finish until ctx is done, then closes whatever is still open.
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.srv.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return s.srv.Close()
+	}
+	return err
+}
+
 func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		slog.Warn("stream request rejected: bad or missing token", "path", r.URL.Path, "remote", r.RemoteAddr)
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	slog.Debug("stream request", "method", r.Method, "path", r.URL.Path, "range", r.Header.Get("Range"))
+
 	// Parse file index from /stream/<idx>
 	idxStr := strings.TrimPrefix(r.URL.Path, "/stream/")
 	idx, err := strconv.Atoi(idxStr)
@@ -87,21 +403,192 @@ func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	f := s.files[idx]
+	memLimit := s.memLimit
+	minBytes, percent := s.readaheadBytes, s.readaheadPercent
 	s.mu.RUnlock()
 
 	reader := f.NewReader()
 	defer reader.Close()
+	// Reads block until the requested chunks have arrived, however far
+	// mpv seeks. Tie them to the request so an abandoned seek (mpv
+	// reconnects with a new Range on every jump) stops waiting and stops
+	// prioritizing its pieces.
+	reader.SetContext(r.Context())
 
-	// Readahead: 5% of file or 8 MB, whichever is larger.
-	readahead := f.Length() / 20
-	if readahead < 8*1024*1024 {
-		readahead = 8 * 1024 * 1024
-	}
-	if readahead > f.Length() {
-		readahead = f.Length()
+	// Probes read ahead only a little, and only the newest playback
+	// reader of a file gets the full readahead; see readers.go.
+	sr := &streamReader{
+		r:        reader,
+		playback: !isProbe(r.Method, r.Header.Get("Range"), f.Length()),
+		full:     clampReadahead(readaheadFor(f.Length(), minBytes, percent), memLimit),
 	}
-	reader.SetReadahead(readahead)
+	s.addReader(idx, sr)
+	defer s.removeReader(idx, sr)
+	slog.Debug("stream reader", "file", idx, "playback", sr.playback)
+	// Responsive reads return chunks as soon as they are written, before
+	// the whole piece is hash-checked; chunks not yet received are still
+	// waited for, never read from storage.
 	reader.SetResponsive()
 
-	http.ServeContent(w, r, f.DisplayPath(), time.Time{}, reader)
+	// Set the type up front: ServeContent would otherwise sniff it, which
+	// often guesses wrong for .mkv and .ts.
+	w.Header().Set("Content-Type", contentType(f.DisplayPath()))
+	if s.dlna {
+		if !timeSeekFromStart(r.Header.Get(dlnaTimeSeekHeader)) {
+			// Mapping a time to a byte offset needs the container's
+			// index; contentFeatures tells renderers to seek by byte.
+			http.Error(w, "time seek not supported, use Range", http.StatusNotAcceptable)
+			return
+		}
+		setDLNAHeaders(w.Header())
+	}
+	http.ServeContent(w, r, f.DisplayPath(), time.Time{}, firstByteReader{Reader: reader, s: s, length: f.Length()})
+}
+
+// videoTypes maps media extensions to their MIME types.
+var videoTypes = map[string]string{
+	".mkv":  "video/x-matroska",
+	".mk3d": "video/x-matroska",
+	".mka":  "audio/x-matroska",
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".mov":  "video/quicktime",
+	".ts":   "video/mp2t",
+	".m2ts": "video/mp2t",
+	".webm": "video/webm",
+	".avi":  "video/x-msvideo",
+	".wmv":  "video/x-ms-wmv",
+	".flv":  "video/x-flv",
+	".ogv":  "video/ogg",
+	".mpg":  "video/mpeg",
+	".mpeg": "video/mpeg",
+	".3gp":  "video/3gpp",
+	".mp3":  "audio/mpeg",
+	".flac": "audio/flac",
+	".m4a":  "audio/mp4",
+	".ogg":  "audio/ogg",
+	".opus": "audio/ogg",
+	".wav":  "audio/wav",
+}
+
+// contentType returns the MIME type for name based on its extension,
+// falling back to application/octet-stream.
+func contentType(name string) string {
+	if t, ok := videoTypes[strings.ToLower(path.Ext(name))]; ok {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// DLNA header names and values. ORG_OP=01 advertises byte (Range) seeking
+// but not time seeking; ORG_CI=0 marks the content as not transcoded; the
+// flags declare streaming transfer mode, background transfer, connection
+// stalling and DLNA 1.5.
+const (
+	dlnaTransferModeHeader    = "transferMode.dlna.org"
+	dlnaContentFeaturesHeader = "contentFeatures.dlna.org"
+	dlnaTimeSeekHeader        = "TimeSeekRange.dlna.org"
+
+	dlnaContentFeatures = "DLNA.ORG_OP=01;DLNA.ORG_CI=0;DLNA.ORG_FLAGS=01700000000000000000000000000000"
+)
+
+func setDLNAHeaders(h http.Header) {
+	h.Set(dlnaTransferModeHeader, "Streaming")
+	h.Set(dlnaContentFeaturesHeader, dlnaContentFeatures)
+}
+
+// timeSeekFromStart reports whether a TimeSeekRange.dlna.org value can be
+// answered as a plain request: it is absent or starts at zero
+// ("npt=0-", "npt=00:00:00.000-").
+func timeSeekFromStart(v string) bool {
+	if v == "" {
+		return true
+	}
+	npt, ok := strings.CutPrefix(strings.TrimSpace(v), "npt=")
+	if !ok {
+		return false
+	}
+	start, _, _ := strings.Cut(npt, "-")
+	return strings.Trim(start, "0:.") == ""
+}
+
+// firstByteReader records the first successful read on the server so
+// time-to-first-byte can be reported.
+type firstByteReader struct {
+	torrent.Reader
+	s      *Server
+	length int64 // file length, for io.SeekEnd
+}
+
+func (r firstByteReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if n > 0 {
+		r.s.markFirstByte()
+	}
+	return n, err
+}
+
+// Seek enforces io.Seeker semantics that http.ServeContent relies on when
+// answering Range requests: the torrent reader accepts any whence and
+// happily moves to a negative offset, which would turn a bad suffix range
+// into a garbage read instead of an error.
+func (r firstByteReader) Seek(offset int64, whence int) (int64, error) {
+	var base int64
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		var err error
+		if base, err = r.Reader.Seek(0, io.SeekCurrent); err != nil {
+			return 0, err
+		}
+	case io.SeekEnd:
+		base = r.length
+	default:
+		return 0, fmt.Errorf("seek: invalid whence %d", whence)
+	}
+	if base+offset < 0 {
+		return 0, errors.New("seek: negative position")
+	}
+	return r.Reader.Seek(base+offset, io.SeekStart)
+}
+
+// readaheadFor returns the readahead for a file of the given length:
+// percent of it, but at least minBytes and at most the whole file.
+func readaheadFor(length, minBytes int64, percent int) int64 {
+	if minBytes <= 0 {
+		minBytes = DefaultReadaheadBytes
+	}
+	if percent <= 0 {
+		percent = DefaultReadaheadPercent
+	}
+	readahead := length * int64(percent) / 100
+	if readahead < minBytes {
+		readahead = minBytes
+	}
+	if readahead > length {
+		readahead = length
+	}
+	return readahead
+}
+
+// clampReadahead limits readahead to a fraction of the memory budget.
+// A readahead larger than the budget makes storage evict pieces the
+// reader is about to consume, so they get downloaded twice.
+func clampReadahead(readahead, memLimit int64) int64 {
+	if memLimit <= 0 {
+		return readahead
+	}
+	if ceiling := memLimit / readaheadBudgetDivisor; readahead > ceiling {
+		return ceiling
+	}
+	return readahead
+}
+
+// CheckReadahead returns an error when the requested readahead would not
+// fit in the memory budget and is going to be clamped by the server.
+func CheckReadahead(readahead, memLimit int64) error {
+	if clampReadahead(readahead, memLimit) < readahead {
+		return fmt.Errorf("readahead of %d bytes exceeds a quarter of the %d byte memory limit and will be clamped", readahead, memLimit)
+	}
+	return nil
 }
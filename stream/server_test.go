@@ -0,0 +1,383 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+
+	memstorage "github.com/enrell/just-stream/storage"
+)
+
+// fakeFile is a streamFile backed by a byte slice.
+type fakeFile struct {
+	name      string
+	data      []byte
+	completed int64
+	// newReader, when set, replaces the default bytes.Reader.
+	newReader func() torrent.Reader
+}
+
+func (f *fakeFile) DisplayPath() string       { return f.name }
+func (f *fakeFile) Length() int64             { return int64(len(f.data)) }
+func (f *fakeFile) BytesCompleted() int64     { return f.completed }
+func (f *fakeFile) Torrent() *torrent.Torrent { return nil }
+
+func (f *fakeFile) NewReader() torrent.Reader {
+	if f.newReader != nil {
+		return f.newReader()
+	}
+	return &fakeReader{ReadSeeker: bytes.NewReader(f.data)}
+}
+
+// fakeReader is a torrent.Reader over an io.ReadSeeker that records the
+// readahead it is given.
+type fakeReader struct {
+	io.ReadSeeker
+
+	mu        sync.Mutex
+	readahead int64
+}
+
+func (r *fakeReader) SetContext(context.Context)             {}
+func (r *fakeReader) SetReadaheadFunc(torrent.ReadaheadFunc) {}
+func (r *fakeReader) SetResponsive()                         {}
+func (r *fakeReader) Close() error                           { return nil }
+
+func (r *fakeReader) ReadContext(_ context.Context, p []byte) (int, error) {
+	return r.Read(p)
+}
+
+func (r *fakeReader) SetReadahead(n int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.readahead = n
+}
+
+func (r *fakeReader) getReadahead() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.readahead
+}
+
+// newTestServer returns a server streaming files and an HTTP test server
+// in front of its handlers.
+func newTestServer(t *testing.T, files ...streamFile) (*Server, *httptest.Server) {
+	t.Helper()
+	s, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.listener.Close()
+	s.files = files
+	ts := httptest.NewServer(s.srv.Handler)
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func streamRequest(t *testing.T, s *Server, ts *httptest.Server, idx int, rangeHeader string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/stream/%d?token=%s", ts.URL, idx, s.token), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rangeHeader != "" {
+		req.Header.Set("Range", rangeHeader)
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func testData(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	return data
+}
+
+func TestStreamRange(t *testing.T) {
+	data := testData(1000)
+	s, ts := newTestServer(t, &fakeFile{name: "ep.mkv", data: data})
+
+	tests := []struct {
+		name         string
+		rangeHeader  string
+		status       int
+		contentRange string
+		body         []byte
+	}{
+		{"no range", "", http.StatusOK, "", data},
+		{"closed", "bytes=100-199", http.StatusPartialContent, "bytes 100-199/1000", data[100:200]},
+		{"open-ended", "bytes=900-", http.StatusPartialContent, "bytes 900-999/1000", data[900:]},
+		{"suffix", "bytes=-50", http.StatusPartialContent, "bytes 950-999/1000", data[950:]},
+		{"suffix longer than file", "bytes=-5000", http.StatusPartialContent, "bytes 0-999/1000", data},
+		{"end past file", "bytes=990-2000", http.StatusPartialContent, "bytes 990-999/1000", data[990:]},
+		{"start past file", "bytes=1000-", http.StatusRequestedRangeNotSatisfiable, "bytes */1000", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := streamRequest(t, s, ts, 0, tt.rangeHeader)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if got := resp.Header.Get("Content-Range"); got != tt.contentRange {
+				t.Errorf("Content-Range = %q, want %q", got, tt.contentRange)
+			}
+			if tt.body == nil {
+				return
+			}
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(body, tt.body) {
+				t.Errorf("body is %d bytes and differs from the %d expected", len(body), len(tt.body))
+			}
+		})
+	}
+}
+
+func TestStreamRejects(t *testing.T) {
+	s, ts := newTestServer(t, &fakeFile{name: "ep.mkv", data: testData(10)})
+	for _, tt := range []struct {
+		name, path, auth string
+		status           int
+	}{
+		{"missing token", "/stream/0", "", http.StatusForbidden},
+		{"wrong token", "/stream/0?token=nope", "", http.StatusForbidden},
+		{"bad index", "/stream/x?token=" + s.token, "", http.StatusBadRequest},
+		{"index out of range", "/stream/1?token=" + s.token, "", http.StatusNotFound},
+		{"bearer token", "/stream/0", "Bearer " + s.token, http.StatusOK},
+		{"bearer scheme in lower case", "/stream/0", "bearer " + s.token, http.StatusOK},
+		{"wrong bearer token", "/stream/0", "Bearer nope", http.StatusForbidden},
+		{"token without a scheme", "/stream/0", s.token, http.StatusForbidden},
+		{"other scheme", "/stream/0", "Basic " + s.token, http.StatusForbidden},
+		{"empty bearer", "/stream/0", "Bearer ", http.StatusForbidden},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", ts.URL+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			resp, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
+
+func TestFirstByteReaderSeek(t *testing.T) {
+	r := firstByteReader{Reader: &fakeReader{ReadSeeker: bytes.NewReader(testData(100))}, length: 100}
+	if pos, err := r.Seek(-10, io.SeekEnd); err != nil || pos != 90 {
+		t.Errorf("Seek(-10, SeekEnd) = %d, %v; want 90", pos, err)
+	}
+	if pos, err := r.Seek(5, io.SeekCurrent); err != nil || pos != 95 {
+		t.Errorf("Seek(5, SeekCurrent) = %d, %v; want 95", pos, err)
+	}
+	if _, err := r.Seek(-101, io.SeekEnd); err == nil {
+		t.Error("Seek before the start succeeded")
+	}
+	if _, err := r.Seek(0, 42); err == nil {
+		t.Error("Seek with an invalid whence succeeded")
+	}
+}
+
+func TestClampReadahead(t *testing.T) {
+	const mb = 1 << 20
+	tests := []struct {
+		name                string
+		readahead, memLimit int64
+		want                int64
+	}{
+		{"no limit", 64 * mb, 0, 64 * mb},
+		{"negative limit", 64 * mb, -1, 64 * mb},
+		{"within budget", 16 * mb, 256 * mb, 16 * mb},
+		{"at the ceiling", 64 * mb, 256 * mb, 64 * mb},
+		{"over the ceiling", 100 * mb, 256 * mb, 64 * mb},
+		{"larger than the budget", 512 * mb, 256 * mb, 64 * mb},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clampReadahead(tt.readahead, tt.memLimit); got != tt.want {
+				t.Errorf("clampReadahead(%d, %d) = %d, want %d", tt.readahead, tt.memLimit, got, tt.want)
+			}
+			err := CheckReadahead(tt.readahead, tt.memLimit)
+			if clamped := tt.want < tt.readahead; (err != nil) != clamped {
+				t.Errorf("CheckReadahead(%d, %d) = %v, want an error: %v", tt.readahead, tt.memLimit, err, clamped)
+			}
+		})
+	}
+}
+
+func TestStreamClampsReadaheadToMemoryLimit(t *testing.T) {
+	data := testData(1000)
+	readers := make(chan *fakeReader, 1)
+	f := &fakeFile{name: "ep.mkv", data: data, newReader: func() torrent.Reader {
+		r := &fakeReader{ReadSeeker: bytes.NewReader(data)}
+		readers <- r
+		return r
+	}}
+	s, ts := newTestServer(t, f)
+	s.SetReadahead(1000, 0)
+	s.SetMemoryLimit(400)
+
+	resp := streamRequest(t, s, ts, 0, "")
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		t.Fatal(err)
+	}
+	if got := (<-readers).getReadahead(); got != 100 {
+		t.Errorf("readahead = %d, want 100 (a quarter of the memory limit)", got)
+	}
+}
+
+// newMemoryTorrent adds a single-file torrent of pieces to a client that
+// never touches the network, stored in ms.
+func newMemoryTorrent(t *testing.T, ms *memstorage.MemoryStorage, pieces [][]byte) *torrent.Torrent {
+	t.Helper()
+	info := metainfo.Info{Name: "ep.mkv", PieceLength: int64(len(pieces[0]))}
+	for _, p := range pieces {
+		info.Length += int64(len(p))
+		sum := sha1.Sum(p)
+		info.Pieces = append(info.Pieces, sum[:]...)
+	}
+	infoBytes, err := bencode.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := torrent.NewDefaultClientConfig()
+	cfg.DataDir = t.TempDir()
+	cfg.DefaultStorage = ms
+	cfg.ListenPort = 0
+	cfg.NoDHT = true
+	cfg.DisableTCP = true
+	cfg.DisableUTP = true
+	cfg.NoDefaultPortForwarding = true
+	client, err := torrent.NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	tor, err := client.AddTorrent(&metainfo.MetaInfo{InfoBytes: infoBytes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-tor.GotInfo()
+	return tor
+}
+
+// downloadPiece stores data as piece i of tor, from off on, as if it had
+// arrived from peers. With verify, the client then hash-checks the piece;
+// without, it only rereads the piece's completion from storage, so a
+// storage that claimed a partial piece was complete would get it served.
+func downloadPiece(t *testing.T, ms *memstorage.MemoryStorage, tor *torrent.Torrent, i int, data []byte, off int64, verify bool) {
+	t.Helper()
+	p := ms.GetTorrent(tor.InfoHash()).Piece(tor.Info().Piece(i))
+	if _, err := p.WriteAt(data, off); err != nil {
+		t.Fatal(err)
+	}
+	if !verify {
+		tor.Piece(i).UpdateCompletion()
+		return
+	}
+	if err := tor.Piece(i).VerifyData(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStreamWaitsForUndownloadedData(t *testing.T) {
+	const pieceLen = 16 << 10
+	pieces := make([][]byte, 4)
+	for i := range pieces {
+		pieces[i] = testData(pieceLen)
+		pieces[i][0] = byte(i + 1)
+	}
+	ms := memstorage.NewMemory()
+	tor := newMemoryTorrent(t, ms, pieces)
+	// The start of the file is downloaded, and half of piece 2: the
+	// memory piece holds zeros for the rest.
+	downloadPiece(t, ms, tor, 0, pieces[0], 0, true)
+	downloadPiece(t, ms, tor, 1, pieces[1], 0, true)
+	downloadPiece(t, ms, tor, 2, pieces[2][:pieceLen/2], 0, false)
+	s, ts := newTestServer(t, tor.Files()[0])
+
+	type result struct {
+		status int
+		body   []byte
+		err    error
+	}
+	first := make(chan []byte, 1)
+	done := make(chan result, 1)
+	go func() {
+		req, err := http.NewRequest("GET", fmt.Sprintf("%s/stream/0?token=%s", ts.URL, s.token), nil)
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		// A seek into the partly downloaded piece.
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", 2*pieceLen))
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		head := make([]byte, 1)
+		if _, err := io.ReadFull(resp.Body, head); err != nil {
+			done <- result{err: err}
+			return
+		}
+		first <- head
+		rest, err := io.ReadAll(resp.Body)
+		done <- result{status: resp.StatusCode, body: append(head, rest...), err: err}
+	}()
+
+	select {
+	case b := <-first:
+		t.Fatalf("served byte %#x before piece 2 completed", b[0])
+	case r := <-done:
+		t.Fatalf("request ended before piece 2 completed: %v", r.err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	downloadPiece(t, ms, tor, 2, pieces[2][pieceLen/2:], pieceLen/2, true)
+	downloadPiece(t, ms, tor, 3, pieces[3], 0, true)
+	var r result
+	select {
+	case r = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request still waiting after the pieces completed")
+	}
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	if r.status != http.StatusPartialContent {
+		t.Errorf("status = %d, want 206", r.status)
+	}
+	if want := bytes.Join(pieces[2:], nil); !bytes.Equal(r.body, want) {
+		t.Errorf("body is %d bytes and differs from the piece data", len(r.body))
+	}
+}
@@ -0,0 +1,96 @@
+package stream
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// Status is the JSON document served at /status for external dashboards.
+// Name and the peer counts are the playing file's torrent's, else the
+// first file's. Rates are in bytes per second.
+type Status struct {
+	Name         string       `json:"name"`
+	CurrentFile  int          `json:"current_file"` // -1 when nothing is playing
+	Files        []FileStatus `json:"files"`
+	ActivePeers  int          `json:"active_peers"`
+	TotalPeers   int          `json:"total_peers"`
+	DownloadRate float64      `json:"download_rate"`
+	UploadRate   float64      `json:"upload_rate"`
+}
+
+// FileStatus is one streamable file in Status, at the index its stream
+// URL uses.
+type FileStatus struct {
+	Index     int     `json:"index"`
+	Path      string  `json:"path"`
+	Length    int64   `json:"length"`
+	Completed int64   `json:"completed"`
+	Progress  float64 `json:"progress"` // 0-1
+}
+
+// SetPlaybackState records what the server can't see for itself: the
+// file index being played (-1 for none) and the transfer rates, as the
+// caller samples them.
+func (s *Server) SetPlaybackState(current int, downRate, upRate float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current = current
+	s.downRate, s.upRate = downRate, upRate
+}
+
+// status assembles the current Status.
+func (s *Server) status() Status {
+	s.mu.RLock()
+	files := s.files
+	st := Status{
+		CurrentFile:  s.current,
+		Files:        make([]FileStatus, 0, len(files)),
+		DownloadRate: s.downRate,
+		UploadRate:   s.upRate,
+	}
+	s.mu.RUnlock()
+
+	// Files may come from several torrents; describe the playing one.
+	named := 0
+	if st.CurrentFile >= 0 && st.CurrentFile < len(files) {
+		named = st.CurrentFile
+	}
+	if len(files) > 0 && files[named].Torrent() != nil {
+		t := files[named].Torrent()
+		st.Name = t.Name()
+		stats := t.Stats()
+		st.ActivePeers, st.TotalPeers = stats.ActivePeers, stats.TotalPeers
+	}
+	for i, f := range files {
+		fs := FileStatus{
+			Index:     i,
+			Path:      f.DisplayPath(),
+			Length:    f.Length(),
+			Completed: f.BytesCompleted(),
+		}
+		if fs.Length > 0 {
+			fs.Progress = float64(fs.Completed) / float64(fs.Length)
+		}
+		st.Files = append(st.Files, fs)
+	}
+	return st
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		slog.Warn("status request rejected: bad or missing token", "remote", r.RemoteAddr)
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(s.status()); err != nil {
+		slog.Debug("write status", "err", err)
+	}
+}
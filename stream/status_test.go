@@ -0,0 +1,95 @@
+package stream
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestStatus(t *testing.T) {
+	s, ts := newTestServer(t,
+		&fakeFile{name: "Show/01.mkv", data: testData(1000), completed: 250},
+		&fakeFile{name: "Show/02.mkv", data: testData(500)},
+	)
+	s.SetPlaybackState(1, 2048, 512)
+
+	resp, err := ts.Client().Get(ts.URL + "/status?token=" + s.token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	// Decode loosely so a renamed or missing key fails the test.
+	var doc map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"name", "current_file", "files", "active_peers", "total_peers", "download_rate", "upload_rate"} {
+		if _, ok := doc[key]; !ok {
+			t.Errorf("missing key %q", key)
+		}
+	}
+	if doc["current_file"] != 1.0 || doc["download_rate"] != 2048.0 || doc["upload_rate"] != 512.0 {
+		t.Errorf("playback state = %v, %v, %v", doc["current_file"], doc["download_rate"], doc["upload_rate"])
+	}
+	files, ok := doc["files"].([]any)
+	if !ok || len(files) != 2 {
+		t.Fatalf("files = %v", doc["files"])
+	}
+	want := map[string]any{"index": 0.0, "path": "Show/01.mkv", "length": 1000.0, "completed": 250.0, "progress": 0.25}
+	first := files[0].(map[string]any)
+	for key, v := range want {
+		if first[key] != v {
+			t.Errorf("files[0].%s = %v, want %v", key, first[key], v)
+		}
+	}
+	if len(first) != len(want) {
+		t.Errorf("files[0] has keys %v, want only %v", first, want)
+	}
+}
+
+func TestStatusRejects(t *testing.T) {
+	s, ts := newTestServer(t)
+	tests := []struct {
+		name, method, path string
+		status             int
+	}{
+		{"missing token", "GET", "/status", http.StatusForbidden},
+		{"wrong token", "GET", "/status?token=nope", http.StatusForbidden},
+		{"POST", "POST", "/status?token=" + s.token, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, ts.URL+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
+
+func TestStatusNothingPlaying(t *testing.T) {
+	s, _ := newTestServer(t)
+	if st := s.status(); st.CurrentFile != -1 || len(st.Files) != 0 {
+		t.Errorf("fresh server status = %+v, want no current file and no files", st)
+	}
+	s.SetPlaybackState(3, 1, 1)
+	s.SetPlaybackState(-1, 0, 0)
+	if st := s.status(); st.CurrentFile != -1 || st.DownloadRate != 0 || st.UploadRate != 0 {
+		t.Errorf("status after playback ended = %+v", st)
+	}
+}
@@ -0,0 +1,44 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/anacrolix/torrent"
+
+	"github.com/enrell/just-stream/config"
+)
+
+// Upper bounds for the advanced client settings. Past these the client
+// spends more on bookkeeping than it gains, or the OS runs out of sockets.
+const (
+	maxPieceHashers    = 64
+	maxConnsPerTorrent = 1000
+)
+
+// applyAdvanced copies the advanced settings onto the client config.
+// Unset values keep the library defaults; out-of-range ones were rejected
+// by the settings screen, but a hand-edited config is clamped here too.
+func applyAdvanced(cfg *torrent.ClientConfig, c *config.Config) {
+	if n := c.PieceHashers; n > 0 {
+		cfg.PieceHashersPerTorrent = min(n, maxPieceHashers)
+	}
+	if n := c.MaxConnsPerTorrent; n > 0 {
+		cfg.EstablishedConnsPerTorrent = min(n, maxConnsPerTorrent)
+	}
+	if n := c.HalfOpenConnsPerTorrent; n > 0 {
+		cfg.HalfOpenConnsPerTorrent = min(n, maxConnsPerTorrent)
+		if cfg.TotalHalfOpenConns < cfg.HalfOpenConnsPerTorrent {
+			cfg.TotalHalfOpenConns = cfg.HalfOpenConnsPerTorrent
+		}
+	}
+	cfg.DisableAggressiveUpload = c.DisableAggressiveUpload
+}
+
+// parseBounded reads a setting between 0 (library default) and max.
+func parseBounded(v string, max int) (int, error) {
+	n, err := parseNonNegative(v)
+	if err == nil && n > max {
+		return 0, fmt.Errorf("%q is more than the maximum of %d", v, max)
+	}
+	return n, err
+}
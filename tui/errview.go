@@ -0,0 +1,82 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// errorViewLines is how many lines of an error are shown at once. Longer
+// errors (wrapped mpv stderr, chained causes) scroll with pgup/pgdown.
+const errorViewLines = 6
+
+// errorLines wraps err to the terminal width and splits it into lines.
+func (m Model) errorLines(err error) []string {
+	width := m.width - 2
+	if width < 20 {
+		width = 78
+	}
+	text := fmt.Sprintf("Error: %v", err)
+	wrapped := lipgloss.NewStyle().Width(width).Render(text)
+	lines := strings.Split(wrapped, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " ")
+	}
+	return lines
+}
+
+// viewError renders err as a height-bounded region starting at the
+// current scroll offset, with indicators for hidden lines.
+func (m Model) viewError(err error) string {
+	lines := m.errorLines(err)
+	start := clampErrScroll(m.errScroll, len(lines))
+	end := start + errorViewLines
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString(m.styles.dim.Render(fmt.Sprintf("  ↑ %d more", start)))
+		b.WriteString("\n")
+	}
+	for _, l := range lines[start:end] {
+		b.WriteString(m.styles.error.Render(l))
+		b.WriteString("\n")
+	}
+	if end < len(lines) {
+		b.WriteString(m.styles.dim.Render(fmt.Sprintf("  ↓ %d more (pgup/pgdown to scroll)", len(lines)-end)))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// scrollError handles error scrolling keys, reporting whether msg was one.
+func (m *Model) scrollError(msg tea.KeyMsg) bool {
+	if m.err == nil {
+		return false
+	}
+	n := len(m.errorLines(m.err))
+	switch msg.String() {
+	case "pgdown", "ctrl+d":
+		m.errScroll = clampErrScroll(m.errScroll+errorViewLines/2, n)
+	case "pgup", "ctrl+u":
+		m.errScroll = clampErrScroll(m.errScroll-errorViewLines/2, n)
+	default:
+		return false
+	}
+	return true
+}
+
+// clampErrScroll keeps the scroll offset inside an error of n lines.
+func clampErrScroll(scroll, n int) int {
+	if maxScroll := n - errorViewLines; scroll > maxScroll {
+		scroll = maxScroll
+	}
+	if scroll < 0 {
+		scroll = 0
+	}
+	return scroll
+}
@@ -0,0 +1,99 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/anacrolix/torrent"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// The file list can be narrowed with /: typing filters files by a
+// case-insensitive substring of their path. The filtered list replaces
+// m.files, so enter and a act on the matches only.
+
+// openFilter focuses the filter box on the file list.
+func (m Model) openFilter() (tea.Model, tea.Cmd) {
+	m.filtering = true
+	m.filterInput.SetValue(m.filter)
+	m.filterInput.CursorEnd()
+	m.filterInput.Focus()
+	return m, textinput.Blink
+}
+
+// updateFilter handles keys while the filter box is focused. Navigation
+// and enter still work on the narrowed list.
+func (m Model) updateFilter(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.filtering = false
+		m.filterInput.Blur()
+		m.setFilter("")
+		return m, nil
+	case "enter":
+		m.filtering = false
+		m.filterInput.Blur()
+		if len(m.files) == 0 {
+			return m, nil
+		}
+		m.err = nil
+		return m.playSelected()
+	case "down":
+		if m.cursor < len(m.files)-1 {
+			m.cursor++
+		}
+		return m, nil
+	case "up":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.filterInput, cmd = m.filterInput.Update(msg)
+	m.setFilter(m.filterInput.Value())
+	return m, cmd
+}
+
+// setFilter applies a new filter string and rebuilds the list.
+func (m *Model) setFilter(filter string) {
+	if filter == m.filter {
+		return
+	}
+	m.filter = filter
+	m.rebuildFileList()
+}
+
+// filterFiles keeps the files whose path contains filter, ignoring case.
+func filterFiles(files []*torrent.File, filter string) []*torrent.File {
+	filter = strings.ToLower(strings.TrimSpace(filter))
+	if filter == "" {
+		return files
+	}
+	var matched []*torrent.File
+	for _, f := range files {
+		if strings.Contains(strings.ToLower(f.DisplayPath()), filter) {
+			matched = append(matched, f)
+		}
+	}
+	return matched
+}
+
+// viewFilter renders the filter line above the file list.
+func (m Model) viewFilter() string {
+	if !m.filtering && m.filter == "" {
+		return ""
+	}
+	var b strings.Builder
+	if m.filtering {
+		b.WriteString("  / ")
+		b.WriteString(m.filterInput.View())
+	} else {
+		b.WriteString(m.styles.dim.Render(fmt.Sprintf("  filter: %s", m.filter)))
+	}
+	b.WriteString("\n")
+	b.WriteString(m.styles.dim.Render(fmt.Sprintf("  %d of %d files match", len(m.files), m.listedTotal)))
+	b.WriteString("\n\n")
+	return b.String()
+}
@@ -0,0 +1,132 @@
+package tui
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/enrell/just-stream/config"
+)
+
+// Torrents whose metadata was fetched are remembered in a history file
+// next to the config. The input screen opens the list with ctrl+r, and
+// picking an entry submits its magnet again.
+
+type historyLoadedMsg struct {
+	entries []config.HistoryEntry
+	err     error
+}
+
+func cmdLoadHistory() tea.Cmd {
+	return func() tea.Msg {
+		entries, err := config.LoadHistory()
+		return historyLoadedMsg{entries: entries, err: err}
+	}
+}
+
+// cmdRecordHistory adds a successfully opened torrent to the history.
+// Failures are not worth interrupting playback for, so they are only
+// logged.
+func (m Model) cmdRecordHistory(uri string, t *torrent.Torrent) tea.Cmd {
+	if IsTorrentFile(uri) {
+		// Keep working no matter which directory the next run starts in.
+		if abs, err := filepath.Abs(uri); err == nil {
+			uri = abs
+		}
+	}
+	entry := config.HistoryEntry{
+		URI:      uri,
+		Name:     t.Name(),
+		InfoHash: t.InfoHash().HexString(),
+		At:       time.Now(),
+	}
+	limit := m.cfg.HistorySize
+	return func() tea.Msg {
+		if err := config.AddHistory(entry, limit); err != nil {
+			slog.Warn("record history", "infohash", entry.InfoHash, "err", err)
+		}
+		return nil
+	}
+}
+
+// openHistory shows the history screen.
+func (m Model) openHistory() (tea.Model, tea.Cmd) {
+	m.screen = screenHistory
+	m.history = nil
+	m.historyErr = nil
+	m.historyCursor = 0
+	m.textInput.Blur()
+	return m, cmdLoadHistory()
+}
+
+func (m Model) updateHistory(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case historyLoadedMsg:
+		m.history = msg.entries
+		m.historyErr = msg.err
+		m.historyCursor = clampCursor(m.historyCursor, len(m.history))
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "j", "down":
+			if m.historyCursor < len(m.history)-1 {
+				m.historyCursor++
+			}
+		case "k", "up":
+			if m.historyCursor > 0 {
+				m.historyCursor--
+			}
+		case "enter":
+			if m.historyCursor >= len(m.history) {
+				return m, nil
+			}
+			uri := m.history[m.historyCursor].URI
+			m.screen = screenInput
+			return m, func() tea.Msg { return submitMagnetMsg{uri: uri} }
+		case "esc", "q":
+			m.screen = screenInput
+			m.textInput.Focus()
+			return m, textinput.Blink
+		}
+	}
+	return m, nil
+}
+
+func (m Model) viewHistory() string {
+	var b strings.Builder
+	b.WriteString(m.styles.title.Render("just-stream"))
+	b.WriteString(" ")
+	b.WriteString(m.styles.dim.Render("history"))
+	b.WriteString("\n\n")
+
+	switch {
+	case m.historyErr != nil:
+		b.WriteString(m.styles.error.Render(fmt.Sprintf("  Error: %v", m.historyErr)))
+		b.WriteString("\n\n")
+	case len(m.history) == 0:
+		b.WriteString(m.styles.dim.Render("  No torrents opened yet."))
+		b.WriteString("\n\n")
+	}
+
+	for i, e := range m.history {
+		name := cmp.Or(e.Name, parseMagnetDisplayName(e.URI), e.InfoHash)
+		when := m.styles.dim.Render("  " + e.At.Local().Format("2006-01-02 15:04"))
+		if i == m.historyCursor {
+			b.WriteString(m.styles.selected.Render(fmt.Sprintf("  > %s", name)))
+		} else {
+			b.WriteString(m.styles.normal.Render(fmt.Sprintf("    %s", name)))
+		}
+		b.WriteString(when)
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(m.styles.help.Render("j/k: navigate  enter: open  esc: back  ctrl+c: quit"))
+	return b.String()
+}
@@ -0,0 +1,130 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/enrell/just-stream/config"
+)
+
+// Remappable actions; the names are the keys of config.Keybindings.
+const (
+	actionDown      = "down"
+	actionUp        = "up"
+	actionTop       = "top"
+	actionBottom    = "bottom"
+	actionPlay      = "play"
+	actionStreamAll = "stream_all"
+	actionQuit      = "quit"
+	actionConfig    = "config"
+)
+
+// defaultKeys binds every action in config.KeyActions.
+var defaultKeys = map[string]string{
+	actionDown:      "j",
+	actionUp:        "k",
+	actionTop:       "g",
+	actionBottom:    "G",
+	actionPlay:      "enter",
+	actionStreamAll: "a",
+	actionQuit:      "q",
+	actionConfig:    "ctrl+s",
+}
+
+// keymap is the active action-to-key bindings.
+type keymap struct {
+	keys  map[string]string // action -> key
+	byKey map[string]string // key -> action
+}
+
+// newKeymap layers bindings over the defaults. Invalid bindings (see
+// config.CheckKeybindings) are ignored as a whole, since one bad entry can
+// leave an action unreachable.
+func newKeymap(bindings map[string]string) keymap {
+	k := keymap{keys: map[string]string{}, byKey: map[string]string{}}
+	for action, key := range defaultKeys {
+		k.keys[action] = key
+	}
+	if config.CheckKeybindings(bindings) == nil {
+		for action, key := range bindings {
+			k.keys[action] = key
+		}
+	}
+	for action, key := range k.keys {
+		if _, taken := k.byKey[key]; taken {
+			// A custom key shadowing another action's default: the
+			// custom binding wins.
+			if bindings[action] == "" {
+				continue
+			}
+		}
+		k.byKey[key] = action
+	}
+	return k
+}
+
+// keys returns the active bindings, built when the model is created and
+// whenever settings are applied.
+func (m Model) keys() keymap {
+	return m.keymap
+}
+
+// canonical translates a key press into the default key of the action it
+// is bound to, so the screens can keep switching on the default keys. A
+// default key whose action was moved elsewhere does nothing; other keys
+// pass through. Only the given actions are considered, so a key bound to
+// a file list action keeps its meaning on the playback screen.
+func (k keymap) canonical(key string, actions ...string) string {
+	for _, action := range actions {
+		if k.byKey[key] == action {
+			return defaultKeys[action]
+		}
+	}
+	for _, action := range actions {
+		if defaultKeys[action] == key {
+			return ""
+		}
+	}
+	return key
+}
+
+// key returns the key bound to action, or "" if another action's custom
+// binding took it.
+func (k keymap) key(action string) string {
+	if key := k.keys[action]; k.byKey[key] == action {
+		return key
+	}
+	return ""
+}
+
+// help returns a help line entry such as "j/k: navigate" for the keys
+// bound to actions, or "" if none are bound.
+func (k keymap) help(label string, actions ...string) string {
+	var keys []string
+	for _, action := range actions {
+		if key := k.key(action); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	return strings.Join(keys, "/") + ": " + label
+}
+
+// helpLine joins help entries with two spaces, skipping empty ones.
+func helpLine(entries ...string) string {
+	var parts []string
+	for _, e := range entries {
+		if e != "" {
+			parts = append(parts, e)
+		}
+	}
+	return strings.Join(parts, "  ")
+}
+
+// fileActions and playingActions are the remappable actions of the file
+// list and playback screens.
+var (
+	fileActions    = []string{actionDown, actionUp, actionTop, actionBottom, actionPlay, actionStreamAll, actionQuit}
+	playingActions = []string{actionQuit}
+)
@@ -0,0 +1,57 @@
+package tui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/anacrolix/torrent"
+
+	memstorage "github.com/enrell/just-stream/storage"
+)
+
+// ListedFile is one media file as printed by ListFiles.
+type ListedFile struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+	Size  int64  `json:"size"`
+}
+
+// ListFiles fetches the metadata of opts.Magnet with the same client
+// settings the TUI uses, then writes its media files, in file list order,
+// to w: one tab-separated "index name size" line each, or a JSON array
+// with asJSON. Nothing beyond the metadata is downloaded.
+func ListFiles(ctx context.Context, opts Options, w io.Writer, asJSON bool) error {
+	if opts.Storage == nil && opts.MemStore == nil {
+		opts.MemStore = memstorage.NewMemory()
+	}
+	m := NewModel(opts)
+	m.magnetURI = opts.Magnet
+	client, t, _, err := fetchMetadata(ctx, m.fetchSetup(), m.shared, nil)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	// Copy so sorting never reorders the torrent's own slice.
+	all := append([]*torrent.File(nil), t.Files()...)
+	media := filterMediaFiles(all, mediaExtensionSet(m.cfg))
+	sortFilesByName(media)
+
+	listed := make([]ListedFile, len(media))
+	for i, f := range media {
+		listed[i] = ListedFile{Index: i, Name: f.DisplayPath(), Size: f.Length()}
+	}
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(listed)
+	}
+	for _, f := range listed {
+		if _, err := fmt.Fprintf(w, "%d\t%s\t%s\n", f.Index, f.Name, humanSize(f.Size)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// peerRow is one connected peer as shown on the peers screen.
+type peerRow struct {
+	addr     string
+	client   string
+	down, up float64 // bytes/s
+	have     int     // pieces the peer has
+}
+
+// openPeers switches from the playback screen to the peer list.
+func (m Model) openPeers() (tea.Model, tea.Cmd) {
+	m.screen = screenPeers
+	m.peerScroll = 0
+	m.refreshPeers()
+	return m, nil
+}
+
+// refreshPeers snapshots the playing torrent's peer connections, fastest first.
+// The library does not expose choke state, so the piece count stands in
+// as the hint for whether a peer can help at all.
+func (m *Model) refreshPeers() {
+	m.peers = nil
+	t := m.currentTorrent()
+	if t == nil {
+		return
+	}
+	for _, pc := range t.PeerConns() {
+		stats := pc.Stats()
+		client, _ := pc.PeerClientName.Load().(string)
+		m.peers = append(m.peers, peerRow{
+			addr:   pc.RemoteAddr.String(),
+			client: client,
+			down:   stats.DownloadRate,
+			up:     stats.LastWriteUploadRate,
+			have:   stats.RemotePieceCount,
+		})
+	}
+	sort.SliceStable(m.peers, func(i, j int) bool {
+		return m.peers[i].down > m.peers[j].down
+	})
+	m.peerScroll = clampCursor(m.peerScroll, len(m.peers))
+}
+
+// updatePeers handles the peer list. Everything but keys goes to
+// updatePlaying so mpv events keep flowing while the list is open.
+func (m Model) updatePeers(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		next, cmd := m.updatePlaying(msg)
+		nm := next.(Model)
+		if _, tick := msg.(tickMsg); tick && nm.screen == screenPeers {
+			nm.refreshPeers()
+		}
+		return nm, cmd
+	}
+	switch m.keys().canonical(key.String(), playingActions...) {
+	case "j", "down":
+		if m.peerScroll < len(m.peers)-1 {
+			m.peerScroll++
+		}
+	case "k", "up":
+		if m.peerScroll > 0 {
+			m.peerScroll--
+		}
+	case "p", "esc":
+		m.screen = screenPlaying
+	case "q":
+		return m.updatePlaying(msg)
+	}
+	return m, nil
+}
+
+func (m Model) viewPeers() string {
+	var b strings.Builder
+	b.WriteString(m.styles.title.Render("just-stream"))
+	b.WriteString(" ")
+	b.WriteString(m.styles.dim.Render("peers"))
+	b.WriteString("\n\n")
+
+	pieces := 0
+	if t := m.currentTorrent(); t != nil && t.Info() != nil {
+		pieces = t.NumPieces()
+	}
+	b.WriteString(m.styles.status.Render(fmt.Sprintf("  %d connected", len(m.peers))))
+	b.WriteString("\n\n")
+	b.WriteString(m.styles.dim.Render(fmt.Sprintf("  %-28s %-20s %10s %10s %6s", "Address", "Client", "Down", "Up", "Has")))
+	b.WriteString("\n")
+
+	visible := m.height - 10
+	if visible < 5 {
+		visible = 20
+	}
+
+	startIdx := 0
+	if m.peerScroll >= visible {
+		startIdx = m.peerScroll - visible + 1
+	}
+	endIdx := startIdx + visible
+	if endIdx > len(m.peers) {
+		endIdx = len(m.peers)
+	}
+
+	for i := startIdx; i < endIdx; i++ {
+		p := m.peers[i]
+		has := "?"
+		if pieces > 0 {
+			has = fmt.Sprintf("%.0f%%", float64(p.have)*100/float64(pieces))
+		}
+		line := fmt.Sprintf("%-28s %-20s %8s/s %8s/s %6s",
+			truncate(p.addr, 28), truncate(p.client, 20),
+			humanSize(int64(p.down)), humanSize(int64(p.up)), has)
+		if i == m.peerScroll {
+			b.WriteString(m.styles.selected.Render("  > " + line))
+		} else {
+			b.WriteString(m.styles.normal.Render("    " + line))
+		}
+		b.WriteString("\n")
+	}
+
+	if startIdx > 0 {
+		b.WriteString(m.styles.dim.Render(fmt.Sprintf("    ... %d more above", startIdx)))
+		b.WriteString("\n")
+	}
+	if endIdx < len(m.peers) {
+		b.WriteString(m.styles.dim.Render(fmt.Sprintf("    ... %d more below", len(m.peers)-endIdx)))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(m.styles.help.Render(helpLine("j/k: scroll  p/esc: back to playback", m.keys().help("quit", actionQuit))))
+	return b.String()
+}
+
+// truncate shortens s to at most n runes, marking the cut with "…".
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
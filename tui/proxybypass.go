@@ -0,0 +1,103 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// proxyBypass matches hosts that skip the proxy, NO_PROXY style. An empty
+// list matches nothing, so everything is proxied.
+type proxyBypass struct {
+	all      bool
+	hosts    []string // exact names
+	suffixes []string // ".example.com" from ".example.com" or "*.example.com"
+	nets     []*net.IPNet
+}
+
+func parseProxyBypass(list []string) (proxyBypass, error) {
+	var b proxyBypass
+	for _, entry := range list {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+		case entry == "*":
+			b.all = true
+		case strings.Contains(entry, "/"):
+			_, n, err := net.ParseCIDR(entry)
+			if err != nil {
+				return proxyBypass{}, fmt.Errorf("proxy bypass %q: %w", entry, err)
+			}
+			b.nets = append(b.nets, n)
+		case strings.HasPrefix(entry, "*."):
+			b.suffixes = append(b.suffixes, entry[1:])
+		case strings.HasPrefix(entry, "."):
+			b.suffixes = append(b.suffixes, entry)
+		default:
+			if ip := net.ParseIP(entry); ip != nil {
+				b.nets = append(b.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+				continue
+			}
+			b.hosts = append(b.hosts, entry)
+		}
+	}
+	return b, nil
+}
+
+// match reports whether host (without port) bypasses the proxy. A
+// ".example.com" suffix also matches example.com itself.
+func (b proxyBypass) match(host string) bool {
+	if b.all {
+		return true
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if ip := net.ParseIP(host); ip != nil {
+		for _, n := range b.nets {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, h := range b.hosts {
+		if host == h {
+			return true
+		}
+	}
+	for _, s := range b.suffixes {
+		if strings.HasSuffix(host, s) || host == s[1:] {
+			return true
+		}
+	}
+	return false
+}
+
+// proxyFunc returns an http.Transport Proxy func that sends requests to
+// proxy unless the target host bypasses it.
+func (b proxyBypass) proxyFunc(proxy *url.URL) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		if b.match(req.URL.Hostname()) {
+			return nil, nil
+		}
+		return proxy, nil
+	}
+}
+
+// dialContext wraps a proxied dial func so bypassed hosts are dialed
+// directly.
+func (b proxyBypass) dialContext(proxied func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	var direct net.Dialer
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		if b.match(host) {
+			return direct.DialContext(ctx, network, addr)
+		}
+		return proxied(ctx, network, addr)
+	}
+}
@@ -0,0 +1,246 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Torrents can be added to the session from the playback screen while
+// something is already playing, or by passing several magnets on the
+// command line. They join the running client and fetch their metadata in
+// the background; once it arrives their files are listed after those of
+// the torrents already in the session, under their own heading, and the
+// start of their first file is pre-buffered. Streaming all runs on from
+// one torrent's files into the next. Command-line magnets are added one
+// at a time, each once the one before has its metadata or has failed, so
+// they don't all compete for the swarm at startup.
+
+// The queue messages carry fromArgs for torrents added from the command
+// line, whose outcome starts the next one.
+type (
+	queueAddedMsg struct {
+		t        *torrent.Torrent
+		fromArgs bool
+	}
+	queueReadyMsg struct {
+		t        *torrent.Torrent
+		fromArgs bool
+	}
+	queueErrMsg struct {
+		t        *torrent.Torrent // nil if it never joined the client
+		err      error
+		fromArgs bool
+	}
+)
+
+// openQueueInput shows the magnet overlay on the playback screen.
+func (m Model) openQueueInput() (tea.Model, tea.Cmd) {
+	m.queueing = true
+	m.queueInput.SetValue("")
+	m.queueInput.Focus()
+	return m, textinput.Blink
+}
+
+// updateQueueInput handles keys while the magnet overlay is open.
+func (m Model) updateQueueInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		uri := strings.TrimSpace(m.queueInput.Value())
+		m.queueing = false
+		m.queueInput.Blur()
+		if uri == "" {
+			return m, nil
+		}
+		return m, m.cmdQueueMagnet(uri, false)
+	case "esc":
+		m.queueing = false
+		m.queueInput.Blur()
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.queueInput, cmd = m.queueInput.Update(msg)
+	return m, cmd
+}
+
+// updateQueue handles queue progress messages on any screen.
+func (m Model) updateQueue(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case queueAddedMsg:
+		if slices.Contains(m.torrents, msg.t) || slices.Contains(m.queue, msg.t) {
+			// The client hands back the torrent it already has.
+			m.queueErr = fmt.Errorf("%s is already in this session", torrentLabel(msg.t))
+			if msg.fromArgs {
+				return m, m.queueNextArg()
+			}
+			return m, nil
+		}
+		m.queueErr = nil
+		m.queue = append(m.queue, msg.t)
+		return m, cmdAwaitQueuedInfo(msg.t, metadataTimeout(m.cfg), msg.fromArgs)
+	case queueReadyMsg:
+		if i := slices.Index(m.queue, msg.t); i >= 0 {
+			m.queue = slices.Delete(m.queue, i, i+1)
+			m.joinSession(msg.t)
+		}
+		if msg.fromArgs {
+			return m, m.queueNextArg()
+		}
+	case queueErrMsg:
+		m.queueErr = msg.err
+		if msg.t != nil {
+			m.queue = slices.DeleteFunc(m.queue, func(t *torrent.Torrent) bool { return t == msg.t })
+		}
+		if msg.fromArgs {
+			// One dead magnet must not hold up the rest.
+			return m, m.queueNextArg()
+		}
+	}
+	return m, nil
+}
+
+// joinSession lists t's files after those already in the session and
+// pre-buffers the start of its first one. The list is extended, not
+// rebuilt, so the files before keep their positions even when sorted by
+// progress, and a playlist in progress is not disturbed.
+func (m *Model) joinSession(t *torrent.Torrent) {
+	m.torrents = append(m.torrents, t)
+	m.appendTorrentFiles(t)
+	m.refreshFileProgress()
+	prebufferFirstFile(t, mediaExtensionSet(m.cfg))
+}
+
+// queueNextArg queues the next command-line magnet still waiting, if any.
+func (m *Model) queueNextArg() tea.Cmd {
+	if len(m.pendingQueue) == 0 {
+		return nil
+	}
+	uri := m.pendingQueue[0]
+	m.pendingQueue = m.pendingQueue[1:]
+	return m.cmdQueueMagnet(uri, true)
+}
+
+func (m Model) cmdQueueMagnet(uri string, fromArgs bool) tea.Cmd {
+	sh := m.shared
+	trackers := extraTrackerTiers(m.cfg)
+	return func() tea.Msg {
+		sh.mu.Lock()
+		client := sh.client
+		sh.mu.Unlock()
+		if client == nil {
+			return queueErrMsg{fromArgs: fromArgs, err: fmt.Errorf("no active torrent session")}
+		}
+		var t *torrent.Torrent
+		if IsTorrentFile(uri) {
+			mi, err := metainfo.LoadFromFile(uri)
+			if err != nil {
+				return queueErrMsg{fromArgs: fromArgs, err: fmt.Errorf("%s is not a valid .torrent file: %w", filepath.Base(uri), err)}
+			}
+			if t, err = client.AddTorrent(mi); err != nil {
+				return queueErrMsg{fromArgs: fromArgs, err: fmt.Errorf("add torrent: %w", err)}
+			}
+		} else {
+			var err error
+			if t, err = client.AddMagnet(uri); err != nil {
+				return queueErrMsg{fromArgs: fromArgs, err: fmt.Errorf("add magnet: %w", err)}
+			}
+		}
+		if len(trackers) > 0 {
+			t.AddTrackers(trackers)
+		}
+		return queueAddedMsg{t: t, fromArgs: fromArgs}
+	}
+}
+
+// cmdAwaitQueuedInfo waits for a queued torrent's metadata, dropping it
+// if none arrives within timeout (0 waits forever).
+func cmdAwaitQueuedInfo(t *torrent.Torrent, timeout time.Duration, fromArgs bool) tea.Cmd {
+	return func() tea.Msg {
+		var timedOut <-chan time.Time
+		if timeout > 0 {
+			timer := time.NewTimer(timeout)
+			defer timer.Stop()
+			timedOut = timer.C
+		}
+		select {
+		case <-t.GotInfo():
+			return queueReadyMsg{t: t, fromArgs: fromArgs}
+		case <-timedOut:
+			t.Drop()
+			return queueErrMsg{t: t, fromArgs: fromArgs, err: fmt.Errorf("%s: no metadata after %s; the torrent may have no reachable peers", t.InfoHash().HexString(), timeout)}
+		case <-t.Closed():
+			return queueErrMsg{t: t, fromArgs: fromArgs, err: fmt.Errorf("%s: dropped before its metadata arrived", t.InfoHash().HexString())}
+		}
+	}
+}
+
+// prebufferFirstFile downloads the opening pieces of a queued torrent's
+// first media file so switching to it starts quickly. The rest waits
+// until it is played, to keep RAM use bounded.
+func prebufferFirstFile(t *torrent.Torrent, exts map[string]bool) {
+	files := filterMediaFiles(t.Files(), exts)
+	if len(files) == 0 {
+		return
+	}
+	sortFilesByName(files)
+	first, boost := startupBoost(files[0], false, 0)
+	t.DownloadPieces(first, boost)
+}
+
+// cursorToNextTorrent moves the cursor to the first listed file of the
+// next torrent in the session, wrapping around to the first.
+func (m *Model) cursorToNextTorrent() {
+	if m.cursor >= len(m.files) {
+		return
+	}
+	current := m.files[m.cursor].Torrent()
+	for i := m.cursor + 1; i < len(m.files); i++ {
+		if m.files[i].Torrent() != current {
+			m.cursor = i
+			return
+		}
+	}
+	if m.files[0].Torrent() != current {
+		m.cursor = 0
+	}
+}
+
+// torrentLabel names t in messages: its name once known, else its info
+// hash.
+func torrentLabel(t *torrent.Torrent) string {
+	if name := t.Name(); name != "" {
+		return name
+	}
+	return t.InfoHash().HexString()
+}
+
+// viewQueue renders the torrents still joining and, when open, the
+// magnet overlay.
+func (m Model) viewQueue() string {
+	var b strings.Builder
+	for _, t := range m.queue {
+		b.WriteString(m.styles.dim.Render(fmt.Sprintf("  Queued:   %s (fetching metadata...)", torrentLabel(t))))
+		b.WriteString("\n")
+	}
+	if m.queueErr != nil {
+		b.WriteString(m.styles.error.Render(fmt.Sprintf("  Queue error: %v", m.queueErr)))
+		b.WriteString("\n")
+	}
+	if m.queueing {
+		b.WriteString("\n")
+		b.WriteString(m.styles.normal.Render("  Queue a magnet link:"))
+		b.WriteString("\n  ")
+		b.WriteString(m.queueInput.View())
+		b.WriteString("\n")
+		b.WriteString(m.styles.help.Render("  enter: queue  esc: cancel"))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,833 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/enrell/just-stream/config"
+	"github.com/enrell/just-stream/player"
+	memstorage "github.com/enrell/just-stream/storage"
+	"github.com/enrell/just-stream/stream"
+)
+
+// settingField is one editable entry on the config screen.
+type settingField struct {
+	key         string // config JSON key
+	section     string // heading shown above the first field of a section
+	label       string
+	placeholder string
+	multiline   bool // edited in a textarea, one value per line
+	get         func(c *config.Config) string
+	// set parses and stores value; an error keeps the screen open.
+	set func(c *config.Config, value string) error
+	// test, when set, checks the entered value on ctrl+t and describes
+	// the result.
+	test func(value string) (string, error)
+}
+
+// advancedSection groups the torrent client tuning knobs at the end of
+// the form.
+const advancedSection = "Advanced (torrent client, applies to the next torrent)"
+
+var settingFields = []settingField{
+	{
+		key:         "mpv_path",
+		label:       "mpv path (leave empty for auto-detect, ctrl+t to test)",
+		placeholder: "/usr/bin/mpv",
+		get:         func(c *config.Config) string { return c.MpvPath },
+		set: func(c *config.Config, v string) error {
+			c.MpvPath = v
+			return nil
+		},
+		test: player.MPVVersion,
+	},
+	{
+		key:         "mpv_args",
+		label:       "extra mpv arguments (space or comma separated)",
+		placeholder: "--hwdec=auto --cache=yes",
+		get:         func(c *config.Config) string { return strings.Join(c.MpvArgs, " ") },
+		set: func(c *config.Config, v string) error {
+			c.MpvArgs = splitArgs(v)
+			return nil
+		},
+	},
+	{
+		key:         "mpv_cache",
+		label:       "mpv cache: yes or no",
+		placeholder: "yes",
+		get:         func(c *config.Config) string { return formatBool(c.MpvCacheEnabled()) },
+		set: func(c *config.Config, v string) error {
+			if v == "" {
+				c.MpvCache = nil
+				return nil
+			}
+			on, err := parseBool(v)
+			c.MpvCache = &on
+			return err
+		},
+	},
+	{
+		key:         "mpv_cache_secs",
+		label:       fmt.Sprintf("mpv cache seconds (0 = %d)", config.DefaultMpvCacheSecs),
+		placeholder: "0",
+		get:         func(c *config.Config) string { return formatInt(c.MpvCacheSecs) },
+		set: func(c *config.Config, v string) (err error) {
+			c.MpvCacheSecs, err = parseNonNegative(v)
+			return err
+		},
+	},
+	{
+		key:         "mpv_demuxer_max_mb",
+		label:       fmt.Sprintf("mpv demuxer cache in MB (0 = %d)", config.DefaultMpvDemuxerMaxMB),
+		placeholder: "0",
+		get:         func(c *config.Config) string { return formatInt(c.MpvDemuxerMaxMB) },
+		set: func(c *config.Config, v string) (err error) {
+			c.MpvDemuxerMaxMB, err = parseNonNegative(v)
+			return err
+		},
+	},
+	{
+		key:         "player",
+		label:       "player: mpv, vlc or custom",
+		placeholder: player.NameMPV,
+		get:         func(c *config.Config) string { return c.Player },
+		set: func(c *config.Config, v string) error {
+			v = strings.ToLower(v)
+			if !player.ValidName(v) {
+				return fmt.Errorf("unknown player %q (want mpv, vlc or custom)", v)
+			}
+			c.Player = v
+			return nil
+		},
+	},
+	{
+		key:         "player_path",
+		label:       "player path (vlc binary, or custom command with {url})",
+		placeholder: "iina {url}",
+		get:         func(c *config.Config) string { return c.PlayerPath },
+		set: func(c *config.Config, v string) error {
+			c.PlayerPath = v
+			return nil
+		},
+	},
+	{
+		key:         "stream_listen_addr",
+		label:       "stream listen address (empty = localhost only, 0.0.0.0:0 for LAN, unix:/path)",
+		placeholder: stream.DefaultListenAddr,
+		get:         func(c *config.Config) string { return c.StreamListenAddr },
+		set: func(c *config.Config, v string) error {
+			if v != "" {
+				if err := stream.CheckListenAddr(v); err != nil {
+					return fmt.Errorf("stream listen address %q: %w", v, err)
+				}
+			}
+			c.StreamListenAddr = v
+			return nil
+		},
+	},
+	{
+		key:         "dlna_headers",
+		label:       "send DLNA headers for smart TVs: yes or no",
+		placeholder: "no",
+		get:         func(c *config.Config) string { return formatBool(c.DLNAHeaders) },
+		set: func(c *config.Config, v string) (err error) {
+			c.DLNAHeaders, err = parseBool(v)
+			return err
+		},
+	},
+	{
+		key:         "proxy_bypass",
+		label:       "trackers that bypass the proxy (e.g. localhost, *.lan, 10.0.0.0/8)",
+		placeholder: "none",
+		get:         func(c *config.Config) string { return strings.Join(c.ProxyBypass, ", ") },
+		set: func(c *config.Config, v string) error {
+			var list []string
+			for _, s := range strings.Split(v, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					list = append(list, s)
+				}
+			}
+			if _, err := parseProxyBypass(list); err != nil {
+				return err
+			}
+			c.ProxyBypass = list
+			return nil
+		},
+	},
+	{
+		key:         "proxy_udp",
+		label:       "relay DHT through a SOCKS5 proxy that supports UDP: yes or no",
+		placeholder: "no",
+		get:         func(c *config.Config) string { return formatBool(c.ProxyUDP) },
+		set: func(c *config.Config, v string) (err error) {
+			c.ProxyUDP, err = parseBool(v)
+			return err
+		},
+	},
+	{
+		key:         "preferred_sub_lang",
+		label:       "preferred subtitle language (e.g. eng or eng,en)",
+		placeholder: "eng",
+		get:         func(c *config.Config) string { return c.PreferredSubLang },
+		set: func(c *config.Config, v string) error {
+			c.PreferredSubLang = v
+			return nil
+		},
+	},
+	{
+		key:         "preferred_audio_lang",
+		label:       "preferred audio language (e.g. jpn or jpn,ja)",
+		placeholder: "jpn",
+		get:         func(c *config.Config) string { return c.PreferredAudioLang },
+		set: func(c *config.Config, v string) error {
+			c.PreferredAudioLang = v
+			return nil
+		},
+	},
+	{
+		key:         "storage",
+		label:       "storage: memory or disk (applies on next start)",
+		placeholder: config.StorageMemory,
+		get:         func(c *config.Config) string { return c.Storage },
+		set: func(c *config.Config, v string) error {
+			v = strings.ToLower(v)
+			if !config.ValidStorage(v) {
+				return fmt.Errorf("unknown storage %q (want memory or disk)", v)
+			}
+			c.Storage = v
+			return nil
+		},
+	},
+	{
+		key:         "storage_dir",
+		label:       "disk storage directory (leave empty for default)",
+		placeholder: memstorage.DefaultDiskDir(),
+		get:         func(c *config.Config) string { return c.StorageDir },
+		set: func(c *config.Config, v string) error {
+			c.StorageDir = v
+			return nil
+		},
+	},
+	{
+		key:         "keep_downloads",
+		label:       "keep disk storage downloads after exit: yes or no",
+		placeholder: "no",
+		get:         func(c *config.Config) string { return formatBool(c.KeepDownloads) },
+		set: func(c *config.Config, v string) (err error) {
+			c.KeepDownloads, err = parseBool(v)
+			return err
+		},
+	},
+	{
+		key:         "download_dir",
+		label:       "download directory for kept downloads (leave empty for default)",
+		placeholder: memstorage.DefaultDownloadDir(),
+		get:         func(c *config.Config) string { return c.DownloadDir },
+		set: func(c *config.Config, v string) error {
+			c.DownloadDir = v
+			return nil
+		},
+	},
+	{
+		key:         "anime4k_mode",
+		label:       "Anime4K mode: A, B, C or off",
+		placeholder: "off",
+		get:         func(c *config.Config) string { return c.Anime4KMode },
+		set: func(c *config.Config, v string) error {
+			if !player.ValidAnime4KMode(v) {
+				return fmt.Errorf("unknown Anime4K mode %q (want A, B, C or off)", v)
+			}
+			c.Anime4KMode = strings.ToUpper(v)
+			if c.Anime4KMode == "OFF" {
+				c.Anime4KMode = ""
+			}
+			return nil
+		},
+	},
+	{
+		key:         "anime4k_shaders",
+		label:       "Anime4K shader directory (leave empty for mpv's)",
+		placeholder: player.DefaultShaderDir(),
+		get:         func(c *config.Config) string { return c.Anime4KShaders },
+		set: func(c *config.Config, v string) error {
+			c.Anime4KShaders = v
+			return nil
+		},
+	},
+	{
+		key:         "ipc_dir",
+		label:       "mpv IPC socket directory (Unix; empty = system temp dir)",
+		placeholder: os.TempDir(),
+		get:         func(c *config.Config) string { return c.IPCDir },
+		set: func(c *config.Config, v string) error {
+			if err := player.CheckIPCDir(v); err != nil {
+				return err
+			}
+			c.IPCDir = v
+			return nil
+		},
+	},
+	{
+		key:         "screenshot_dir",
+		label:       "screenshot directory, for i during playback (empty = mpv's)",
+		placeholder: "~/Pictures",
+		get:         func(c *config.Config) string { return c.ScreenshotDir },
+		set: func(c *config.Config, v string) error {
+			c.ScreenshotDir = v
+			return nil
+		},
+	},
+	{
+		key:         "metadata_timeout_secs",
+		label:       "metadata timeout in seconds (0 = 60s, -1 = wait forever)",
+		placeholder: "0",
+		get:         func(c *config.Config) string { return formatInt(c.MetadataTimeoutSecs) },
+		set: func(c *config.Config, v string) (err error) {
+			c.MetadataTimeoutSecs, err = parseInt(v)
+			return err
+		},
+	},
+	{
+		key:         "max_upload_rate",
+		label:       "max upload rate in bytes/s (0 = unlimited)",
+		placeholder: "0",
+		get:         func(c *config.Config) string { return formatInt(int(c.MaxUploadRate)) },
+		set: func(c *config.Config, v string) error {
+			n, err := parseInt(v)
+			c.MaxUploadRate = int64(n)
+			return err
+		},
+	},
+	{
+		key:         "max_download_rate",
+		label:       "max download rate in bytes/s (0 = unlimited)",
+		placeholder: "0",
+		get:         func(c *config.Config) string { return formatInt(int(c.MaxDownloadRate)) },
+		set: func(c *config.Config, v string) error {
+			n, err := parseInt(v)
+			c.MaxDownloadRate = int64(n)
+			return err
+		},
+	},
+	{
+		key:         "readahead_bytes",
+		label:       "readahead minimum in bytes (0 = 8MB)",
+		placeholder: "0",
+		get:         func(c *config.Config) string { return formatInt(int(c.ReadaheadBytes)) },
+		set: func(c *config.Config, v string) error {
+			n, err := parseNonNegative(v)
+			if err != nil {
+				return err
+			}
+			c.ReadaheadBytes = int64(n)
+			return stream.CheckReadahead(c.ReadaheadBytes, int64(c.MemoryLimitMB)<<20)
+		},
+	},
+	{
+		key:         "readahead_percent",
+		label:       fmt.Sprintf("readahead percent of the file (0 = %d)", stream.DefaultReadaheadPercent),
+		placeholder: "0",
+		get:         func(c *config.Config) string { return formatInt(c.ReadaheadPercent) },
+		set: func(c *config.Config, v string) (err error) {
+			c.ReadaheadPercent, err = parsePercent(v)
+			return err
+		},
+	},
+	{
+		key:         "startup_boost_percent",
+		label:       fmt.Sprintf("startup boost percent of the file (0 = %d)", defaultStartupBoostPercent),
+		placeholder: "0",
+		get:         func(c *config.Config) string { return formatInt(c.StartupBoostPercent) },
+		set: func(c *config.Config, v string) (err error) {
+			c.StartupBoostPercent, err = parsePercent(v)
+			return err
+		},
+	},
+	{
+		key:         "preload_count",
+		label:       fmt.Sprintf("episodes to preload when streaming all (empty = %d, 0 = off)", config.DefaultPreloadCount),
+		placeholder: strconv.Itoa(config.DefaultPreloadCount),
+		get: func(c *config.Config) string {
+			if c.PreloadCount == nil {
+				return ""
+			}
+			return strconv.Itoa(*c.PreloadCount)
+		},
+		set: func(c *config.Config, v string) error {
+			if v == "" {
+				c.PreloadCount = nil
+				return nil
+			}
+			n, err := parseNonNegative(v)
+			if err != nil {
+				return err
+			}
+			c.PreloadCount = &n
+			return nil
+		},
+	},
+	{
+		key:         "autoplay_all",
+		label:       "enter streams all files from the cursor on: yes or no",
+		placeholder: "no",
+		get:         func(c *config.Config) string { return formatBool(c.AutoplayAll) },
+		set: func(c *config.Config, v string) (err error) {
+			c.AutoplayAll, err = parseBool(v)
+			return err
+		},
+	},
+	{
+		key:         "autoplay_single_file",
+		label:       "play single-file torrents right away: yes or no",
+		placeholder: "no",
+		get:         func(c *config.Config) string { return formatBool(c.AutoplaySingleFile) },
+		set: func(c *config.Config, v string) (err error) {
+			c.AutoplaySingleFile, err = parseBool(v)
+			return err
+		},
+	},
+	{
+		key:         "media_extensions",
+		label:       "media extensions, replacing the built-in list (empty = built-in)",
+		placeholder: ".mkv, .mp4, .avi, ...",
+		get:         func(c *config.Config) string { return strings.Join(c.MediaExtensions, ", ") },
+		set: func(c *config.Config, v string) error {
+			c.MediaExtensions = normalizeExtensions(strings.Split(v, ","))
+			return nil
+		},
+	},
+	{
+		key:         "extra_media_extensions",
+		label:       "extra media extensions (e.g. .mpg, .3gp, .divx)",
+		placeholder: "none",
+		get:         func(c *config.Config) string { return strings.Join(c.ExtraMediaExtensions, ", ") },
+		set: func(c *config.Config, v string) error {
+			c.ExtraMediaExtensions = normalizeExtensions(strings.Split(v, ","))
+			return nil
+		},
+	},
+	{
+		key:         "keybindings",
+		label:       "key bindings as action=key (" + strings.Join(config.KeyActions, ", ") + ")",
+		placeholder: "down=ctrl+n, up=ctrl+p",
+		get:         func(c *config.Config) string { return config.FormatPairs(c.Keybindings) },
+		set: func(c *config.Config, v string) error {
+			bindings, err := config.ParsePairs(v)
+			if err != nil {
+				return fmt.Errorf("key bindings: %w", err)
+			}
+			if err := config.CheckKeybindings(bindings); err != nil {
+				return err
+			}
+			if len(bindings) == 0 {
+				bindings = nil
+			}
+			c.Keybindings = bindings
+			return nil
+		},
+	},
+	{
+		key:         "theme",
+		label:       "theme: " + strings.Join(config.Themes, ", "),
+		placeholder: "default",
+		get:         func(c *config.Config) string { return c.Theme },
+		set: func(c *config.Config, v string) error {
+			v = strings.ToLower(v)
+			if !config.ValidTheme(v) {
+				return fmt.Errorf("unknown theme %q (want %s)", v, strings.Join(config.Themes, ", "))
+			}
+			c.Theme = v
+			return nil
+		},
+	},
+	{
+		key:         "theme_colors",
+		label:       "theme colors as role=color (" + strings.Join(config.ThemeColorRoles, ", ") + ")",
+		placeholder: "accent=#FFAF00, dim=244",
+		get:         func(c *config.Config) string { return config.FormatPairs(c.ThemeColors) },
+		set: func(c *config.Config, v string) error {
+			colors, err := config.ParsePairs(v)
+			if err != nil {
+				return fmt.Errorf("theme colors: %w", err)
+			}
+			if err := config.CheckThemeColors(colors); err != nil {
+				return err
+			}
+			if len(colors) == 0 {
+				colors = nil
+			}
+			c.ThemeColors = colors
+			return nil
+		},
+	},
+	{
+		key:         "show_tips",
+		label:       "show tips while loading: yes or no",
+		placeholder: "yes",
+		get:         func(c *config.Config) string { return formatBool(c.ShowsTips()) },
+		set: func(c *config.Config, v string) error {
+			if v == "" {
+				c.ShowTips = nil
+				return nil
+			}
+			show, err := parseBool(v)
+			c.ShowTips = &show
+			return err
+		},
+	},
+	{
+		key:         "seed_after_playback",
+		label:       "keep seeding after playback: yes or no",
+		placeholder: "yes",
+		get:         func(c *config.Config) string { return formatBool(c.SeedsAfterPlayback()) },
+		set: func(c *config.Config, v string) error {
+			if v == "" {
+				c.SeedAfterPlayback = nil
+				return nil
+			}
+			seed, err := parseBool(v)
+			c.SeedAfterPlayback = &seed
+			return err
+		},
+	},
+	{
+		key:         "default_volume",
+		label:       fmt.Sprintf("default volume, 0-%d (empty = mpv's; follows +/- during playback)", player.MaxVolume),
+		placeholder: "100",
+		get: func(c *config.Config) string {
+			if c.DefaultVolume == nil {
+				return ""
+			}
+			return strconv.Itoa(*c.DefaultVolume)
+		},
+		set: func(c *config.Config, v string) error {
+			if v == "" {
+				c.DefaultVolume = nil
+				return nil
+			}
+			n, err := parseInt(v)
+			if err != nil {
+				return err
+			}
+			if n < 0 || n > player.MaxVolume {
+				return fmt.Errorf("volume %d is outside 0-%d", n, player.MaxVolume)
+			}
+			c.DefaultVolume = &n
+			return nil
+		},
+	},
+	{
+		key:         "history_size",
+		label:       fmt.Sprintf("history size (0 = %d)", config.DefaultHistorySize),
+		placeholder: "0",
+		get:         func(c *config.Config) string { return formatInt(c.HistorySize) },
+		set: func(c *config.Config, v string) (err error) {
+			c.HistorySize, err = parseInt(v)
+			return err
+		},
+	},
+	{
+		key:         "use_default_trackers",
+		label:       "use default public trackers: yes or no",
+		placeholder: "no",
+		get:         func(c *config.Config) string { return formatBool(c.UseDefaultTrackers) },
+		set: func(c *config.Config, v string) (err error) {
+			c.UseDefaultTrackers, err = parseBool(v)
+			return err
+		},
+	},
+	{
+		key:         "extra_trackers",
+		label:       "extra trackers (one per line)",
+		placeholder: "udp://tracker.example.org:1337/announce",
+		multiline:   true,
+		get:         func(c *config.Config) string { return strings.Join(c.ExtraTrackers, "\n") },
+		set: func(c *config.Config, v string) error {
+			c.ExtraTrackers = nil
+			for _, line := range strings.Split(v, "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					c.ExtraTrackers = append(c.ExtraTrackers, line)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		key:         "piece_hashers",
+		section:     advancedSection,
+		label:       fmt.Sprintf("piece hash-check workers per torrent (0 = library default, max %d)", maxPieceHashers),
+		placeholder: "0",
+		get:         func(c *config.Config) string { return formatInt(c.PieceHashers) },
+		set: func(c *config.Config, v string) (err error) {
+			c.PieceHashers, err = parseBounded(v, maxPieceHashers)
+			return err
+		},
+	},
+	{
+		key:         "max_conns_per_torrent",
+		section:     advancedSection,
+		label:       fmt.Sprintf("max peer connections per torrent (0 = library default, max %d)", maxConnsPerTorrent),
+		placeholder: "0",
+		get:         func(c *config.Config) string { return formatInt(c.MaxConnsPerTorrent) },
+		set: func(c *config.Config, v string) (err error) {
+			c.MaxConnsPerTorrent, err = parseBounded(v, maxConnsPerTorrent)
+			return err
+		},
+	},
+	{
+		key:         "half_open_conns_per_torrent",
+		section:     advancedSection,
+		label:       fmt.Sprintf("half-open connections per torrent (0 = library default, max %d)", maxConnsPerTorrent),
+		placeholder: "0",
+		get:         func(c *config.Config) string { return formatInt(c.HalfOpenConnsPerTorrent) },
+		set: func(c *config.Config, v string) (err error) {
+			c.HalfOpenConnsPerTorrent, err = parseBounded(v, maxConnsPerTorrent)
+			return err
+		},
+	},
+	{
+		key:         "disable_aggressive_upload",
+		section:     advancedSection,
+		label:       "disable aggressive upload: yes or no",
+		placeholder: "no",
+		get:         func(c *config.Config) string { return formatBool(c.DisableAggressiveUpload) },
+		set: func(c *config.Config, v string) (err error) {
+			c.DisableAggressiveUpload, err = parseBool(v)
+			return err
+		},
+	},
+}
+
+// settingIndex returns the index of the field with the given key, or 0.
+func settingIndex(key string) int {
+	for i, f := range settingFields {
+		if f.key == key {
+			return i
+		}
+	}
+	return 0
+}
+
+// CheckConfig runs every settings field's parser over the value c holds,
+// so a config edited outside the settings screen (just-stream config set)
+// gets the same checks. The error names the offending JSON key.
+func CheckConfig(c *config.Config) error {
+	scratch := *c
+	for _, f := range settingFields {
+		if err := f.set(&scratch, f.get(c)); err != nil {
+			return fmt.Errorf("%s: %w", f.key, err)
+		}
+	}
+	return nil
+}
+
+// settingTestedMsg carries the result of a field's test.
+type settingTestedMsg struct {
+	result string
+	err    error
+}
+
+// cmdTestSetting runs the focused field's test off the UI goroutine;
+// testing an mpv path starts a process.
+func (m Model) cmdTestSetting() tea.Cmd {
+	f := settingFields[m.configFocus]
+	if f.test == nil {
+		return func() tea.Msg {
+			return settingTestedMsg{err: fmt.Errorf("nothing to test for this field")}
+		}
+	}
+	value := strings.TrimSpace(m.configInputs[m.configFocus].Value())
+	return func() tea.Msg {
+		result, err := f.test(value)
+		return settingTestedMsg{result: result, err: err}
+	}
+}
+
+// parseBool reads a yes/no setting. Empty means no.
+func parseBool(v string) (bool, error) {
+	switch strings.ToLower(v) {
+	case "", "no", "n", "off", "false":
+		return false, nil
+	case "yes", "y", "on", "true":
+		return true, nil
+	}
+	return false, fmt.Errorf("%q is not yes or no", v)
+}
+
+// parseInt reads a whole-number setting. Empty means 0.
+func parseInt(v string) (int, error) {
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a whole number", v)
+	}
+	return n, nil
+}
+
+// parseNonNegative is parseInt for settings where 0 picks the default and
+// negative values make no sense.
+func parseNonNegative(v string) (int, error) {
+	n, err := parseInt(v)
+	if err == nil && n < 0 {
+		return 0, fmt.Errorf("%q must not be negative", v)
+	}
+	return n, err
+}
+
+// parsePercent reads a percentage from 0 to 100.
+func parsePercent(v string) (int, error) {
+	n, err := parseNonNegative(v)
+	if err == nil && n > 100 {
+		return 0, fmt.Errorf("%q is more than 100%%", v)
+	}
+	return n, err
+}
+
+// splitArgs splits a flag list on spaces, and on commas that start a new
+// flag, so values such as "--slang=eng,en" stay whole.
+func splitArgs(v string) []string {
+	var args []string
+	for _, f := range strings.Fields(v) {
+		args = append(args, strings.Split(strings.ReplaceAll(f, ",--", "\x00--"), "\x00")...)
+	}
+	return args
+}
+
+// formatInt shows 0 as empty so the placeholder explains the default.
+func formatInt(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func formatBool(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// settingInput edits one settingField: a single-line input, or a textarea
+// for multiline fields.
+type settingInput struct {
+	multiline bool
+	line      textinput.Model
+	area      textarea.Model
+}
+
+func newSettingInputs() []settingInput {
+	inputs := make([]settingInput, len(settingFields))
+	for i, f := range settingFields {
+		if f.multiline {
+			ta := textarea.New()
+			ta.Placeholder = f.placeholder
+			ta.ShowLineNumbers = false
+			ta.SetWidth(60)
+			ta.SetHeight(4)
+			inputs[i] = settingInput{multiline: true, area: ta}
+			continue
+		}
+		in := textinput.New()
+		in.Placeholder = f.placeholder
+		in.CharLimit = 512
+		in.Width = 60
+		inputs[i] = settingInput{line: in}
+	}
+	return inputs
+}
+
+func (in *settingInput) Value() string {
+	if in.multiline {
+		return in.area.Value()
+	}
+	return in.line.Value()
+}
+
+func (in *settingInput) SetValue(v string) {
+	if in.multiline {
+		in.area.SetValue(v)
+		return
+	}
+	in.line.SetValue(v)
+}
+
+func (in *settingInput) Focus() {
+	if in.multiline {
+		in.area.Focus()
+		return
+	}
+	in.line.Focus()
+}
+
+func (in *settingInput) Blur() {
+	if in.multiline {
+		in.area.Blur()
+		return
+	}
+	in.line.Blur()
+}
+
+func (in settingInput) Update(msg tea.Msg) (settingInput, tea.Cmd) {
+	var cmd tea.Cmd
+	if in.multiline {
+		in.area, cmd = in.area.Update(msg)
+	} else {
+		in.line, cmd = in.line.Update(msg)
+	}
+	return in, cmd
+}
+
+func (in settingInput) View() string {
+	if in.multiline {
+		return in.area.View()
+	}
+	return in.line.View()
+}
+
+// loadSettings fills the config screen from the current config and
+// focuses the first field.
+func (m *Model) loadSettings() {
+	for i, f := range settingFields {
+		m.configInputs[i].SetValue(f.get(m.cfg))
+	}
+	m.focusSetting(0)
+}
+
+// focusSetting moves the cursor to field i, wrapping around.
+func (m *Model) focusSetting(i int) {
+	n := len(m.configInputs)
+	m.configFocus = (i%n + n) % n
+	for j := range m.configInputs {
+		if j == m.configFocus {
+			m.configInputs[j].Focus()
+		} else {
+			m.configInputs[j].Blur()
+		}
+	}
+}
+
+// applySettings stores every field into the config, or none of them if
+// one is invalid.
+func (m *Model) applySettings() error {
+	c := *m.cfg
+	for i, f := range settingFields {
+		if err := f.set(&c, strings.TrimSpace(m.configInputs[i].Value())); err != nil {
+			m.focusSetting(i)
+			return err
+		}
+	}
+	*m.cfg = c
+	m.styles = newStyles(m.cfg)
+	m.keymap = newKeymap(m.cfg.Keybindings)
+	m.spinner.Style = m.styles.spinner
+	return nil
+}
@@ -0,0 +1,285 @@
+package tui
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"golang.org/x/net/proxy"
+)
+
+// socksHandshakeTimeout bounds the UDP ASSOCIATE exchange with the proxy.
+const socksHandshakeTimeout = 10 * time.Second
+
+// SOCKS5 constants from RFC 1928 and RFC 1929.
+const (
+	socksVersion      = 5
+	socksNoAuth       = 0x00
+	socksUserPass     = 0x02
+	socksCmdAssociate = 0x03
+	socksATYPIPv4     = 0x01
+	socksATYPDomain   = 0x03
+	socksATYPIPv6     = 0x04
+)
+
+// addSOCKSDHT gives client a DHT server whose UDP traffic is relayed by
+// the SOCKS5 proxy at rawURL with UDP ASSOCIATE. configureProxy turns the
+// client's own DHT off, since most SOCKS5 proxies don't relay UDP; this
+// adds it back for those that do. It must run before torrents are added,
+// and fails, leaving DHT off, if the proxy refuses the association.
+//
+// Only the DHT's datagrams go through the proxy. Host names are still
+// resolved with the local resolver, even for socks5h: the DHT's bootstrap
+// nodes, and the relay if the proxy names it by host name. SOCKS5 has no
+// way to resolve a name without connecting to it, and the DHT needs
+// addresses before it sends anything.
+func addSOCKSDHT(client *torrent.Client, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("parse proxy URL: %w", err)
+	}
+	if u.Scheme != "socks5" && u.Scheme != "socks5h" {
+		return fmt.Errorf("UDP needs a SOCKS5 proxy, not %s", u.Scheme)
+	}
+	var auth *proxy.Auth
+	if u.User != nil {
+		auth = &proxy.Auth{User: u.User.Username()}
+		auth.Password, _ = u.User.Password()
+	}
+	pc, err := socksUDPAssociate(u.Host, auth)
+	if err != nil {
+		return err
+	}
+	srv, err := client.NewAnacrolixDhtServer(pc)
+	if err != nil {
+		pc.Close()
+		return fmt.Errorf("start DHT: %w", err)
+	}
+	client.AddDhtServer(torrent.AnacrolixDhtServerWrapper{Server: srv})
+	go func() {
+		<-client.Closed()
+		srv.Close()
+		pc.Close()
+	}()
+	slog.Info("DHT relayed through SOCKS5 proxy", "proxy", u.Host, "relay", pc.relay.String())
+	return nil
+}
+
+// socksPacketConn is a net.PacketConn whose datagrams travel through a
+// SOCKS5 UDP relay. The association lasts as long as the control
+// connection, so losing that closes the packet conn too.
+type socksPacketConn struct {
+	*net.UDPConn
+	ctrl  net.Conn
+	relay *net.UDPAddr
+
+	// readMu guards readBuf, which ReadFrom reuses for every datagram.
+	readMu  sync.Mutex
+	readBuf []byte
+}
+
+// socksUDPAssociate asks the proxy at addr for a UDP relay.
+func socksUDPAssociate(addr string, auth *proxy.Auth) (*socksPacketConn, error) {
+	ctrl, err := net.DialTimeout("tcp", addr, socksHandshakeTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("dial SOCKS5 proxy: %w", err)
+	}
+	relay, err := socksAssociate(ctrl, auth)
+	if err != nil {
+		ctrl.Close()
+		return nil, fmt.Errorf("SOCKS5 UDP associate: %w", err)
+	}
+	if relay.IP.IsUnspecified() {
+		// The relay is on the proxy host itself.
+		relay.IP = ctrl.RemoteAddr().(*net.TCPAddr).IP
+	}
+	udp, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		ctrl.Close()
+		return nil, fmt.Errorf("open UDP socket: %w", err)
+	}
+	c := &socksPacketConn{UDPConn: udp, ctrl: ctrl, relay: relay}
+	go func() {
+		// The proxy sends nothing more on the control connection; a read
+		// returning means it ended the association.
+		_, _ = io.Copy(io.Discard, ctrl)
+		c.UDPConn.Close()
+	}()
+	return c, nil
+}
+
+// socksAssociate runs the greeting, authentication and UDP ASSOCIATE
+// request on ctrl and returns the relay address.
+func socksAssociate(ctrl net.Conn, auth *proxy.Auth) (*net.UDPAddr, error) {
+	_ = ctrl.SetDeadline(time.Now().Add(socksHandshakeTimeout))
+	defer ctrl.SetDeadline(time.Time{})
+
+	method := byte(socksNoAuth)
+	if auth != nil {
+		method = socksUserPass
+	}
+	if _, err := ctrl.Write([]byte{socksVersion, 1, method}); err != nil {
+		return nil, err
+	}
+	var reply [2]byte
+	if _, err := io.ReadFull(ctrl, reply[:]); err != nil {
+		return nil, err
+	}
+	if reply[0] != socksVersion || reply[1] != method {
+		return nil, errors.New("proxy refused the authentication method")
+	}
+	if auth != nil {
+		if len(auth.User) > 255 || len(auth.Password) > 255 {
+			return nil, errors.New("proxy user name or password too long")
+		}
+		msg := []byte{1, byte(len(auth.User))}
+		msg = append(msg, auth.User...)
+		msg = append(msg, byte(len(auth.Password)))
+		msg = append(msg, auth.Password...)
+		if _, err := ctrl.Write(msg); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(ctrl, reply[:]); err != nil {
+			return nil, err
+		}
+		if reply[1] != 0 {
+			return nil, errors.New("proxy rejected the user name or password")
+		}
+	}
+
+	// Ask for a relay without naming our address; we may be behind NAT.
+	if _, err := ctrl.Write([]byte{socksVersion, socksCmdAssociate, 0, socksATYPIPv4, 0, 0, 0, 0, 0, 0}); err != nil {
+		return nil, err
+	}
+	var head [3]byte
+	if _, err := io.ReadFull(ctrl, head[:]); err != nil {
+		return nil, err
+	}
+	if head[1] != 0 {
+		return nil, fmt.Errorf("proxy refused UDP (reply code %d)", head[1])
+	}
+	relay, err := readSOCKSAddr(ctrl)
+	if err != nil {
+		return nil, err
+	}
+	return relay, nil
+}
+
+// readSOCKSAddr reads an ATYP-prefixed address and port. Domain names are
+// resolved, since the relay must be an IP to send datagrams to.
+func readSOCKSAddr(r io.Reader) (*net.UDPAddr, error) {
+	var atyp [1]byte
+	if _, err := io.ReadFull(r, atyp[:]); err != nil {
+		return nil, err
+	}
+	var host string
+	switch atyp[0] {
+	case socksATYPIPv4, socksATYPIPv6:
+		ip := make(net.IP, net.IPv4len)
+		if atyp[0] == socksATYPIPv6 {
+			ip = make(net.IP, net.IPv6len)
+		}
+		if _, err := io.ReadFull(r, ip); err != nil {
+			return nil, err
+		}
+		host = ip.String()
+	case socksATYPDomain:
+		var n [1]byte
+		if _, err := io.ReadFull(r, n[:]); err != nil {
+			return nil, err
+		}
+		name := make([]byte, n[0])
+		if _, err := io.ReadFull(r, name); err != nil {
+			return nil, err
+		}
+		host = string(name)
+	default:
+		return nil, fmt.Errorf("unknown SOCKS5 address type %d", atyp[0])
+	}
+	var port [2]byte
+	if _, err := io.ReadFull(r, port[:]); err != nil {
+		return nil, err
+	}
+	return net.ResolveUDPAddr("udp", net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port[:])))))
+}
+
+// WriteTo sends b to addr through the relay.
+func (c *socksPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	ua, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return 0, fmt.Errorf("SOCKS5 relay: unsupported address %v", addr)
+	}
+	// RSV RSV FRAG ATYP ADDR PORT DATA
+	msg := []byte{0, 0, 0}
+	if ip4 := ua.IP.To4(); ip4 != nil {
+		msg = append(msg, socksATYPIPv4)
+		msg = append(msg, ip4...)
+	} else {
+		msg = append(msg, socksATYPIPv6)
+		msg = append(msg, ua.IP.To16()...)
+	}
+	msg = binary.BigEndian.AppendUint16(msg, uint16(ua.Port))
+	msg = append(msg, b...)
+	if _, err := c.UDPConn.WriteTo(msg, c.relay); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// ReadFrom returns the next datagram the relay forwarded, with the
+// address of its original sender. Anything else is dropped.
+func (c *socksPacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+	// Room for the largest header: RSV RSV FRAG ATYP, an IPv6 address
+	// and the port.
+	if need := len(b) + 4 + net.IPv6len + 2; len(c.readBuf) < need {
+		c.readBuf = make([]byte, need)
+	}
+	buf := c.readBuf
+	for {
+		n, from, err := c.UDPConn.ReadFromUDP(buf)
+		if err != nil {
+			return 0, nil, err
+		}
+		if !from.IP.Equal(c.relay.IP) || from.Port != c.relay.Port {
+			continue
+		}
+		// Fragmented datagrams (FRAG != 0) are optional and never used
+		// by the DHT; drop them along with truncated ones.
+		if n < 4 || buf[2] != 0 {
+			continue
+		}
+		var ipLen int
+		switch buf[3] {
+		case socksATYPIPv4:
+			ipLen = net.IPv4len
+		case socksATYPIPv6:
+			ipLen = net.IPv6len
+		default:
+			continue
+		}
+		if n < 4+ipLen+2 {
+			continue
+		}
+		src := &net.UDPAddr{
+			IP:   net.IP(append([]byte(nil), buf[4:4+ipLen]...)),
+			Port: int(binary.BigEndian.Uint16(buf[4+ipLen:])),
+		}
+		return copy(b, buf[4+ipLen+2:n]), src, nil
+	}
+}
+
+// Close ends the association and closes the local socket.
+func (c *socksPacketConn) Close() error {
+	c.ctrl.Close()
+	return c.UDPConn.Close()
+}
@@ -0,0 +1,264 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// fakeSOCKS is an in-process SOCKS5 proxy that answers one UDP ASSOCIATE
+// with the address of relay, a UDP socket the test drives.
+type fakeSOCKS struct {
+	addr      string
+	relay     *net.UDPConn
+	user      string
+	pass      string
+	replyCode byte
+	errs      chan error
+}
+
+func startFakeSOCKS(t *testing.T, user, pass string, replyCode byte) *fakeSOCKS {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	relay, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ln.Close()
+		relay.Close()
+	})
+	f := &fakeSOCKS{addr: ln.Addr().String(), relay: relay, user: user, pass: pass, replyCode: replyCode, errs: make(chan error, 1)}
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		f.errs <- f.serve(conn)
+		// Hold the association open until the client hangs up.
+		_, _ = io.Copy(io.Discard, conn)
+	}()
+	return f
+}
+
+func (f *fakeSOCKS) serve(conn net.Conn) error {
+	var greet [2]byte
+	if _, err := io.ReadFull(conn, greet[:]); err != nil {
+		return err
+	}
+	methods := make([]byte, greet[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return err
+	}
+	want := byte(socksNoAuth)
+	if f.user != "" {
+		want = socksUserPass
+	}
+	if greet[0] != socksVersion || !bytes.Contains(methods, []byte{want}) {
+		_, err := conn.Write([]byte{socksVersion, 0xff})
+		return err
+	}
+	if _, err := conn.Write([]byte{socksVersion, want}); err != nil {
+		return err
+	}
+	if f.user != "" {
+		user, pass, err := readUserPass(conn)
+		if err != nil {
+			return err
+		}
+		status := byte(0)
+		if user != f.user || pass != f.pass {
+			status = 1
+		}
+		if _, err := conn.Write([]byte{1, status}); err != nil || status != 0 {
+			return err
+		}
+	}
+	var req [10]byte
+	if _, err := io.ReadFull(conn, req[:]); err != nil {
+		return err
+	}
+	if req[0] != socksVersion || req[1] != socksCmdAssociate || req[3] != socksATYPIPv4 {
+		_, err := conn.Write([]byte{socksVersion, 7, 0, socksATYPIPv4, 0, 0, 0, 0, 0, 0})
+		return err
+	}
+	reply := []byte{socksVersion, f.replyCode, 0, socksATYPIPv4, 127, 0, 0, 1}
+	reply = binary.BigEndian.AppendUint16(reply, uint16(f.relay.LocalAddr().(*net.UDPAddr).Port))
+	_, err := conn.Write(reply)
+	return err
+}
+
+func readUserPass(r io.Reader) (user, pass string, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return "", "", err
+	}
+	u := make([]byte, head[1])
+	if _, err := io.ReadFull(r, u); err != nil {
+		return "", "", err
+	}
+	var n [1]byte
+	if _, err := io.ReadFull(r, n[:]); err != nil {
+		return "", "", err
+	}
+	p := make([]byte, n[0])
+	if _, err := io.ReadFull(r, p); err != nil {
+		return "", "", err
+	}
+	return string(u), string(p), nil
+}
+
+// datagram builds a relayed datagram: RSV RSV FRAG ATYP ADDR PORT DATA.
+func datagram(frag byte, addr *net.UDPAddr, data string) []byte {
+	msg := []byte{0, 0, frag}
+	if ip4 := addr.IP.To4(); ip4 != nil {
+		msg = append(msg, socksATYPIPv4)
+		msg = append(msg, ip4...)
+	} else {
+		msg = append(msg, socksATYPIPv6)
+		msg = append(msg, addr.IP.To16()...)
+	}
+	msg = binary.BigEndian.AppendUint16(msg, uint16(addr.Port))
+	return append(msg, data...)
+}
+
+func TestSOCKSUDPRelay(t *testing.T) {
+	f := startFakeSOCKS(t, "alice", "secret", 0)
+	pc, err := socksUDPAssociate(f.addr, &proxy.Auth{User: "alice", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	if err := <-f.errs; err != nil {
+		t.Fatalf("proxy side of the handshake: %v", err)
+	}
+	if got, want := pc.relay.String(), f.relay.LocalAddr().String(); got != want {
+		t.Fatalf("relay = %s, want %s", got, want)
+	}
+	f.relay.SetDeadline(time.Now().Add(5 * time.Second))
+	pc.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// Outgoing datagrams carry the destination in the SOCKS5 header.
+	buf := make([]byte, 1500)
+	for _, dst := range []*net.UDPAddr{
+		{IP: net.IPv4(1, 2, 3, 4), Port: 6881},
+		{IP: net.ParseIP("2001:db8::1"), Port: 51413},
+	} {
+		if n, err := pc.WriteTo([]byte("ping"), dst); err != nil || n != 4 {
+			t.Fatalf("WriteTo(%s) = %d, %v", dst, n, err)
+		}
+		n, _, err := f.relay.ReadFromUDP(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := datagram(0, dst, "ping"); !bytes.Equal(buf[:n], want) {
+			t.Errorf("relay got % x, want % x", buf[:n], want)
+		}
+	}
+
+	// Datagrams not from the relay, and fragments, are dropped.
+	local := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: pc.LocalAddr().(*net.UDPAddr).Port}
+	stranger, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stranger.Close()
+	src := &net.UDPAddr{IP: net.IPv4(5, 6, 7, 8), Port: 51413}
+	if _, err := stranger.WriteToUDP(datagram(0, src, "spoofed"), local); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.relay.WriteToUDP(datagram(1, src, "fragment"), local); err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range []string{"pong", "again"} {
+		if _, err := f.relay.WriteToUDP(datagram(0, src, data), local); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b := make([]byte, 1500)
+	n, from, err := pc.ReadFrom(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b[:n]) != "pong" || from.String() != src.String() {
+		t.Errorf("ReadFrom = %q from %s, want \"pong\" from %s", b[:n], from, src)
+	}
+	first := &pc.readBuf[0]
+	n, _, err = pc.ReadFrom(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b[:n]) != "again" {
+		t.Errorf("second ReadFrom = %q", b[:n])
+	}
+	if &pc.readBuf[0] != first {
+		t.Error("ReadFrom allocated a new buffer for the second datagram")
+	}
+}
+
+func TestSOCKSUDPAssociateRefused(t *testing.T) {
+	tests := []struct {
+		name      string
+		user      string
+		auth      *proxy.Auth
+		replyCode byte
+		want      string
+	}{
+		{"wrong password", "alice", &proxy.Auth{User: "alice", Password: "guess"}, 0, "rejected the user name or password"},
+		{"auth required", "alice", nil, 0, "refused the authentication method"},
+		{"UDP not allowed", "", nil, 2, "refused UDP (reply code 2)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := startFakeSOCKS(t, tt.user, "secret", tt.replyCode)
+			pc, err := socksUDPAssociate(f.addr, tt.auth)
+			if err == nil {
+				pc.Close()
+				t.Fatal("associate succeeded")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q doesn't mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadSOCKSAddr(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want string
+	}{
+		{"IPv4", []byte{socksATYPIPv4, 10, 0, 0, 1, 0x1a, 0xe1}, "10.0.0.1:6881"},
+		{"IPv6", append(append([]byte{socksATYPIPv6}, net.ParseIP("2001:db8::2").To16()...), 0, 80), "[2001:db8::2]:80"},
+		{"domain", append([]byte{socksATYPDomain, 9}, append([]byte("127.0.0.1"), 0x04, 0x38)...), "127.0.0.1:1080"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, err := readSOCKSAddr(bytes.NewReader(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if addr.String() != tt.want {
+				t.Errorf("got %s, want %s", addr, tt.want)
+			}
+		})
+	}
+	if _, err := readSOCKSAddr(bytes.NewReader([]byte{9, 0, 0})); err == nil {
+		t.Error("unknown address type accepted")
+	}
+	if _, err := readSOCKSAddr(bytes.NewReader([]byte{socksATYPIPv4, 10, 0})); err == nil {
+		t.Error("truncated address accepted")
+	}
+}
@@ -0,0 +1,126 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/enrell/just-stream/config"
+)
+
+// palette assigns a color to each role in config.ThemeColorRoles.
+type palette map[string]lipgloss.TerminalColor
+
+// palettes are the presets config.Themes names. mono leaves every color to
+// the terminal and marks roles with bold and faint text only.
+var palettes = map[string]palette{
+	"default": {
+		"accent":    lipgloss.Color("#FF6AC1"),
+		"subtitle":  lipgloss.Color("#9B9B9B"),
+		"text":      lipgloss.Color("#D4D4D4"),
+		"dim":       lipgloss.Color("#666666"),
+		"help":      lipgloss.Color("#626262"),
+		"status":    lipgloss.Color("#7EC8E3"),
+		"error":     lipgloss.Color("#FF4444"),
+		"warn":      lipgloss.Color("#F1FA8C"),
+		"playing":   lipgloss.Color("#50FA7B"),
+		"seeding":   lipgloss.Color("#FFB86C"),
+		"bar_empty": lipgloss.Color("#333333"),
+	},
+	"mono": {},
+	"solarized": {
+		"accent":    lipgloss.Color("#D33682"),
+		"subtitle":  lipgloss.Color("#93A1A1"),
+		"text":      lipgloss.Color("#839496"),
+		"dim":       lipgloss.Color("#586E75"),
+		"help":      lipgloss.Color("#586E75"),
+		"status":    lipgloss.Color("#2AA198"),
+		"error":     lipgloss.Color("#DC322F"),
+		"warn":      lipgloss.Color("#B58900"),
+		"playing":   lipgloss.Color("#859900"),
+		"seeding":   lipgloss.Color("#CB4B16"),
+		"bar_empty": lipgloss.Color("#073642"),
+	},
+}
+
+// styles are the lipgloss styles every view renders with, built from the
+// configured theme.
+type styles struct {
+	title, subtitle, selected, normal, dim, status, error, help, header lipgloss.Style
+	progressFull, progressEmpty, playing, seeding, warn, spinner        lipgloss.Style
+}
+
+// newStyles builds the styles for c's theme and color overrides. Unknown
+// names fall back to the default theme; invalid overrides are skipped, as
+// settings and `config set` reject them before they are saved.
+func newStyles(c *config.Config) styles {
+	base, ok := palettes[c.Theme]
+	if !ok {
+		base = palettes["default"]
+	}
+	p := palette{}
+	for role, color := range base {
+		p[role] = color
+	}
+	if config.CheckThemeColors(c.ThemeColors) == nil {
+		for role, color := range c.ThemeColors {
+			p[role] = lipgloss.Color(color)
+		}
+	}
+	fg := func(role string) lipgloss.Style {
+		s := lipgloss.NewStyle()
+		if color, ok := p[role]; ok {
+			return s.Foreground(color)
+		}
+		switch role {
+		case "subtitle", "dim", "help", "bar_empty":
+			// Without a color, muted text is faint instead.
+			s = s.Faint(true)
+		}
+		return s
+	}
+	return styles{
+		title:         fg("accent").Bold(true),
+		subtitle:      fg("subtitle"),
+		selected:      fg("accent").Bold(true),
+		normal:        fg("text"),
+		dim:           fg("dim"),
+		status:        fg("status"),
+		error:         fg("error").Bold(true),
+		help:          fg("help"),
+		header:        fg("accent").Bold(true),
+		progressFull:  fg("accent"),
+		progressEmpty: fg("bar_empty"),
+		playing:       fg("playing").Bold(true),
+		seeding:       fg("seeding"),
+		warn:          fg("warn").Bold(true),
+		spinner:       fg("accent"),
+	}
+}
+
+// barWidth is the width of a progress bar in cells.
+const barWidth = 40
+
+// partialBlocks draw a cell filled by 1 to 7 eighths.
+var partialBlocks = []string{"▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+
+// progressBar renders pct (0-100) as a fixed-width bar, drawing the cell
+// at the edge in eighths so the bar grows smoothly.
+func (s styles) progressBar(pct float64) string {
+	full, eighths := barCells(pct/100, barWidth)
+	bar := strings.Repeat("█", full)
+	empty := barWidth - full
+	if eighths > 0 {
+		bar += partialBlocks[eighths-1]
+		empty--
+	}
+	return s.progressFull.Render(bar) + s.progressEmpty.Render(strings.Repeat("░", empty))
+}
+
+// barCells splits a bar of width cells filled to frac, clamped to 0-1,
+// into whole cells and the eighths, 0-7, of the cell after them.
+func barCells(frac float64, width int) (full, eighths int) {
+	frac = min(max(frac, 0), 1)
+	n := int(frac * float64(width*8))
+	return n / 8, n % 8
+}
@@ -0,0 +1,52 @@
+package tui
+
+import "testing"
+
+func TestBarCells(t *testing.T) {
+	tests := []struct {
+		name          string
+		frac          float64
+		width         int
+		full, eighths int
+	}{
+		{"empty", 0, barWidth, 0, 0},
+		{"full", 1, barWidth, barWidth, 0},
+		{"half", 0.5, barWidth, 20, 0},
+		{"one eighth of a cell", 1.0 / (8 * barWidth), barWidth, 0, 1},
+		{"just under a cell", 7.0 / (8 * barWidth), barWidth, 0, 7},
+		{"a cell and three eighths", 11.0 / (8 * barWidth), barWidth, 1, 3},
+		{"below an eighth", 0.001, barWidth, 0, 0},
+		{"just under full", 0.999, barWidth, barWidth - 1, 7},
+		{"negative", -0.5, barWidth, 0, 0},
+		{"over full", 1.5, barWidth, barWidth, 0},
+		{"narrow bar", 0.25, 4, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			full, eighths := barCells(tt.frac, tt.width)
+			if full != tt.full || eighths != tt.eighths {
+				t.Errorf("barCells(%v, %d) = %d, %d; want %d, %d", tt.frac, tt.width, full, eighths, tt.full, tt.eighths)
+			}
+		})
+	}
+}
+
+func TestFraction(t *testing.T) {
+	tests := []struct {
+		done, total int64
+		want        float64
+	}{
+		{0, 100, 0},
+		{25, 100, 0.25},
+		{100, 100, 1},
+		{150, 100, 1},
+		{-5, 100, 0},
+		{10, 0, 0},
+		{10, -1, 0},
+	}
+	for _, tt := range tests {
+		if got := fraction(tt.done, tt.total); got != tt.want {
+			t.Errorf("fraction(%d, %d) = %v, want %v", tt.done, tt.total, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package tui
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// episodePatterns match common episode numbering schemes, most specific
+// first: "S01E05", "Episode 5" / "Ep 05" / "E05", and " - 05" as used by
+// most fansub releases.
+var episodePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)s\d{1,2}e(\d{1,4})`),
+	regexp.MustCompile(`(?i)\b(?:episode|ep|e)[ ._]?(\d{1,4})\b`),
+	regexp.MustCompile(`\s-\s(\d{1,4})(?:v\d)?\b`),
+}
+
+// releaseTagPattern matches bracketed release tags such as "[Group]" or
+// "(1080p)".
+var releaseTagPattern = regexp.MustCompile(`\[[^\]]*\]|\([^)]*\)`)
+
+// parseEpisodeNumber extracts the episode number from a file name.
+func parseEpisodeNumber(name string) (int, bool) {
+	for _, re := range episodePatterns {
+		if m := re.FindStringSubmatch(name); m != nil {
+			n, err := strconv.Atoi(m[1])
+			if err == nil {
+				return n, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// showName derives a readable show name from a torrent name by dropping
+// release tags and dot/underscore separators.
+func showName(torrentName string) string {
+	name := releaseTagPattern.ReplaceAllString(torrentName, "")
+	name = strings.NewReplacer(".", " ", "_", " ").Replace(name)
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// formatTitle renders a mpv media title from a template. Supported
+// placeholders are {show}, {episode} (as "E05"), {name} (the file name)
+// and {index} (1-based playlist position). An empty template, or one that
+// needs an episode number the file name doesn't carry, yields the file
+// name unchanged.
+func formatTitle(format, torrentName, path string, index int) string {
+	name := shortName(path)
+	if format == "" {
+		return name
+	}
+	episode := ""
+	if strings.Contains(format, "{episode}") {
+		n, ok := parseEpisodeNumber(name)
+		if !ok {
+			return name
+		}
+		episode = "E" + padNumber(n)
+	}
+	return strings.NewReplacer(
+		"{show}", showName(torrentName),
+		"{episode}", episode,
+		"{name}", name,
+		"{index}", strconv.Itoa(index+1),
+	).Replace(format)
+}
+
+// padNumber zero-pads n to at least two digits.
+func padNumber(n int) string {
+	s := strconv.Itoa(n)
+	if len(s) < 2 {
+		s = "0" + s
+	}
+	return s
+}
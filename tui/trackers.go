@@ -0,0 +1,40 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/enrell/just-stream/config"
+)
+
+// defaultTrackers are well-known public trackers added to every torrent
+// when Config.UseDefaultTrackers is set, so magnets that carry few or no
+// trackers can still find peers.
+var defaultTrackers = []string{
+	"udp://tracker.opentrackr.org:1337/announce",
+	"udp://open.stealth.si:80/announce",
+	"udp://tracker.torrent.eu.org:451/announce",
+	"udp://exodus.desync.com:6969/announce",
+	"udp://open.demonii.com:1337/announce",
+	"https://tracker.tamersunion.org:443/announce",
+}
+
+// extraTrackerTiers returns the trackers to add on top of a torrent's
+// own, one per tier so each is announced to independently.
+func extraTrackerTiers(cfg *config.Config) [][]string {
+	var urls []string
+	urls = append(urls, cfg.ExtraTrackers...)
+	if cfg.UseDefaultTrackers {
+		urls = append(urls, defaultTrackers...)
+	}
+	seen := make(map[string]bool)
+	var tiers [][]string
+	for _, u := range urls {
+		u = strings.TrimSpace(u)
+		if u == "" || seen[u] {
+			continue
+		}
+		seen[u] = true
+		tiers = append(tiers, []string{u})
+	}
+	return tiers
+}
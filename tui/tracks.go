@@ -0,0 +1,248 @@
+package tui
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/enrell/just-stream/config"
+	"github.com/enrell/just-stream/player"
+)
+
+// The track picker (t on the playback screen) lists the playing file's
+// audio and subtitle tracks and switches between them. The pick is saved
+// per torrent by language, so later episodes and re-streams start on the
+// same tracks.
+
+// trackRow is one line of the track picker. A sub row without a track
+// turns subtitles off.
+type trackRow struct {
+	kind  string // "audio" or "sub"
+	track *player.Track
+}
+
+type tracksLoadedMsg struct {
+	tracks []player.Track
+	err    error
+}
+
+// openTracks switches from the playback screen to the track picker.
+func (m Model) openTracks() (tea.Model, tea.Cmd) {
+	mpv := m.shared.getMPV()
+	if mpv == nil || !mpv.HasIPC() {
+		m.playStatus = "Track selection needs mpv with IPC"
+		return m, nil
+	}
+	m.screen = screenTracks
+	m.tracks = nil
+	m.trackCursor = 0
+	m.tracksErr = nil
+	m.tracksStatus = ""
+	return m, cmdLoadTracks(mpv)
+}
+
+func cmdLoadTracks(mpv player.Controller) tea.Cmd {
+	return func() tea.Msg {
+		tracks, err := mpv.Tracks()
+		return tracksLoadedMsg{tracks: tracks, err: err}
+	}
+}
+
+// cmdSelectTrack switches to row's track, saves the choice for the
+// torrent, and reloads the list so the selection mark follows.
+func (m Model) cmdSelectTrack(row trackRow) tea.Cmd {
+	mpv := m.shared.getMPV()
+	if mpv == nil {
+		return nil
+	}
+	infoHash := m.currentTorrent().InfoHash().HexString()
+	return func() tea.Msg {
+		id := 0
+		if row.track != nil {
+			id = row.track.ID
+		}
+		if err := mpv.SelectTrack(row.kind, id); err != nil {
+			return tracksLoadedMsg{err: fmt.Errorf("select track: %w", err)}
+		}
+		err := config.UpdateTrackChoice(infoHash, func(c *config.TrackChoice) {
+			switch {
+			case row.track == nil:
+				c.SubsOff, c.SubLang = true, ""
+			case row.kind == "audio":
+				c.AudioLang = row.track.Lang
+			default:
+				c.SubsOff, c.SubLang = false, row.track.Lang
+			}
+		})
+		if err != nil {
+			slog.Warn("save track choice", "err", err)
+		}
+		tracks, err := mpv.Tracks()
+		return tracksLoadedMsg{tracks: tracks, err: err}
+	}
+}
+
+// trackRows lists the audio tracks, then the subtitle tracks followed by
+// a row to turn subtitles off.
+func trackRows(tracks []player.Track) []trackRow {
+	var rows []trackRow
+	for _, kind := range []string{"audio", "sub"} {
+		n := 0
+		for i := range tracks {
+			if tracks[i].Type == kind {
+				rows = append(rows, trackRow{kind: kind, track: &tracks[i]})
+				n++
+			}
+		}
+		if kind == "sub" && n > 0 {
+			rows = append(rows, trackRow{kind: "sub"})
+		}
+	}
+	return rows
+}
+
+// updateTracks handles the track picker. Everything but keys and the
+// track list goes to updatePlaying so mpv events keep flowing.
+func (m Model) updateTracks(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tracksLoadedMsg:
+		m.tracks, m.tracksErr = msg.tracks, msg.err
+		m.trackCursor = clampCursor(m.trackCursor, len(trackRows(m.tracks)))
+		return m, nil
+	case tea.KeyMsg:
+		rows := trackRows(m.tracks)
+		switch m.keys().canonical(msg.String(), playingActions...) {
+		case "j", "down":
+			if m.trackCursor < len(rows)-1 {
+				m.trackCursor++
+			}
+		case "k", "up":
+			if m.trackCursor > 0 {
+				m.trackCursor--
+			}
+		case "enter":
+			if m.trackCursor >= len(rows) {
+				return m, nil
+			}
+			row := rows[m.trackCursor]
+			m.tracksStatus = ""
+			if row.track != nil && row.track.Lang == "" {
+				m.tracksStatus = "This track has no language, so the choice applies to this file only"
+			}
+			return m, m.cmdSelectTrack(row)
+		case "t", "esc":
+			m.screen = screenPlaying
+		case "q":
+			return m.updatePlaying(msg)
+		}
+		return m, nil
+	}
+	return m.updatePlaying(msg)
+}
+
+func (m Model) viewTracks() string {
+	var b strings.Builder
+	b.WriteString(m.styles.title.Render("just-stream"))
+	b.WriteString(" ")
+	b.WriteString(m.styles.dim.Render("tracks"))
+	b.WriteString("\n\n")
+
+	switch {
+	case m.tracksErr != nil:
+		b.WriteString(m.viewError(m.tracksErr))
+		b.WriteString("\n")
+	case m.tracks == nil:
+		b.WriteString(m.styles.dim.Render("  Loading tracks..."))
+		b.WriteString("\n")
+	default:
+		rows := trackRows(m.tracks)
+		subsOn := false
+		for _, r := range rows {
+			if r.kind == "sub" && r.track != nil && r.track.Selected {
+				subsOn = true
+			}
+		}
+		for _, kind := range []string{"audio", "sub"} {
+			header, none := "Audio", "  No audio tracks"
+			if kind == "sub" {
+				header, none = "Subtitles", "  No subtitle tracks"
+			}
+			b.WriteString(m.styles.header.Render("  " + header))
+			b.WriteString("\n")
+			listed := false
+			for i, r := range rows {
+				if r.kind != kind {
+					continue
+				}
+				listed = true
+				mark, label := " ", "off"
+				if r.track != nil {
+					label = trackLabel(*r.track)
+					if r.track.Selected {
+						mark = "●"
+					}
+				} else if !subsOn {
+					mark = "●"
+				}
+				line := mark + " " + label
+				if i == m.trackCursor {
+					b.WriteString(m.styles.selected.Render("  > " + line))
+				} else {
+					b.WriteString(m.styles.normal.Render("    " + line))
+				}
+				b.WriteString("\n")
+			}
+			if !listed {
+				b.WriteString(m.styles.dim.Render("  " + none))
+				b.WriteString("\n")
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if m.tracksStatus != "" {
+		b.WriteString(m.styles.status.Render("  " + m.tracksStatus))
+		b.WriteString("\n\n")
+	}
+	b.WriteString(m.styles.help.Render(helpLine("j/k: move  enter: select  t/esc: back to playback", m.keys().help("quit", actionQuit))))
+	return b.String()
+}
+
+// trackLabel describes a track as "#2 jpn Japanese (aac, default)".
+func trackLabel(t player.Track) string {
+	parts := []string{fmt.Sprintf("#%d", t.ID)}
+	if t.Lang != "" {
+		parts = append(parts, t.Lang)
+	}
+	if t.Title != "" {
+		parts = append(parts, t.Title)
+	}
+	var notes []string
+	if t.Codec != "" {
+		notes = append(notes, t.Codec)
+	}
+	if t.Default {
+		notes = append(notes, "default")
+	}
+	if t.External {
+		notes = append(notes, "external")
+	}
+	if len(notes) > 0 {
+		parts = append(parts, "("+strings.Join(notes, ", ")+")")
+	}
+	return strings.Join(parts, " ")
+}
+
+// preferLang puts a remembered track language ahead of the configured
+// preference list.
+func preferLang(picked, preferred string) string {
+	switch {
+	case picked == "":
+		return preferred
+	case preferred == "":
+		return picked
+	}
+	return picked + "," + preferred
+}
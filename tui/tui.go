@@ -1,75 +1,45 @@
 package tui
 
 import (
+	"bufio"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log/slog"
+	"math"
+	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/anacrolix/torrent/storage"
+	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/lipgloss"
 	"golang.org/x/net/proxy"
+	"golang.org/x/time/rate"
 
-"github.com/enrell/just-stream/config"
+	"github.com/enrell/just-stream/config"
 	"github.com/enrell/just-stream/player"
 	memstorage "github.com/enrell/just-stream/storage"
 	"github.com/enrell/just-stream/stream"
 )
 
-// --- Styles ---
-
-var (
-	titleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("#FF6AC1"))
-
-	subtitleStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#9B9B9B"))
-
-	selectedStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FF6AC1")).
-			Bold(true)
-
-	normalStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#D4D4D4"))
-
-	dimStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#666666"))
-
-	statusStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#7EC8E3"))
-
-	errorStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FF4444")).
-			Bold(true)
-
-	helpStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#626262"))
-
-	headerStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FF6AC1")).
-			Bold(true)
-
-	progressFullStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("#FF6AC1"))
-
-	progressEmptyStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("#333333"))
-
-	playingStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#50FA7B")).
-			Bold(true)
-
-	seedingStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FFB86C"))
-)
-
 // --- Screens ---
 
 type screen int
@@ -79,7 +49,10 @@ const (
 	screenLoading               // waiting for metadata
 	screenFiles                 // file selection list
 	screenPlaying               // playback status
-	screenConfig                // settings (mpv path)
+	screenConfig                // settings
+	screenHistory               // recently opened torrents
+	screenPeers                 // connected peers, opened from playback
+	screenTracks                // audio/subtitle track picker, opened from playback
 )
 
 // --- Messages ---
@@ -88,13 +61,43 @@ type (
 	metadataReadyMsg struct {
 		client *torrent.Client
 		t      *torrent.Torrent
+		// mi is the parsed metainfo file when the torrent came from one.
+		// Magnet links carry no comment or creation fields, so it is nil
+		// for them; the client's own Metainfo() only synthesizes these.
+		mi *metainfo.MetaInfo
+	}
+	metadataErrMsg struct{ err error }
+	// metadataStartedMsg hands over the torrent while its metadata is
+	// still being fetched, so the loading screen can show the swarm.
+	metadataStartedMsg struct {
+		gen int
+		t   *torrent.Torrent
+	}
+	loadingTickMsg struct{ gen int }
+	mpvExitedMsg   struct {
+		gen int // the playback it ended; see Model.playGen
+		err error
+		// volume is mpv's last volume, when volumeKnown.
+		volume      float64
+		volumeKnown bool
+	}
+	playlistPosMsg   struct{ pos int }
+	timePosMsg       struct{ pos, dur float64 }
+	playbackStartMsg struct{ at time.Time }
+	fileErrorMsg     struct {
+		pos    int
+		detail string
 	}
-	metadataErrMsg  struct{ err error }
-	mpvExitedMsg    struct{ err error }
-	playlistPosMsg  struct{ pos int }
 	configSavedMsg  struct{ err error }
 	tickMsg         time.Time
+	progressTickMsg time.Time
 	submitMagnetMsg struct{ uri string }
+	urlCopiedMsg    struct{ err error }
+	screenshotMsg   struct{ err error }
+	clipboardMsg    struct {
+		text string
+		err  error
+	}
 )
 
 // shared holds mutable state accessed from both the TUI thread and
@@ -103,10 +106,16 @@ type (
 type shared struct {
 	mu          sync.Mutex
 	server      *stream.Server
-	mpv         *player.MPV
+	mpv         player.Controller
 	client      *torrent.Client
 	playingName string
 	program     *tea.Program // set after program starts, used for Send()
+	playGen     int          // mirrors Model.playGen for cmdStartPlayback
+
+	// Session identity reused by every torrent client this process
+	// creates; see sessionIdentity.
+	peerID     string
+	listenPort int
 }
 
 func (s *shared) setPlayingName(name string) {
@@ -121,81 +130,345 @@ func (s *shared) getPlayingName() string {
 	return s.playingName
 }
 
+// send delivers msg to the Bubble Tea program from a background goroutine.
+// Messages sent before the program is set are dropped.
+func (s *shared) send(msg tea.Msg) {
+	s.mu.Lock()
+	p := s.program
+	s.mu.Unlock()
+	if p != nil {
+		p.Send(msg)
+	}
+}
+
+// sessionIdentity returns the peer ID and listen port for a new torrent
+// client. Private trackers account ratio by peer ID and port, so both stay
+// fixed for the whole session instead of changing with each client. The
+// first call picks a random peer ID under the given BEP 20 prefix; the
+// port is the configured one, or whatever the first client bound.
+//
+// Completion is announced by the torrent library itself (event=completed)
+// once every piece of a torrent is present. Streaming a subset of files
+// never completes the torrent from the tracker's point of view, so no
+// completed event is forced for single files.
+func (s *shared) sessionIdentity(bep20 string, configuredPort int) (string, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.peerID == "" {
+		id := make([]byte, 20)
+		n := copy(id, bep20)
+		if _, err := rand.Read(id[n:]); err != nil {
+			// Leave the ID empty so the client generates its own.
+			return "", configuredPort
+		}
+		s.peerID = string(id)
+	}
+	if configuredPort != 0 {
+		return s.peerID, configuredPort
+	}
+	return s.peerID, s.listenPort
+}
+
+// rememberListenPort records the port the first client bound so later
+// clients in the session reuse it.
+func (s *shared) rememberListenPort(port int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listenPort == 0 {
+		s.listenPort = port
+	}
+}
+
+func (s *shared) getMPV() player.Controller {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.mpv
+}
+
+func (s *shared) getServer() *stream.Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.server
+}
+
+// serverSetup is what the stream server needs from the model, captured
+// so the server can be started from a command.
+type serverSetup struct {
+	opts           stream.ServerOptions
+	memLimit       int64
+	readaheadBytes int64
+	readaheadPct   int
+	// files are all of the session's files, each torrent's in its own
+	// order, so a stream URL keeps naming the same file however the list
+	// is sorted or filtered and as torrents join; see fileIndex.
+	files []*torrent.File
+}
+
+func (m Model) serverSetup() serverSetup {
+	var memLimit int64
+	if m.memStore != nil {
+		memLimit = m.memStore.Limit()
+	}
+	return serverSetup{
+		opts: stream.ServerOptions{
+			TokenBytes:  m.cfg.StreamTokenBytes,
+			ListenAddr:  m.listenAddr(),
+			DLNAHeaders: m.cfg.DLNAHeaders,
+		},
+		memLimit:       memLimit,
+		readaheadBytes: m.cfg.ReadaheadBytes,
+		readaheadPct:   m.cfg.ReadaheadPercent,
+		files:          m.sessionFiles(),
+	}
+}
+
+// ensureServer starts the stream server unless it is already running, and
+// points it at setup's files.
+func (s *shared) ensureServer(setup serverSetup) (*stream.Server, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.server == nil {
+		srv, err := stream.NewServerWithOptions(setup.opts)
+		if err != nil {
+			return nil, err
+		}
+		s.server = srv
+		go srv.Serve()
+		slog.Info("stream server started", "addr", srv.Addr())
+	}
+	s.server.SetMemoryLimit(setup.memLimit)
+	s.server.SetReadahead(setup.readaheadBytes, setup.readaheadPct)
+	s.server.SetFiles(setup.files)
+	return s.server, nil
+}
+
+// failedFile records a playlist entry mpv reported as unplayable.
+type failedFile struct {
+	file   *torrent.File
+	detail string
+}
+
 // --- Model ---
 
 type Model struct {
-	screen   screen
-	width    int
-	height   int
-	quitting bool
-	err      error
+	screen    screen
+	width     int
+	height    int
+	quitting  bool
+	err       error
+	errScroll int // first visible line of a long error
 
 	// Input screen
 	textInput textinput.Model
 
+	inputHint string // shown under the magnet input, e.g. clipboard errors
+	inputErr  error  // why the submitted input can't be fetched
+
+	// History screen
+	history       []config.HistoryEntry
+	historyCursor int
+	historyErr    error
+
 	// Loading screen
-	spinner   spinner.Model
-	magnetURI string
+	spinner      spinner.Model
+	magnetURI    string
+	displayName  string             // magnetURI's dn=, shown until the info arrives
+	fetchWarning string             // why the fetch will likely fail, e.g. no trackers and no DHT
+	cancelFetch  context.CancelFunc // aborts the metadata fetch in flight
+	fetchGen     int                // counts fetches, to drop stale messages
+	// The torrent whose metadata is being fetched, and its swarm as of
+	// the last loadingTickMsg.
+	fetching        *torrent.Torrent
+	fetchPeers      int
+	fetchPeersTotal int
+	fetchStarted    time.Time
+	loadingTicks    int // spinner ticks since the fetch started, for rotating tips
 
 	// File list screen
-	torrent     *torrent.Torrent
-	files       []*torrent.File
-	cursor      int
-	torrentName string
-	streamAll   bool
+	// torrents are every torrent in the session, in the order they
+	// joined; files lists them grouped in that order.
+	torrents  []*torrent.Torrent
+	metainfo  *metainfo.MetaInfo // source metainfo of the first torrent, nil for magnets
+	files     []*torrent.File
+	cursor    int
+	streamAll bool
+	// playlistFirst is the file index of the first playlist entry in
+	// stream-all mode; mpv playlist positions are relative to it.
+	playlistFirst int
+	hasMedia      bool // false when every torrent fell back to all files
+	// confirmingPlay asks before playing a file that isn't media.
+	confirmingPlay bool
+	filesStatus    string // transient note, e.g. after copying a URL
+	// Per-file completion in percent, refreshed on progressTickMsg
+	// rather than on every render.
+	filePct         map[*torrent.File]float64
+	progressTicking bool
+	listedTotal     int // files listed before the filter is applied
+	sortMode        sortMode
+
+	// File list filter (/)
+	filterInput textinput.Model
+	filtering   bool   // filter box is focused
+	filter      string // applied filter, kept after the box closes
 
 	// Playback screen
-	memStore    *memstorage.MemoryStorage
-	currentFile int
-	totalFiles  int
-	startTime   time.Time
+	memStore       *memstorage.MemoryStorage // nil when storing on disk
+	store          storage.ClientImpl
+	currentFile    int
+	totalFiles     int
+	startTime      time.Time
+	firstFrame     time.Duration // file-select to first rendered frame; 0 until known
+	failed         []failedFile  // files mpv could not play this session
+	playPos        float64       // playback position in seconds, from mpv
+	playDur        float64       // duration of the playing file in seconds
+	confirmingQuit bool          // q pressed while seeding; waiting for y/n
+	playStatus     string        // transient note, e.g. after a screenshot
+	playGen        int           // counts playbacks, to drop a stopped one's messages
+	// Peers screen (p)
+	peers      []peerRow
+	peerScroll int
+	// Track picker (t)
+	tracks       []player.Track // nil until loaded
+	trackCursor  int
+	tracksErr    error
+	tracksStatus string
+
+	// Torrents joining the session, still fetching metadata
+	queue      []*torrent.Torrent
+	queueInput textinput.Model // magnet overlay on the playback screen
+	queueing   bool            // overlay is open
+	queueErr   error
 
 	// Shared mutable state for background goroutines
 	shared *shared
 
 	// Magnet passed as CLI arg
 	initialMagnet string
+	pendingQueue  []string // command-line magnets not yet queued
+
+	// Player chosen with --player; empty uses the config
+	playerOverride string
+	listenOverride string
+
+	// Rate limits from --max-up/--max-down; nil uses the config
+	maxUpOverride, maxDownOverride *int64
 
 	// Proxy URL string (socks5://host:port or http://host:port)
 	proxyURL string
 
+	// Transfer rates, sampled every tick
+	bytesRead    int64     // torrent payload downloaded at the last sample
+	bytesWritten int64     // torrent payload uploaded at the last sample
+	rateSampleAt time.Time // when the byte counters were sampled
+	downRate     float64   // bytes/s
+	upRate       float64   // bytes/s
+	smoothDown   float64   // downRate averaged over several ticks, for ETAs
+
+	// Debug diagnostics
+	debug          bool
+	piecesDone     int       // completed pieces at the last sample
+	piecesSampleAt time.Time // when piecesDone was sampled
+	piecesPerSec   float64
+
+	// Stall watchdog
+	stallPieces int       // current file's complete pieces at the last change
+	stallSince  time.Time // when stallPieces last changed
+	stallKicks  int       // recoveries attempted this playback
+	// Stall warning, independent of the watchdog being enabled
+	warnPieces int // current file's complete pieces at the last tick
+	stallTicks int // consecutive ticks without a new piece
+
 	// Config
 	cfg          *config.Config
-	configInput  textinput.Model // text input for mpv path on config screen
-	prevScreen   screen          // screen to return to after config
-	configStatus string          // transient status message on config screen
+	styles       styles         // built from cfg's theme
+	keymap       keymap         // built from cfg's key bindings
+	configInputs []settingInput // one input per settingFields entry
+	configFocus  int
+	prevScreen   screen // screen to return to after config
+	configStatus string // transient status message on config screen
 }
 
-func NewModel(memStore *memstorage.MemoryStorage, magnetURI string, proxyURL string, cfg *config.Config) Model {
+// Options configures a new Model from command-line and config state.
+type Options struct {
+	// MemStore is the RAM-backed piece storage used by the torrent client.
+	MemStore *memstorage.MemoryStorage
+	// Storage replaces MemStore with another backend, e.g. disk storage.
+	Storage storage.ClientImpl
+	// Magnet skips the input screen when non-empty. It may also be the
+	// path of a .torrent file.
+	Magnet string
+	// Queue holds more magnets or .torrent paths to add to the session,
+	// one at a time, once Magnet's is up; their files are listed after
+	// Magnet's. If Magnet fails, the next one takes its place.
+	Queue []string
+	// ProxyURL routes torrent traffic (socks5://host:port or http://host:port).
+	ProxyURL string
+	// Config holds the persisted user settings.
+	Config *config.Config
+	// Debug shows extra diagnostics on the playback screen.
+	Debug bool
+	// Player overrides Config.Player for this session.
+	Player string
+	// ListenAddr overrides Config.StreamListenAddr for this session.
+	ListenAddr string
+	// MaxUploadRate and MaxDownloadRate override the configured limits
+	// (bytes/s, 0 unlimited) for this session when non-nil.
+	MaxUploadRate, MaxDownloadRate *int64
+}
+
+func NewModel(opts Options) Model {
 	ti := textinput.New()
 	ti.Placeholder = "magnet:?xt=urn:btih:..."
 	ti.CharLimit = 4096
 	ti.Width = 80
 	ti.Focus()
 
-	ci := textinput.New()
-	ci.Placeholder = "/usr/bin/mpv"
-	ci.CharLimit = 512
-	ci.Width = 60
+	fi := textinput.New()
+	fi.Placeholder = "filter files"
+	fi.CharLimit = 256
+	fi.Width = 40
 
-	s := spinner.New()
-	s.Spinner = spinner.Dot
-	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF6AC1"))
+	qi := textinput.New()
+	qi.Placeholder = "magnet:?xt=urn:btih:..."
+	qi.CharLimit = 4096
+	qi.Width = 60
 
+	cfg := opts.Config
 	if cfg == nil {
 		cfg = &config.Config{}
 	}
+	st := newStyles(cfg)
+
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	s.Style = st.spinner
+
+	store := opts.Storage
+	if store == nil {
+		store = opts.MemStore
+	}
 
 	return Model{
-		screen:        screenInput,
-		textInput:     ti,
-		configInput:   ci,
-		spinner:       s,
-		memStore:      memStore,
-		initialMagnet: magnetURI,
-		proxyURL:      proxyURL,
-		cfg:           cfg,
-		shared:        &shared{},
+		screen:          screenInput,
+		styles:          st,
+		keymap:          newKeymap(cfg.Keybindings),
+		textInput:       ti,
+		configInputs:    newSettingInputs(),
+		queueInput:      qi,
+		filterInput:     fi,
+		spinner:         s,
+		memStore:        opts.MemStore,
+		store:           store,
+		initialMagnet:   opts.Magnet,
+		pendingQueue:    opts.Queue,
+		playerOverride:  opts.Player,
+		listenOverride:  opts.ListenAddr,
+		maxUpOverride:   opts.MaxUploadRate,
+		maxDownOverride: opts.MaxDownloadRate,
+		proxyURL:        opts.ProxyURL,
+		debug:           opts.Debug,
+		cfg:             cfg,
+		shared:          &shared{},
 	}
 }
 
@@ -227,18 +500,27 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		if msg.String() == "ctrl+c" {
 			m.quitting = true
-			m.cleanup()
+			m.gracefulShutdown()
 			return m, tea.Quit
 		}
-		// ctrl+s opens config from any screen except config itself.
-		if msg.String() == "ctrl+s" && m.screen != screenConfig {
+		// ctrl+s opens config from any screen except config itself. A
+		// plain key bound to it must not fire while typing.
+		typing := m.screen == screenInput || m.filtering || m.queueing
+		if m.keys().canonical(msg.String(), actionConfig) == "ctrl+s" && m.screen != screenConfig &&
+			!(typing && msg.Type == tea.KeyRunes) {
 			m.prevScreen = m.screen
 			m.screen = screenConfig
 			m.configStatus = ""
-			m.configInput.SetValue(m.cfg.MpvPath)
-			m.configInput.Focus()
+			m.loadSettings()
 			return m, textinput.Blink
 		}
+	case queueAddedMsg, queueReadyMsg, queueErrMsg:
+		return m.updateQueue(msg)
+	case progressTickMsg:
+		if m.screen == screenFiles {
+			m.refreshFileProgress()
+		}
+		return m, cmdProgressTick()
 	}
 
 	switch m.screen {
@@ -252,6 +534,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.updatePlaying(msg)
 	case screenConfig:
 		return m.updateConfig(msg)
+	case screenHistory:
+		return m.updateHistory(msg)
+	case screenPeers:
+		return m.updatePeers(msg)
+	case screenTracks:
+		return m.updateTracks(msg)
 	}
 	return m, nil
 }
@@ -272,6 +560,12 @@ func (m Model) View() string {
 		content = m.viewPlaying()
 	case screenConfig:
 		content = m.viewConfig()
+	case screenHistory:
+		content = m.viewHistory()
+	case screenPeers:
+		content = m.viewPeers()
+	case screenTracks:
+		content = m.viewTracks()
 	}
 	return content + "\n"
 }
@@ -283,40 +577,84 @@ func (m Model) View() string {
 func (m Model) updateInput(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case submitMagnetMsg:
-		m.magnetURI = msg.uri
-		m.screen = screenLoading
-		return m, tea.Batch(m.spinner.Tick, m.cmdFetchMetadata())
+		return m.submitInput(msg.uri)
 	case tea.KeyMsg:
+		// An error about the last submission goes once the input changes.
+		m.inputErr = nil
 		switch msg.String() {
 		case "enter":
 			uri := strings.TrimSpace(m.textInput.Value())
 			if uri == "" {
 				return m, nil
 			}
-			m.magnetURI = uri
-			m.screen = screenLoading
-			return m, tea.Batch(m.spinner.Tick, m.cmdFetchMetadata())
+			return m.submitInput(uri)
+		case "ctrl+r":
+			return m.openHistory()
+		case "ctrl+v":
+			// Read the clipboard directly: terminals often chunk long
+			// pastes, which mangles magnets in the text input.
+			return m, cmdReadClipboard
 		case "esc":
 			m.quitting = true
 			return m, tea.Quit
 		}
+	case clipboardMsg:
+		text := strings.TrimSpace(msg.text)
+		if msg.err != nil || text == "" {
+			m.inputHint = "Clipboard not available here (headless or SSH?); paste with your terminal instead."
+			return m, nil
+		}
+		m.inputHint = ""
+		m.textInput.SetValue(text)
+		m.textInput.CursorEnd()
+		if looksLikeMagnet(text) {
+			return m.submitInput(text)
+		}
+		return m, nil
 	}
 	var cmd tea.Cmd
 	m.textInput, cmd = m.textInput.Update(msg)
 	return m, cmd
 }
 
+// submitInput fetches uri, or keeps the input screen open with the reason
+// it can't be fetched.
+func (m Model) submitInput(uri string) (tea.Model, tea.Cmd) {
+	m.inputErr = nil
+	if !IsTorrentFile(uri) {
+		if err := validateMagnet(uri); err != nil {
+			slog.Info("rejected input", "uri", uri, "err", err)
+			m.inputErr = err
+			m.screen = screenInput
+			m.textInput.SetValue(uri)
+			m.textInput.CursorEnd()
+			m.textInput.Focus()
+			return m, textinput.Blink
+		}
+	}
+	m.magnetURI = uri
+	return m.startFetch()
+}
+
 func (m Model) viewInput() string {
 	var b strings.Builder
-	b.WriteString(titleStyle.Render("just-stream"))
+	b.WriteString(m.styles.title.Render("just-stream"))
 	b.WriteString("\n")
-	b.WriteString(subtitleStyle.Render("Torrent streaming to mpv with Anime4K"))
+	b.WriteString(m.styles.subtitle.Render("Torrent streaming to mpv with Anime4K"))
 	b.WriteString("\n\n")
-	b.WriteString(normalStyle.Render("Paste a magnet link:"))
+	b.WriteString(m.styles.normal.Render("Paste a magnet link or .torrent file path:"))
 	b.WriteString("\n\n")
 	b.WriteString(m.textInput.View())
 	b.WriteString("\n\n")
-	b.WriteString(helpStyle.Render("enter: submit  ctrl+s: config  esc/ctrl+c: quit"))
+	if m.inputErr != nil {
+		b.WriteString(m.styles.error.Render("Error: " + m.inputErr.Error()))
+		b.WriteString("\n\n")
+	}
+	if m.inputHint != "" {
+		b.WriteString(m.styles.dim.Render(m.inputHint))
+		b.WriteString("\n\n")
+	}
+	b.WriteString(m.styles.help.Render("enter: submit  ctrl+v: paste  ctrl+r: history  ctrl+s: config  esc/ctrl+c: quit"))
 	return b.String()
 }
 
@@ -324,46 +662,304 @@ func (m Model) viewInput() string {
 // Loading Screen
 // ──────────────────────────────────────────────
 
+func cmdReadClipboard() tea.Msg {
+	text, err := clipboard.ReadAll()
+	return clipboardMsg{text: text, err: err}
+}
+
+// looksLikeMagnet reports whether s is a BitTorrent magnet link worth
+// submitting without a further keypress.
+func looksLikeMagnet(s string) bool {
+	return strings.HasPrefix(strings.ToLower(s), "magnet:?") &&
+		strings.Contains(strings.ToLower(s), "xt=urn:btih:")
+}
+
+// validateMagnet checks that uri is a magnet link with a well-formed
+// BitTorrent info hash: 40 hex or 32 base32 characters after
+// xt=urn:btih:, or a v2 xt=urn:btmh: hash.
+func validateMagnet(uri string) error {
+	if strings.EqualFold(filepath.Ext(uri), ".torrent") {
+		return fmt.Errorf("%s: no such .torrent file", uri)
+	}
+	u, err := url.Parse(uri)
+	if err != nil || !strings.EqualFold(u.Scheme, "magnet") {
+		return errors.New("not a magnet link (magnet:?xt=urn:btih:...) or .torrent file")
+	}
+	for _, xt := range u.Query()["xt"] {
+		switch lower := strings.ToLower(xt); {
+		case strings.HasPrefix(lower, "urn:btmh:"):
+			return nil
+		case strings.HasPrefix(lower, "urn:btih:"):
+			return checkInfoHash(xt[len("urn:btih:"):])
+		}
+	}
+	return errors.New("magnet link has no xt=urn:btih: info hash")
+}
+
+// checkInfoHash reports whether hash is a v1 info hash in either of the
+// encodings magnet links use.
+func checkInfoHash(hash string) error {
+	switch len(hash) {
+	case 40:
+		if _, err := hex.DecodeString(hash); err != nil {
+			return fmt.Errorf("info hash %q is not hexadecimal", hash)
+		}
+	case 32:
+		if _, err := base32.StdEncoding.DecodeString(strings.ToUpper(hash)); err != nil {
+			return fmt.Errorf("info hash %q is not base32", hash)
+		}
+	default:
+		return fmt.Errorf("info hash is %d characters long; want 40 (hex) or 32 (base32)", len(hash))
+	}
+	return nil
+}
+
+// magnetHasTrackers reports whether a magnet link names any tr= trackers.
+// Magnets without them rely on DHT alone (BEP 9).
+func magnetHasTrackers(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	for _, tr := range u.Query()["tr"] {
+		if strings.TrimSpace(tr) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// dhtDisabled reports whether torrents are fetched without DHT: behind a
+// SOCKS5 proxy, unless DHT is relayed through it. Otherwise the client
+// runs DHT bootstrapped from the library's global router list.
+func (m Model) dhtDisabled() bool {
+	if m.proxyURL == "" || m.cfg.ProxyUDP {
+		return false
+	}
+	u, err := url.Parse(m.proxyURL)
+	return err == nil && (u.Scheme == "socks5" || u.Scheme == "socks5h")
+}
+
+// parseMagnetDisplayName returns the dn= display name of a magnet link,
+// decoded, or "" when uri is not a magnet or carries none.
+func parseMagnetDisplayName(uri string) string {
+	if !strings.HasPrefix(strings.ToLower(uri), "magnet:") {
+		return ""
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(u.Query().Get("dn"))
+}
+
+// fetchLabel names the torrent being fetched in messages: the magnet's
+// display name when it has one, else the URI.
+func (m Model) fetchLabel() string {
+	if m.displayName != "" {
+		return m.displayName
+	}
+	return m.magnetURI
+}
+
+// startFetch switches to the loading screen and fetches m.magnetURI.
+func (m Model) startFetch() (tea.Model, tea.Cmd) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancelFetch = cancel
+	m.fetchGen++
+	m.fetching = nil
+	m.fetchPeers, m.fetchPeersTotal = 0, 0
+	m.fetchStarted = time.Now()
+	m.loadingTicks = 0
+	m.err = nil
+	m.inputHint = ""
+	m.displayName = parseMagnetDisplayName(m.magnetURI)
+	m.fetchWarning = ""
+	if !IsTorrentFile(m.magnetURI) && !magnetHasTrackers(m.magnetURI) && len(extraTrackerTiers(m.cfg)) == 0 && m.dhtDisabled() {
+		m.fetchWarning = "This magnet has no trackers and DHT is off behind the SOCKS5 proxy, so no peers are likely to be found. Add trackers in the settings (extra trackers, or use the default trackers), or turn on relaying DHT through the proxy."
+		slog.Warn("trackerless magnet without DHT", "uri", m.magnetURI)
+	}
+	slog.Info("fetching metadata", "uri", m.magnetURI, "name", m.displayName)
+	m.screen = screenLoading
+	return m, tea.Batch(m.spinner.Tick, m.cmdFetchMetadata(ctx))
+}
+
+// abortFetch cancels the metadata fetch and returns to the input screen
+// with the magnet ready to edit.
+func (m Model) abortFetch() (tea.Model, tea.Cmd) {
+	if m.cancelFetch != nil {
+		m.cancelFetch()
+		m.cancelFetch = nil
+	}
+	m.err = nil
+	m.screen = screenInput
+	m.textInput.SetValue(m.magnetURI)
+	m.textInput.CursorEnd()
+	m.textInput.Focus()
+	return m, textinput.Blink
+}
+
 func (m Model) updateLoading(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case metadataReadyMsg:
+		slog.Info("metadata ready", "name", msg.t.Name(), "infohash", msg.t.InfoHash().HexString(), "files", len(msg.t.Files()))
 		m.shared.mu.Lock()
 		m.shared.client = msg.client
 		m.shared.mu.Unlock()
 
-		m.torrent = msg.t
-		m.torrentName = msg.t.Name()
-		m.files = filterMediaFiles(msg.t.Files())
-		if len(m.files) == 0 {
-			m.files = msg.t.Files()
-		}
-		sortFilesByName(m.files)
+		m.fetching = nil
+		m.torrents = []*torrent.Torrent{msg.t}
+		m.metainfo = msg.mi
+		m.cursor = 0
+		m.filter = ""
+		m.rebuildFileList()
+		m.restoreLastFile(msg.t)
 		m.screen = screenFiles
-		return m, nil
+		m.refreshFileProgress()
+		cmds := []tea.Cmd{m.cmdRecordHistory(m.magnetURI, msg.t)}
+		// The client now exists, so the rest of the command line can
+		// join the session, one torrent after another.
+		cmds = append(cmds, m.queueNextArg())
+		if !m.progressTicking {
+			m.progressTicking = true
+			cmds = append(cmds, cmdProgressTick())
+		}
+		if m.cfg.AutoplaySingleFile && m.hasMedia && len(m.files) == 1 {
+			next, play := m.beginPlayback(0, false)
+			return next, tea.Batch(append(cmds, play)...)
+		}
+		return m, tea.Batch(cmds...)
+	case metadataStartedMsg:
+		if msg.gen != m.fetchGen {
+			return m, nil
+		}
+		m.fetching = msg.t
+		m.sampleFetchPeers()
+		return m, cmdLoadingTick(msg.gen)
+	case loadingTickMsg:
+		if msg.gen != m.fetchGen || m.fetching == nil || m.err != nil {
+			return m, nil
+		}
+		m.sampleFetchPeers()
+		return m, cmdLoadingTick(msg.gen)
 	case metadataErrMsg:
+		m.fetching = nil
+		slog.Warn("metadata fetch failed", "uri", m.magnetURI, "err", msg.err)
+		if len(m.pendingQueue) > 0 {
+			// One dead magnet must not sink the rest of the command
+			// line: report it and carry on with the next.
+			m.queueErr = fmt.Errorf("%s: %w", m.fetchLabel(), msg.err)
+			m.magnetURI = m.pendingQueue[0]
+			m.pendingQueue = m.pendingQueue[1:]
+			return m.startFetch()
+		}
 		m.err = msg.err
+		if m.displayName != "" {
+			m.err = fmt.Errorf("%s: %w", m.displayName, msg.err)
+		}
+		m.errScroll = 0
+		return m, nil
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			return m.abortFetch()
+		case "r":
+			// Tracker and DHT failures are often transient; try the
+			// same magnet again without retyping it.
+			if m.err != nil {
+				return m.startFetch()
+			}
+		}
+		m.scrollError(msg)
 		return m, nil
 	case spinner.TickMsg:
 		var cmd tea.Cmd
 		m.spinner, cmd = m.spinner.Update(msg)
+		m.loadingTicks++
 		return m, cmd
 	}
 	return m, nil
 }
 
+// loadingTips rotate under the peer count while metadata is fetched.
+var loadingTips = []string{
+	"Tip: press esc to cancel and edit the magnet",
+	"Tip: slow to start? Add trackers in the settings (ctrl+s on the input screen)",
+	"Tip: raise the metadata timeout in the settings for quiet swarms",
+	"Tip: ctrl+r on the input screen reopens a recent torrent",
+}
+
+// tipInterval is how long each loading tip stays up.
+const tipInterval = 6 * time.Second
+
+// loadingTip returns the tip to show now, counted in spinner ticks.
+func (m Model) loadingTip() string {
+	perTip := max(int(tipInterval/m.spinner.Spinner.FPS), 1)
+	return loadingTips[m.loadingTicks/perTip%len(loadingTips)]
+}
+
+// loadingTickInterval paces the loading screen's peer count.
+const loadingTickInterval = time.Second
+
+func cmdLoadingTick(gen int) tea.Cmd {
+	return tea.Tick(loadingTickInterval, func(time.Time) tea.Msg {
+		return loadingTickMsg{gen: gen}
+	})
+}
+
+// sampleFetchPeers reads the swarm size of the torrent being fetched.
+func (m *Model) sampleFetchPeers() {
+	stats := m.fetching.Stats()
+	m.fetchPeers, m.fetchPeersTotal = stats.ActivePeers, stats.TotalPeers
+}
+
+// fetchElapsed reports how long the metadata fetch has run, out of the
+// timeout when there is one.
+func (m Model) fetchElapsed() string {
+	elapsed := time.Since(m.fetchStarted).Truncate(time.Second)
+	if timeout := metadataTimeout(m.cfg); timeout > 0 {
+		return fmt.Sprintf("%s / %s", elapsed, timeout)
+	}
+	return elapsed.String()
+}
+
 func (m Model) viewLoading() string {
 	var b strings.Builder
-	b.WriteString(titleStyle.Render("just-stream"))
+	b.WriteString(m.styles.title.Render("just-stream"))
 	b.WriteString("\n\n")
 	if m.err != nil {
-		b.WriteString(errorStyle.Render(fmt.Sprintf("Error: %v", m.err)))
-		b.WriteString("\n\n")
-		b.WriteString(helpStyle.Render("ctrl+c: quit"))
+		b.WriteString(m.viewError(m.err))
+		b.WriteString("\n")
+		b.WriteString(m.styles.help.Render("r: retry  esc: edit magnet  ctrl+c: quit"))
 	} else {
 		b.WriteString(m.spinner.View())
-		b.WriteString(statusStyle.Render(" Fetching torrent metadata..."))
+		b.WriteString(m.styles.status.Render(" Fetching torrent metadata..."))
+		b.WriteString("\n\n")
+		if m.displayName != "" {
+			b.WriteString(m.styles.header.Render(m.displayName))
+			b.WriteString("\n\n")
+		}
+		if m.fetchWarning != "" {
+			b.WriteString(m.styles.warn.Render(m.fetchWarning))
+			b.WriteString("\n\n")
+		}
+		// The torrent client doesn't expose how much of the info
+		// dictionary has arrived, so the swarm and the time spent are the
+		// best measure of progress.
+		switch {
+		case m.fetching == nil:
+			b.WriteString(m.styles.dim.Render("Connecting to peers and downloading info"))
+		case m.fetchPeers > 0:
+			b.WriteString(m.styles.normal.Render(fmt.Sprintf("Peers: %d active / %d total — requesting metadata… %s", m.fetchPeers, m.fetchPeersTotal, m.fetchElapsed())))
+		default:
+			b.WriteString(m.styles.dim.Render(fmt.Sprintf("Peers: 0 active / %d total — searching for peers… %s", m.fetchPeersTotal, m.fetchElapsed())))
+		}
 		b.WriteString("\n\n")
-		b.WriteString(dimStyle.Render("Connecting to peers and downloading info"))
+		if m.cfg.ShowsTips() {
+			b.WriteString(m.styles.dim.Render(m.loadingTip()))
+			b.WriteString("\n\n")
+		}
+		b.WriteString(m.styles.help.Render("esc: cancel  ctrl+c: quit"))
 	}
 	return b.String()
 }
@@ -373,8 +969,33 @@ func (m Model) viewLoading() string {
 // ──────────────────────────────────────────────
 
 func (m Model) updateFiles(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(urlCopiedMsg); ok {
+		if msg.err != nil {
+			m.filesStatus = fmt.Sprintf("Could not copy stream URL: %v", msg.err)
+		} else {
+			m.filesStatus = "Stream URL copied"
+		}
+		return m, nil
+	}
 	if km, ok := msg.(tea.KeyMsg); ok {
-		switch km.String() {
+		// The copy status lasts until the next keypress.
+		m.filesStatus = ""
+		if m.filtering {
+			return m.updateFilter(km)
+		}
+		if m.confirmingPlay {
+			m.confirmingPlay = false
+			if km.String() == "y" {
+				return m.beginPlayback(m.cursor, false)
+			}
+			return m, nil
+		}
+		if m.scrollError(km) {
+			return m, nil
+		}
+		switch key := m.keys().canonical(km.String(), fileActions...); key {
+		case "/":
+			return m.openFilter()
 		case "j", "down":
 			if m.cursor < len(m.files)-1 {
 				m.cursor++
@@ -387,15 +1008,50 @@ func (m Model) updateFiles(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.cursor = 0
 		case "G", "end":
 			m.cursor = len(m.files) - 1
-			case "enter":
-				m.err = nil // Clear previous error
-				return m.beginPlayback(m.cursor, false)
-			case "a":
-				m.err = nil // Clear previous error
+		case "r":
+			m.rebuildFileList()
+		case "s":
+			m.sortMode = (m.sortMode + 1) % numSortModes
+			m.rebuildFileList()
+		case "n":
+			m.cursorToNextTorrent()
+		case "enter":
+			if len(m.files) == 0 {
+				return m, nil
+			}
+			m.err = nil // Clear previous error
+			return m.playSelected()
+		case "a", "A":
+			if len(m.files) == 0 {
+				return m, nil
+			}
+			if !m.hasMedia {
+				// A playlist of archives and images would only fail
+				// file by file; single files can still be forced.
+				m.filesStatus = "Nothing to stream all: no playable media in this torrent"
+				return m, nil
+			}
+			m.err = nil // Clear previous error
+			if key == "a" {
 				return m.beginPlayback(0, true)
-		case "q", "esc":
+			}
+			return m.beginPlaylistFrom(m.cursor)
+		case "y":
+			if len(m.files) == 0 {
+				return m, nil
+			}
+			return m, m.cmdCopyStreamURL(m.cursor)
+		case "esc":
+			if m.filter != "" {
+				m.setFilter("")
+				return m, nil
+			}
 			m.quitting = true
-			m.cleanup()
+			m.gracefulShutdown()
+			return m, tea.Quit
+		case "q":
+			m.quitting = true
+			m.gracefulShutdown()
 			return m, tea.Quit
 		}
 	}
@@ -404,22 +1060,47 @@ func (m Model) updateFiles(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 func (m Model) viewFiles() string {
 	var b strings.Builder
-	b.WriteString(titleStyle.Render("just-stream"))
+	b.WriteString(m.styles.title.Render("just-stream"))
 	b.WriteString("\n")
-	b.WriteString(headerStyle.Render(m.torrentName))
+	b.WriteString(m.styles.header.Render(m.sessionName()))
 	b.WriteString("\n")
-	b.WriteString(dimStyle.Render(fmt.Sprintf("%d episodes found", len(m.files))))
-	b.WriteString("\n\n")
+	if m.hasMedia {
+		b.WriteString(m.styles.dim.Render(fmt.Sprintf("%d episodes found", len(m.files))))
+	} else {
+		b.WriteString(m.styles.warn.Render("No playable media found — showing all files"))
+	}
+	b.WriteString("\n")
+	b.WriteString(m.viewTorrentInfo())
+	b.WriteString("\n")
+	b.WriteString(m.viewFilter())
 
 	if m.err != nil {
-		b.WriteString(errorStyle.Render(fmt.Sprintf("Error: %v", m.err)))
-		b.WriteString("\n\n")
+		b.WriteString(m.viewError(m.err))
+		b.WriteString("\n")
+	}
+
+	if len(m.failed) > 0 {
+		b.WriteString(m.styles.seeding.Render(fmt.Sprintf("%d file(s) failed to play:", len(m.failed))))
+		b.WriteString("\n")
+		for _, f := range m.failed {
+			line := "    " + shortName(f.file.DisplayPath())
+			if f.detail != "" {
+				line += " (" + f.detail + ")"
+			}
+			b.WriteString(m.styles.dim.Render(line))
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
 	}
 
 	visible := m.height - 10
 	if visible < 5 {
 		visible = 20
 	}
+	// Leave room for the torrent headings.
+	if len(m.torrents) > 1 {
+		visible = max(visible-len(m.torrents), 5)
+	}
 
 	startIdx := 0
 	if m.cursor >= visible {
@@ -435,79 +1116,387 @@ func (m Model) viewFiles() string {
 		name := shortName(f.DisplayPath())
 		size := humanSize(f.Length())
 
-		if i == m.cursor {
-			b.WriteString(selectedStyle.Render(fmt.Sprintf("  > [%02d] %s  %s", i+1, name, size)))
-		} else {
+		// With several torrents, each group of files is headed by its
+		// torrent's name.
+		if len(m.torrents) > 1 && (i == startIdx || f.Torrent() != m.files[i-1].Torrent()) {
+			b.WriteString(m.styles.header.Render("  " + f.Torrent().Name()))
+			b.WriteString("\n")
+		}
+
+		// In the all-files fallback, tag entries that aren't worth
+		// opening in mpv (.nfo, images, archives). Listed media files
+		// always look playable.
+		nonMedia := !m.looksPlayable(f.DisplayPath())
+		tag := ""
+		if nonMedia {
+			tag = "  [non-media]"
+		}
+		// Show what is already cached, e.g. from an earlier play.
+		if pct := m.filePct[f]; pct > 0 {
+			size += fmt.Sprintf("  %.0f%%", pct)
+		}
+
+		switch {
+		case i == m.cursor:
+			b.WriteString(m.styles.selected.Render(fmt.Sprintf("  > [%02d] %s  %s", i+1, name, size)))
+			b.WriteString(m.styles.dim.Render(tag))
+		case nonMedia:
+			b.WriteString(m.styles.dim.Render(fmt.Sprintf("    [%02d] %s  %s%s", i+1, name, size, tag)))
+		default:
 			line := fmt.Sprintf("    [%02d] %s", i+1, name)
-			b.WriteString(normalStyle.Render(line))
-			b.WriteString(dimStyle.Render(fmt.Sprintf("  %s", size)))
+			b.WriteString(m.styles.normal.Render(line))
+			b.WriteString(m.styles.dim.Render(fmt.Sprintf("  %s", size)))
 		}
 		b.WriteString("\n")
 	}
 
 	if startIdx > 0 {
-		b.WriteString(dimStyle.Render(fmt.Sprintf("    ... %d more above", startIdx)))
+		b.WriteString(m.styles.dim.Render(fmt.Sprintf("    ... %d more above", startIdx)))
 		b.WriteString("\n")
 	}
 	if endIdx < len(m.files) {
-		b.WriteString(dimStyle.Render(fmt.Sprintf("    ... %d more below", len(m.files)-endIdx)))
+		b.WriteString(m.styles.dim.Render(fmt.Sprintf("    ... %d more below", len(m.files)-endIdx)))
+		b.WriteString("\n")
+	}
+
+	if m.cursor < len(m.files) && !m.looksPlayable(m.files[m.cursor].DisplayPath()) {
+		name := shortName(m.files[m.cursor].DisplayPath())
+		b.WriteString("\n")
+		if m.confirmingPlay {
+			b.WriteString(m.styles.warn.Render(fmt.Sprintf("  %s doesn't look like a media file. Play anyway? (y/n)", name)))
+		} else {
+			b.WriteString(m.styles.dim.Render("  Selected file doesn't look like a media file"))
+		}
+		b.WriteString("\n")
+	}
+
+	if m.filesStatus != "" {
+		b.WriteString("\n")
+		b.WriteString(m.styles.status.Render("  " + m.filesStatus))
 		b.WriteString("\n")
 	}
 
+	b.WriteString(m.viewQueue())
+
 	b.WriteString("\n")
-	b.WriteString(helpStyle.Render("j/k: navigate  enter: play  a: stream all  ctrl+s: config  q: quit"))
+	k := m.keys()
+	streamAll := helpLine(k.help("stream all", actionStreamAll), "A: stream from here")
+	if !m.hasMedia {
+		streamAll = ""
+	}
+	help := helpLine(
+		k.help("navigate", actionDown, actionUp),
+		k.help("play", actionPlay),
+		streamAll,
+		"y: copy URL  /: filter",
+		fmt.Sprintf("s: sort (%s)  r: refresh", m.sortMode),
+		k.help("config", actionConfig),
+		k.help("quit", actionQuit),
+	)
+	if m.filtering {
+		help = "type to filter  ↑/↓: navigate  enter: play  esc: clear filter"
+	} else if m.filter != "" {
+		help = helpLine(
+			k.help("navigate", actionDown, actionUp),
+			k.help("play", actionPlay),
+			k.help("stream matches", actionStreamAll),
+			"/: edit filter  esc: clear filter",
+			k.help("quit", actionQuit),
+		)
+	}
+	if len(m.torrents) > 1 {
+		help += "  n: next torrent"
+	}
+	b.WriteString(m.styles.help.Render(help))
 	return b.String()
 }
 
-// ──────────────────────────────────────────────
-// Playback Screen
-// ──────────────────────────────────────────────
+// cmdScreenshot asks mpv to save the current frame. mpv's reply can take
+// a moment while it encodes the image, so it is awaited off the UI.
+func (m Model) cmdScreenshot() tea.Cmd {
+	mpv := m.shared.getMPV()
+	if mpv == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		return screenshotMsg{err: mpv.Screenshot()}
+	}
+}
 
-func (m Model) updatePlaying(msg tea.Msg) (tea.Model, tea.Cmd) {
-	switch msg := msg.(type) {
-	case playlistPosMsg:
-		newPos := msg.pos
-		if newPos < 0 || newPos >= len(m.files) {
-			return m, nil
+// cmdCopyStreamURL starts the stream server if it isn't running and
+// copies the URL of list entry fileIdx, token included, for use in
+// another app. The server stays up until the next playback ends.
+func (m Model) cmdCopyStreamURL(fileIdx int) tea.Cmd {
+	sh := m.shared
+	setup := m.serverSetup()
+	idx := fileIndex(setup.files, m.files[fileIdx])
+	return func() tea.Msg {
+		srv, err := sh.ensureServer(setup)
+		if err != nil {
+			return urlCopiedMsg{err: err}
 		}
+		return urlCopiedMsg{err: clipboard.WriteAll(srv.FileURL(idx))}
+	}
+}
 
-		// Free RAM for old episode if moving forward.
-		oldPos := m.currentFile
-		if newPos > oldPos {
-			for i := oldPos; i < newPos; i++ {
-				m.freeEpisodeRAM(i)
-			}
+// playSelected plays the file under the cursor, first asking for
+// confirmation when it doesn't look like something mpv can play.
+func (m Model) playSelected() (tea.Model, tea.Cmd) {
+	if !m.looksPlayable(m.files[m.cursor].DisplayPath()) {
+		m.confirmingPlay = true
+		return m, nil
+	}
+	if m.cfg.AutoplayAll && m.hasMedia && len(m.files) > 1 {
+		return m.beginPlaylistFrom(m.cursor)
+	}
+	return m.beginPlayback(m.cursor, false)
+}
+
+// viewTorrentInfo renders the source metainfo's comment and creation
+// fields, when the torrent came from a file that has them.
+func (m Model) viewTorrentInfo() string {
+	if m.metainfo == nil {
+		return ""
+	}
+	var b strings.Builder
+	if c := strings.TrimSpace(m.metainfo.Comment); c != "" {
+		b.WriteString(m.styles.dim.Render("Comment:    " + c))
+		b.WriteString("\n")
+	}
+	if c := strings.TrimSpace(m.metainfo.CreatedBy); c != "" {
+		b.WriteString(m.styles.dim.Render("Created by: " + c))
+		b.WriteString("\n")
+	}
+	if m.metainfo.CreationDate > 0 {
+		created := time.Unix(m.metainfo.CreationDate, 0).Format("2006-01-02 15:04")
+		b.WriteString(m.styles.dim.Render("Created:    " + created))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// ──────────────────────────────────────────────
+// Playback Screen
+// ──────────────────────────────────────────────
+
+func (m Model) updatePlaying(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case playlistPosMsg:
+		newPos := msg.pos
+		if newPos < 0 || newPos >= len(m.files) {
+			return m, nil
 		}
 
+		oldPos := m.currentFile
 		m.currentFile = newPos
 		m.shared.setPlayingName(shortName(m.files[newPos].DisplayPath()))
 
-		// Update priorities: boost new file, deprioritize others.
+		// Update priorities: boost new file, deprioritize others. This
+		// protects the new file and the preloaded ones first, so freeing
+		// the old episode can't take pieces they share with it.
 		m.setPriorities(newPos)
+		m.resetStall()
 
-		return m, nil
-
-case mpvExitedMsg:
-			// mpv exited (user quit or playlist ended). Return to file list.
-			if msg.err != nil {
-				m.err = fmt.Errorf("mpv failed to start: %w", msg.err)
-			}
-			m.cleanupPlayback()
-			m.screen = screenFiles
-			if m.currentFile < len(m.files) {
-				m.cursor = m.currentFile
+		// Free RAM for old episode if moving forward.
+		if newPos > oldPos {
+			for i := oldPos; i < newPos; i++ {
+				m.freeEpisodeRAM(i)
 			}
+		}
+
+		return m, m.cmdSaveLastFile(newPos)
+
+	case mpvExitedMsg:
+		if msg.gen != m.playGen {
+			// A playback already stopped with esc.
+			return m, nil
+		}
+		// mpv exited (user quit or playlist ended). Return to file list.
+		if errors.Is(msg.err, player.ErrMPVNotFound) {
+			// Nothing to retry from the file list; send the user where
+			// the path can be fixed and tested.
+			cmd := m.cleanupPlayback(true)
+			m.queueing = false
+			m.prevScreen = screenFiles
+			m.screen = screenConfig
+			m.loadSettings()
+			m.focusSetting(settingIndex("mpv_path"))
+			m.configStatus = "Error: mpv was not found. Enter its path below and press ctrl+t to test it."
+			return m, tea.Batch(cmd, textinput.Blink)
+		}
+		if msg.err != nil {
+			m.err = fmt.Errorf("%s failed to start: %w", m.playerName(), msg.err)
+			m.errScroll = 0
+		}
+		var saveVolume tea.Cmd
+		if msg.volumeKnown {
+			saveVolume = m.rememberVolume(msg.volume)
+		}
+		return m, tea.Batch(saveVolume, m.backToFiles())
+
+	case fileErrorMsg:
+		idx := msg.pos
+		if !m.streamAll {
+			idx = m.currentFile
+		}
+		if idx < 0 || idx >= len(m.files) {
 			return m, nil
+		}
+		m.failed = append(m.failed, failedFile{file: m.files[idx], detail: msg.detail})
+		mpv := m.shared.getMPV()
+		if mpv == nil {
+			return m, nil
+		}
+		if m.cfg.StopOnFileError || !m.streamAll {
+			_ = mpv.Quit()
+		} else if m.playlistFirst+mpv.PlaylistPos() == msg.pos {
+			// mpv normally advances on its own; only push it along if it
+			// is still parked on the broken entry.
+			_ = mpv.PlaylistNext()
+		}
+		return m, nil
+
+	case timePosMsg:
+		m.playPos, m.playDur = msg.pos, msg.dur
+		return m, nil
+
+	case screenshotMsg:
+		if msg.err != nil {
+			m.playStatus = fmt.Sprintf("Screenshot failed: %v", msg.err)
+		} else {
+			m.playStatus = "Screenshot saved"
+		}
+		return m, nil
+
+	case playbackStartMsg:
+		if m.firstFrame == 0 {
+			m.firstFrame = msg.at.Sub(m.startTime)
+		}
+		return m, nil
 
 	case tickMsg:
+		m.samplePieceRate(time.Time(msg))
+		m.sampleTransferRate(time.Time(msg))
+		if srv := m.shared.getServer(); srv != nil {
+			current := -1
+			if m.currentFile < len(m.files) {
+				current = fileIndex(m.sessionFiles(), m.files[m.currentFile])
+			}
+			srv.SetPlaybackState(current, m.downRate, m.upRate)
+		}
+		m.checkStall(time.Time(msg))
+		m.trackStallWarning()
 		return m, m.cmdTick()
 
 	case tea.KeyMsg:
-		switch msg.String() {
+		if m.queueing {
+			return m.updateQueueInput(msg)
+		}
+		// The screenshot status lasts until the next keypress.
+		m.playStatus = ""
+		if m.confirmingQuit {
+			m.confirmingQuit = false
+			if msg.String() != "y" {
+				return m, nil
+			}
+			m.gracefulShutdown()
+			m.quitting = true
+			return m, tea.Quit
+		}
+		switch m.keys().canonical(msg.String(), playingActions...) {
+		case "esc":
+			// Way out of a playback that never buffers, in either mode;
+			// the player is killed rather than asked to quit, since it
+			// may be stuck waiting on the stream.
+			slog.Info("playback stopped from the TUI")
+			m.bumpPlayGen()
+			return m, m.backToFiles()
 		case "q":
-			m.cleanup()
+			if m.cfg.SeedsAfterPlayback() && m.upRate > 0 {
+				// Don't cut off a swarm that may depend on us without
+				// asking; ctrl+c still quits outright.
+				m.confirmingQuit = true
+				return m, nil
+			}
+			m.gracefulShutdown()
 			m.quitting = true
 			return m, tea.Quit
+		case "m":
+			return m.openQueueInput()
+		case "p":
+			return m.openPeers()
+		case "t":
+			return m.openTracks()
+		case "i":
+			return m, m.cmdScreenshot()
+		case "+", "=", "-":
+			mpv := m.shared.getMPV()
+			if mpv == nil {
+				return m, nil
+			}
+			vol, ok := mpv.Volume()
+			if !ok {
+				return m, nil
+			}
+			step := volumeStep
+			if msg.String() == "-" {
+				step = -step
+			}
+			_ = mpv.SetVolume(int(math.Round(vol)) + step)
+		case "[", "]", "backspace":
+			mpv := m.shared.getMPV()
+			if mpv == nil {
+				return m, nil
+			}
+			speed, ok := mpv.Speed()
+			if !ok {
+				return m, nil
+			}
+			switch msg.String() {
+			case "[":
+				speed -= speedStep
+			case "]":
+				speed += speedStep
+			default:
+				speed = 1
+			}
+			// Round away float drift so 1.0 is reachable again.
+			_ = mpv.SetSpeed(max(math.Round(speed*10)/10, speedStep))
+		case "z", "Z", "x", "X":
+			// Sync adjustments mirror mpv's own z/Z bindings, in 100ms steps.
+			mpv := m.shared.getMPV()
+			if mpv == nil {
+				return m, nil
+			}
+			switch msg.String() {
+			case "z":
+				_ = mpv.AddSubDelay(-0.1)
+			case "Z":
+				_ = mpv.AddSubDelay(0.1)
+			case "x":
+				_ = mpv.AddAudioDelay(-0.1)
+			case "X":
+				_ = mpv.AddAudioDelay(0.1)
+			}
+		case " ", "left", "right":
+			// Remote control for when the mpv window is on another
+			// machine, e.g. over SSH.
+			mpv := m.shared.getMPV()
+			if mpv == nil {
+				return m, nil
+			}
+			switch msg.String() {
+			case " ":
+				if mpv.Paused() {
+					_ = mpv.Resume()
+				} else {
+					_ = mpv.Pause()
+				}
+			case "left":
+				_ = mpv.Seek(-10, "relative")
+			case "right":
+				_ = mpv.Seek(10, "relative")
+			}
 		}
 	}
 	return m, nil
@@ -515,7 +1504,7 @@ case mpvExitedMsg:
 
 func (m Model) viewPlaying() string {
 	var b strings.Builder
-	b.WriteString(titleStyle.Render("just-stream"))
+	b.WriteString(m.styles.title.Render("just-stream"))
 	b.WriteString("\n\n")
 
 	name := m.shared.getPlayingName()
@@ -524,60 +1513,125 @@ func (m Model) viewPlaying() string {
 	}
 
 	if m.streamAll {
-		b.WriteString(playingStyle.Render(fmt.Sprintf("  Episode %d/%d", m.currentFile+1, m.totalFiles)))
+		b.WriteString(m.styles.playing.Render(fmt.Sprintf("  Episode %d/%d", m.currentFile+1, m.totalFiles)))
 		b.WriteString("\n")
 	}
-	b.WriteString(normalStyle.Render(fmt.Sprintf("  Playing: %s", name)))
-	b.WriteString("\n\n")
+	b.WriteString(m.styles.normal.Render(fmt.Sprintf("  Playing: %s", name)))
+	b.WriteString("\n")
+	if srv := m.shared.getServer(); srv != nil && srv.BindWarning() != "" {
+		b.WriteString(m.styles.warn.Render("  " + srv.BindWarning()))
+		b.WriteString("\n")
+	}
+	if addr := m.listenAddr(); !stream.IsLoopback(addr) {
+		b.WriteString(m.styles.warn.Render(fmt.Sprintf("  Stream reachable from the network on %s (protected by its URL token only)", addr)))
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
 
-	if m.torrent != nil {
-		stats := m.torrent.Stats()
-		b.WriteString(statusStyle.Render(fmt.Sprintf("  Peers:    %d active / %d total",
+	if t := m.currentTorrent(); t != nil {
+		if len(m.torrents) > 1 {
+			b.WriteString(m.styles.normal.Render(fmt.Sprintf("  Torrent:  %s", t.Name())))
+			b.WriteString("\n")
+		}
+		stats := t.Stats()
+		b.WriteString(m.styles.status.Render(fmt.Sprintf("  Peers:    %d active / %d total",
 			stats.ActivePeers, stats.TotalPeers)))
 		b.WriteString("\n")
+		b.WriteString(m.styles.normal.Render(fmt.Sprintf("  Down:     %s/s  (%s total)",
+			humanSize(int64(m.downRate)), humanSize(stats.BytesReadData.Int64()))))
+		b.WriteString("\n")
+		b.WriteString(m.styles.normal.Render(fmt.Sprintf("  Up:       %s/s  (%s total)",
+			humanSize(int64(m.upRate)), humanSize(stats.BytesWrittenData.Int64()))))
+		b.WriteString("\n")
 
 		if m.currentFile < len(m.files) {
-			f := m.files[m.currentFile]
-			var completed int64
-			total := int64(f.EndPieceIndex() - f.BeginPieceIndex())
-			for i := f.BeginPieceIndex(); i < f.EndPieceIndex(); i++ {
-				if m.torrent.PieceState(i).Complete {
-					completed++
-				}
+			pct := filePercent(m.files[m.currentFile])
+			b.WriteString(m.styles.normal.Render(fmt.Sprintf("  Episode:  %s %.1f%%", m.styles.progressBar(pct), pct)))
+			if eta := m.bufferETA(); eta != "" {
+				b.WriteString(m.styles.dim.Render("  " + eta))
 			}
-			pct := float64(0)
-			if total > 0 {
-				pct = float64(completed) / float64(total) * 100
-			}
-
-			barW := 40
-			filled := int(pct / 100 * float64(barW))
-			if filled > barW {
-				filled = barW
+			b.WriteString("\n")
+			if m.stalled() {
+				b.WriteString(m.styles.warn.Render("  Stalled — searching for peers"))
+				b.WriteString("\n")
 			}
-			bar := progressFullStyle.Render(strings.Repeat("█", filled)) +
-				progressEmptyStyle.Render(strings.Repeat("░", barW-filled))
 
-			b.WriteString(normalStyle.Render(fmt.Sprintf("  Buffer:   %s %.1f%%", bar, pct)))
+			totalPct := fraction(t.BytesCompleted(), t.Length()) * 100
+			b.WriteString(m.styles.normal.Render(fmt.Sprintf("  Total:    %s %.1f%%", m.styles.progressBar(totalPct), totalPct)))
 			b.WriteString("\n")
 
 			// Show seeding status
-			if pct >= 100 {
-				b.WriteString(seedingStyle.Render("  Status:   Seeding (sharing with peers)"))
+			if pct >= 100 && m.cfg.SeedsAfterPlayback() {
+				b.WriteString(m.styles.seeding.Render("  Status:   Seeding (sharing with peers)"))
 				b.WriteString("\n")
 			}
 		}
 
 		elapsed := time.Since(m.startTime).Truncate(time.Second)
-		b.WriteString(normalStyle.Render(fmt.Sprintf("  Elapsed:  %s", elapsed)))
+		b.WriteString(m.styles.normal.Render(fmt.Sprintf("  Elapsed:  %s", elapsed)))
+		b.WriteString("\n")
+
+		b.WriteString(m.styles.normal.Render(fmt.Sprintf("  Startup:  %s", m.startupLatency())))
+		b.WriteString("\n")
+
+		if m.memStore != nil {
+			b.WriteString(m.styles.normal.Render(fmt.Sprintf("  RAM:      %s", m.ramUsage())))
+			b.WriteString("\n")
+		} else if ds, ok := m.store.(*memstorage.DiskStorage); ok {
+			b.WriteString(m.styles.normal.Render(fmt.Sprintf("  Disk:     %s", ds.Dir())))
+			b.WriteString("\n")
+		}
+
+		if m.debug {
+			b.WriteString(m.styles.dim.Render(fmt.Sprintf("  Pieces:   %.1f/s (%d/%d complete)",
+				m.piecesPerSec, stats.PiecesComplete, t.NumPieces())))
+			b.WriteString("\n")
+		}
+
+		if mpv := m.shared.getMPV(); mpv != nil && mpv.HasIPC() {
+			line := fmt.Sprintf("  Position: %s / %s", clock(m.playPos), clock(m.playDur))
+			if mpv.Paused() {
+				line += "  (paused)"
+			}
+			b.WriteString(m.styles.normal.Render(line))
+			b.WriteString("\n")
+
+			sub, audio := mpv.Delays()
+			b.WriteString(m.styles.normal.Render(fmt.Sprintf("  Sync:     sub %+.1fs  audio %+.1fs", sub, audio)))
+			b.WriteString("\n")
+			if vol, ok := mpv.Volume(); ok {
+				b.WriteString(m.styles.normal.Render(fmt.Sprintf("  Volume:   %.0f%%", vol)))
+				b.WriteString("\n")
+			}
+			if speed, ok := mpv.Speed(); ok {
+				b.WriteString(m.styles.normal.Render(fmt.Sprintf("  Speed:    %.1fx", speed)))
+				b.WriteString("\n")
+			}
+		}
+	}
+
+	b.WriteString(m.viewQueue())
+
+	if t := m.currentTorrent(); m.confirmingQuit && t != nil {
+		b.WriteString("\n")
+		stats := t.Stats()
+		uploaded := stats.BytesWrittenData.Int64()
+		b.WriteString(m.styles.warn.Render(fmt.Sprintf("  Still seeding, %s uploaded. Quit anyway? (y/n)", humanSize(uploaded))))
+		b.WriteString("\n")
+	}
+
+	if m.playStatus != "" {
+		b.WriteString("\n")
+		b.WriteString(m.styles.status.Render("  " + m.playStatus))
 		b.WriteString("\n")
 	}
 
 	b.WriteString("\n")
+	controls := "space: pause  ←/→: seek  +/-: volume  [/]: speed  bksp: 1x  z/Z: sub delay  x/X: audio delay  i: screenshot  m: queue magnet  p: peers  t: tracks"
 	if m.streamAll {
-		b.WriteString(helpStyle.Render("Shift+>/< in mpv: next/prev  q: quit"))
+		b.WriteString(m.styles.help.Render(helpLine("Shift+>/< in mpv: next/prev", controls, "esc: back to list", m.keys().help("quit", actionQuit))))
 	} else {
-		b.WriteString(helpStyle.Render("q: back to list"))
+		b.WriteString(m.styles.help.Render(helpLine(controls, "esc: back to list", m.keys().help("quit", actionQuit))))
 	}
 	return b.String()
 }
@@ -589,19 +1643,44 @@ func (m Model) viewPlaying() string {
 func (m Model) updateConfig(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case configSavedMsg:
+		switch {
+		case msg.err != nil:
+			m.configStatus = fmt.Sprintf("Error: %v", msg.err)
+		case !config.Persistent():
+			m.configStatus = "Applied for this session (not saved)"
+		default:
+			m.configStatus = "Saved!"
+		}
+		return m, nil
+	case settingTestedMsg:
 		if msg.err != nil {
 			m.configStatus = fmt.Sprintf("Error: %v", msg.err)
 		} else {
-			m.configStatus = "Saved!"
+			m.configStatus = "OK: " + msg.result
 		}
 		return m, nil
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "enter":
-			path := strings.TrimSpace(m.configInput.Value())
-			m.cfg.MpvPath = path
+		// In a multiline field, enter and the arrows edit the text; tab
+		// and ctrl+s still leave or save.
+		multiline := m.configInputs[m.configFocus].multiline
+		switch key := msg.String(); {
+		case key == "ctrl+s" || key == "enter" && !multiline:
+			if err := m.applySettings(); err != nil {
+				m.configStatus = fmt.Sprintf("Error: %v", err)
+				return m, nil
+			}
 			return m, m.cmdSaveConfig()
-		case "esc":
+		case key == "ctrl+t":
+			// Not plain t: the focused field takes typed text.
+			m.configStatus = "Testing..."
+			return m, m.cmdTestSetting()
+		case key == "tab" || key == "down" && !multiline:
+			m.focusSetting(m.configFocus + 1)
+			return m, textinput.Blink
+		case key == "shift+tab" || key == "up" && !multiline:
+			m.focusSetting(m.configFocus - 1)
+			return m, textinput.Blink
+		case key == "esc":
 			m.screen = m.prevScreen
 			// Re-focus the magnet input if returning there.
 			if m.screen == screenInput {
@@ -612,41 +1691,75 @@ func (m Model) updateConfig(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	}
 	var cmd tea.Cmd
-	m.configInput, cmd = m.configInput.Update(msg)
+	m.configInputs[m.configFocus], cmd = m.configInputs[m.configFocus].Update(msg)
 	return m, cmd
 }
 
 func (m Model) viewConfig() string {
 	var b strings.Builder
-	b.WriteString(titleStyle.Render("just-stream"))
+	b.WriteString(m.styles.title.Render("just-stream"))
 	b.WriteString(" ")
-	b.WriteString(dimStyle.Render("settings"))
+	b.WriteString(m.styles.dim.Render("settings"))
 	b.WriteString("\n\n")
 
-	b.WriteString(normalStyle.Render("  mpv path (leave empty for auto-detect):"))
-	b.WriteString("\n\n")
-	b.WriteString("  ")
-	b.WriteString(m.configInput.View())
-	b.WriteString("\n\n")
+	// Each field takes three lines; show a window around the focused one
+	// when the terminal can't fit them all.
+	visible := (m.height - 12) / 3
+	if visible < 3 {
+		visible = len(settingFields)
+	}
+	startIdx := 0
+	if m.configFocus >= visible {
+		startIdx = m.configFocus - visible + 1
+	}
+	endIdx := min(startIdx+visible, len(settingFields))
+	if startIdx > 0 {
+		b.WriteString(m.styles.dim.Render(fmt.Sprintf("  ... %d more above", startIdx)))
+		b.WriteString("\n\n")
+	}
+
+	for i := startIdx; i < endIdx; i++ {
+		f := settingFields[i]
+		if f.section != "" && (i == startIdx || settingFields[i-1].section != f.section) {
+			b.WriteString(m.styles.header.Render("  " + f.section))
+			b.WriteString("\n\n")
+		}
+		style := m.styles.normal
+		if i == m.configFocus {
+			style = m.styles.selected
+		}
+		b.WriteString(style.Render(fmt.Sprintf("  %s:", f.label)))
+		b.WriteString("\n")
+		b.WriteString("  ")
+		b.WriteString(m.configInputs[i].View())
+		b.WriteString("\n\n")
+	}
+	if endIdx < len(settingFields) {
+		b.WriteString(m.styles.dim.Render(fmt.Sprintf("  ... %d more below", len(settingFields)-endIdx)))
+		b.WriteString("\n\n")
+	}
 
 	if m.configStatus != "" {
 		if strings.HasPrefix(m.configStatus, "Error") {
 			b.WriteString("  ")
-			b.WriteString(errorStyle.Render(m.configStatus))
+			b.WriteString(m.styles.error.Render(m.configStatus))
 		} else {
 			b.WriteString("  ")
-			b.WriteString(playingStyle.Render(m.configStatus))
+			b.WriteString(m.styles.playing.Render(m.configStatus))
 		}
 		b.WriteString("\n\n")
 	}
 
 	cfgPath, _ := config.Path()
-	if cfgPath != "" {
-		b.WriteString(dimStyle.Render(fmt.Sprintf("  config: %s", cfgPath)))
+	if !config.Persistent() {
+		b.WriteString(m.styles.dim.Render("  config: in memory only (--no-persist)"))
+		b.WriteString("\n\n")
+	} else if cfgPath != "" {
+		b.WriteString(m.styles.dim.Render(fmt.Sprintf("  config: %s", cfgPath)))
 		b.WriteString("\n\n")
 	}
 
-	b.WriteString(helpStyle.Render("enter: save  esc: back  ctrl+c: quit"))
+	b.WriteString(m.styles.help.Render("tab/↑↓: field  ctrl+t: test  enter/ctrl+s: save  esc: back  ctrl+c: quit"))
 	return b.String()
 }
 
@@ -661,113 +1774,219 @@ func (m Model) cmdSaveConfig() tea.Cmd {
 // Commands (run in background goroutines)
 // ──────────────────────────────────────────────
 
-func (m Model) cmdFetchMetadata() tea.Cmd {
-	memStore := m.memStore
-	uri := m.magnetURI
-	proxyURL := m.proxyURL
-	return func() tea.Msg {
-		cfg := torrent.NewDefaultClientConfig()
-		cfg.DefaultStorage = memStore
-		cfg.ListenPort = 0
+// fetchSetup is what a metadata fetch needs from the model, copied so the
+// fetch can run off the UI goroutine.
+type fetchSetup struct {
+	uri            string
+	store          storage.ClientImpl
+	memStore       *memstorage.MemoryStorage
+	proxyURL       string
+	maxUp, maxDown int64
+	cfg            config.Config
+}
 
-		// Configure proxy if provided.
-		if proxyURL != "" {
-			if err := configureProxy(cfg, proxyURL); err != nil {
-				return metadataErrMsg{err: fmt.Errorf("proxy config: %w", err)}
-			}
+func (m Model) fetchSetup() fetchSetup {
+	maxUp, maxDown := m.rateLimits()
+	return fetchSetup{
+		uri:      m.magnetURI,
+		store:    m.store,
+		memStore: m.memStore,
+		proxyURL: m.proxyURL,
+		maxUp:    maxUp,
+		maxDown:  maxDown,
+		cfg:      *m.cfg,
+	}
+}
+
+func (m Model) cmdFetchMetadata(ctx context.Context) tea.Cmd {
+	fetch := m.fetchSetup()
+	sh := m.shared
+	gen := m.fetchGen
+	return func() tea.Msg {
+		client, t, mi, err := fetchMetadata(ctx, fetch, sh, func(t *torrent.Torrent) {
+			sh.send(metadataStartedMsg{gen: gen, t: t})
+		})
+		if ctx.Err() != nil {
+			// Aborted with esc; nobody is waiting for this client.
+			return nil
+		}
+		if err != nil {
+			return metadataErrMsg{err: err}
 		}
+		return metadataReadyMsg{client: client, t: t, mi: mi}
+	}
+}
 
-		client, err := torrent.NewClient(cfg)
+// fetchMetadata starts a torrent client for fetch.uri and waits for the
+// torrent's info. started is called with a magnet's torrent as soon as it
+// is added, before its info arrives. On error the client is closed.
+func fetchMetadata(ctx context.Context, fetch fetchSetup, sh *shared, started func(*torrent.Torrent)) (*torrent.Client, *torrent.Torrent, *metainfo.MetaInfo, error) {
+	// A .torrent file carries its own info, so parse it up front and
+	// fail before any network setup if it is not valid.
+	var mi *metainfo.MetaInfo
+	if IsTorrentFile(fetch.uri) {
+		var err error
+		mi, err = metainfo.LoadFromFile(fetch.uri)
 		if err != nil {
-			return metadataErrMsg{err: fmt.Errorf("create client: %w", err)}
+			return nil, nil, nil, fmt.Errorf("%s is not a valid .torrent file: %w", filepath.Base(fetch.uri), err)
+		}
+	}
+
+	cfg := torrent.NewDefaultClientConfig()
+	cfg.DefaultStorage = fetch.store
+	// Client events go to the --log file, never the terminal the TUI
+	// is drawing on.
+	cfg.Slogger = slog.Default().With("component", "torrent")
+	cfg.PeerID, cfg.ListenPort = sh.sessionIdentity(cfg.Bep20, fetch.cfg.ListenPort)
+	applyAdvanced(cfg, &fetch.cfg)
+	// A zero burst lets the client pick one that fits its chunk sizes.
+	if fetch.maxUp > 0 {
+		cfg.UploadRateLimiter = rate.NewLimiter(rate.Limit(fetch.maxUp), 0)
+	}
+	if fetch.maxDown > 0 {
+		cfg.DownloadRateLimiter = rate.NewLimiter(rate.Limit(fetch.maxDown), 0)
+	}
+
+	// Configure proxy if provided.
+	if fetch.proxyURL != "" {
+		if err := configureProxy(cfg, fetch.proxyURL, fetch.cfg.ProxyBypass); err != nil {
+			return nil, nil, nil, fmt.Errorf("proxy config: %w", err)
+		}
+	}
+
+	client, err := torrent.NewClient(cfg)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("create client: %w", err)
+	}
+	sh.rememberListenPort(client.LocalPort())
+	if fetch.proxyURL != "" && fetch.cfg.ProxyUDP && cfg.NoDHT {
+		// Before any torrent is added, so they all announce to it.
+		if err := addSOCKSDHT(client, fetch.proxyURL); err != nil {
+			slog.Warn("DHT stays off behind the proxy", "err", err)
 		}
+	}
+	if fetch.memStore != nil {
+		fetch.memStore.SetOnEvict(func(ih metainfo.Hash, piece int) {
+			// The client caches completion; make it notice the piece is
+			// gone so it is downloaded again when needed.
+			if t, ok := client.Torrent(ih); ok {
+				t.Piece(piece).UpdateCompletion()
+			}
+		})
+	}
 
-		t, err := client.AddMagnet(uri)
+	var t *torrent.Torrent
+	if mi != nil {
+		t, err = client.AddTorrent(mi)
+		if err != nil {
+			client.Close()
+			return nil, nil, nil, fmt.Errorf("add torrent: %w", err)
+		}
+	} else {
+		t, err = client.AddMagnet(fetch.uri)
 		if err != nil {
 			client.Close()
-			return metadataErrMsg{err: fmt.Errorf("add magnet: %w", err)}
+			return nil, nil, nil, fmt.Errorf("add magnet: %w", err)
 		}
+	}
+	if trackers := extraTrackerTiers(&fetch.cfg); len(trackers) > 0 {
+		t.AddTrackers(trackers)
+	}
+	if mi == nil && started != nil {
+		// A .torrent file's info is ready at once; only magnets
+		// wait on the swarm.
+		started(t)
+	}
 
-		<-t.GotInfo()
-		return metadataReadyMsg{client: client, t: t}
+	timeout := metadataTimeout(&fetch.cfg)
+	var timedOut <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timedOut = timer.C
+	}
+	select {
+	case <-t.GotInfo():
+	case <-timedOut:
+		client.Close()
+		return nil, nil, nil, fmt.Errorf("no metadata after %s; the torrent may have no reachable peers", timeout)
+	case <-ctx.Done():
+	}
+	if ctx.Err() != nil {
+		client.Close()
+		return nil, nil, nil, ctx.Err()
 	}
+	return client, t, mi, nil
 }
 
 func (m Model) cmdStartPlayback() tea.Cmd {
 	sh := m.shared
-	t := m.torrent
 	files := m.files
 	streamAllMode := m.streamAll
 	startIdx := m.currentFile
+	first := m.playlistFirst
 	mpvPath := m.cfg.MpvPath
+	titleFormat := m.cfg.TitleFormat
+	setup := m.serverSetup()
+	memStore := m.memStore
+	lowLatency := m.cfg.LowLatencyStart
+	preload := 0
+	if m.streamAll {
+		preload = m.cfg.Preload()
+	}
+	boostPct := m.cfg.StartupBoostPercent
+	anime4KMode := m.cfg.Anime4KMode
+	playerName := m.playerName()
+	playerPath := m.cfg.PlayerPath
+	anime4KDir := m.cfg.Anime4KShaders
+	subLang, audioLang := m.cfg.PreferredSubLang, m.cfg.PreferredAudioLang
+	mpvArgs := m.cfg.MpvArgs
+	mpvCache := m.cfg.MpvCacheEnabled()
+	cacheSecs := cmp.Or(m.cfg.MpvCacheSecs, config.DefaultMpvCacheSecs)
+	demuxerMaxMB := cmp.Or(m.cfg.MpvDemuxerMaxMB, config.DefaultMpvDemuxerMaxMB)
+	screenshotDir := m.cfg.ScreenshotDir
+	volume := m.cfg.DefaultVolume
+	ipcDir := m.cfg.IPCDir
+	gen := m.playGen
 
 	return func() tea.Msg {
-		// Ensure HTTP server is running.
-		sh.mu.Lock()
-		if sh.server == nil {
-			srv, err := stream.NewServer()
-			if err != nil {
-				sh.mu.Unlock()
-				return mpvExitedMsg{err: err}
-			}
-			sh.server = srv
-			go srv.Serve()
+		if _, err := sh.ensureServer(setup); err != nil {
+			return mpvExitedMsg{gen: gen, err: err}
 		}
-		sh.server.SetFiles(files)
-		sh.mu.Unlock()
 
-		// Build URL and title lists.
+		// Build URL and title lists. In stream-all mode the playlist
+		// runs on across torrents when the session has several.
 		var urls []string
 		var titles []string
 
 		if streamAllMode {
-			// All files as playlist entries.
-			for i := range files {
+			// Every file from the first playlist entry on.
+			for i := first; i < len(files); i++ {
 				sh.mu.Lock()
-				u := sh.server.FileURL(i)
+				u := sh.server.FileURL(fileIndex(setup.files, files[i]))
 				sh.mu.Unlock()
 				urls = append(urls, u)
-				titles = append(titles, shortName(files[i].DisplayPath()))
+				titles = append(titles, formatTitle(titleFormat, files[i].Torrent().Name(), files[i].DisplayPath(), i))
 			}
 		} else {
 			// Single file.
 			sh.mu.Lock()
-			u := sh.server.FileURL(startIdx)
+			u := sh.server.FileURL(fileIndex(setup.files, files[startIdx]))
 			sh.mu.Unlock()
 			urls = append(urls, u)
-			titles = append(titles, shortName(files[startIdx].DisplayPath()))
+			titles = append(titles, formatTitle(titleFormat, files[startIdx].Torrent().Name(), files[startIdx].DisplayPath(), startIdx))
 		}
 
-		// Set playing name.
-		playIdx := 0
-		if streamAllMode {
-			playIdx = startIdx
-		}
-		sh.setPlayingName(titles[playIdx])
+		sh.setPlayingName(shortName(files[startIdx].DisplayPath()))
 
-		// Prioritize starting file, deprioritize others.
-		actualIdx := startIdx
-		if !streamAllMode {
-			actualIdx = startIdx
-		}
-		for i, f := range files {
-			if i == actualIdx {
-				f.SetPriority(torrent.PiecePriorityNormal)
-			} else {
-				f.SetPriority(torrent.PiecePriorityNone)
-			}
-		}
+		prioritizeFiles(files, startIdx, preload, lowLatency, boostPct, memStore)
 
-		// Boost first 5% of starting file for fast startup.
-		startFile := files[actualIdx]
-		first := startFile.BeginPieceIndex()
-		end := startFile.EndPieceIndex()
-		boost := first + (end-first)/20
-		if boost <= first {
-			boost = first + 1
-		}
-		for i := first; i < boost; i++ {
-			t.Piece(i).SetPriority(torrent.PiecePriorityNow)
+		startPos, _ := config.LookupPosition(resumeKey(files[startIdx]))
+		noSubs := false
+		if choice, ok := config.LookupTrackChoice(files[startIdx].Torrent().InfoHash().HexString()); ok {
+			audioLang = preferLang(choice.AudioLang, audioLang)
+			subLang = preferLang(choice.SubLang, subLang)
+			noSubs = choice.SubsOff
 		}
 
 		// Kill any existing mpv.
@@ -781,30 +2000,58 @@ func (m Model) cmdStartPlayback() tea.Cmd {
 		// Build mpv launch options with playlist position callback.
 		launchStartIdx := 0
 		if streamAllMode {
-			launchStartIdx = startIdx
+			launchStartIdx = startIdx - first
 		}
 
 		opts := player.LaunchOpts{
-			URLs:       urls,
-			Titles:     titles,
-			StartIndex: launchStartIdx,
-			MpvPath:    mpvPath,
+			URLs:          urls,
+			Titles:        titles,
+			StartIndex:    launchStartIdx,
+			StartPos:      startPos,
+			MpvPath:       mpvPath,
+			LowLatency:    lowLatency,
+			Anime4KMode:   anime4KMode,
+			Anime4KDir:    anime4KDir,
+			SubLang:       subLang,
+			AudioLang:     audioLang,
+			NoSubs:        noSubs,
+			ExtraArgs:     mpvArgs,
+			Cache:         mpvCache,
+			CacheSecs:     cacheSecs,
+			DemuxerMaxMB:  demuxerMaxMB,
+			ScreenshotDir: screenshotDir,
+			Volume:        volume,
+			IPCDir:        ipcDir,
 			OnPlaylistPos: func(pos int) {
-				sh.mu.Lock()
-				p := sh.program
-				sh.mu.Unlock()
-				if p != nil {
-					p.Send(playlistPosMsg{pos: pos})
-				}
+				sh.send(playlistPosMsg{pos: first + pos})
+			},
+			OnPlaybackStart: func() {
+				sh.send(playbackStartMsg{at: time.Now()})
+			},
+			OnFileError: func(pos int, detail string) {
+				sh.send(fileErrorMsg{pos: first + pos, detail: detail})
+			},
+			OnTimePos: func(pos, dur float64) {
+				sh.send(timePosMsg{pos: pos, dur: dur})
 			},
 		}
 
-		mpvInst, err := player.Launch(opts)
+		pl, err := player.New(playerName, playerPath)
+		if err != nil {
+			return mpvExitedMsg{gen: gen, err: err}
+		}
+		mpvInst, err := pl.Launch(opts)
 		if err != nil {
-			return mpvExitedMsg{err: err}
+			return mpvExitedMsg{gen: gen, err: err}
 		}
 
 		sh.mu.Lock()
+		if sh.playGen != gen {
+			// Playback was stopped with esc while the player started.
+			sh.mu.Unlock()
+			mpvInst.Kill()
+			return mpvExitedMsg{gen: gen}
+		}
 		sh.mpv = mpvInst
 		sh.mu.Unlock()
 
@@ -812,11 +2059,191 @@ func (m Model) cmdStartPlayback() tea.Cmd {
 		waitErr := mpvInst.Wait()
 
 		sh.mu.Lock()
+		if sh.mpv == mpvInst {
+			// Not killed by cleanupPlayback, which saves on its own.
+			saveResumePosition(files, streamAllMode, first, startIdx, mpvInst)
+		}
 		sh.mpv = nil
 		sh.mu.Unlock()
 
-		return mpvExitedMsg{err: waitErr}
+		vol, volKnown := mpvInst.Volume()
+		return mpvExitedMsg{gen: gen, err: waitErr, volume: vol, volumeKnown: volKnown}
+	}
+}
+
+// samplePieceRate updates the pieces-per-second readout from the change
+// in completed pieces since the previous tick. Unlike byte rates, this
+// tracks how fast the streaming buffer fills regardless of piece size.
+func (m *Model) samplePieceRate(now time.Time) {
+	t := m.currentTorrent()
+	if t == nil {
+		return
+	}
+	done := t.Stats().PiecesComplete
+	if !m.piecesSampleAt.IsZero() {
+		if dt := now.Sub(m.piecesSampleAt).Seconds(); dt > 0 {
+			// As with byte rates, the count restarts when the playlist
+			// moves on to another torrent.
+			m.piecesPerSec = max(float64(done-m.piecesDone)/dt, 0)
+		}
+	}
+	m.piecesDone = done
+	m.piecesSampleAt = now
+}
+
+// volumeStep is how much + and - change the volume, in percent.
+const volumeStep = 5
+
+// speedStep is how much [ and ] change the playback speed.
+const speedStep = 0.1
+
+// defaultMetadataTimeout bounds the wait for a magnet's metadata.
+const defaultMetadataTimeout = 60 * time.Second
+
+// metadataTimeout returns the configured metadata timeout, or 0 for none.
+func metadataTimeout(cfg *config.Config) time.Duration {
+	switch {
+	case cfg.MetadataTimeoutSecs < 0:
+		return 0
+	case cfg.MetadataTimeoutSecs == 0:
+		return defaultMetadataTimeout
+	}
+	return time.Duration(cfg.MetadataTimeoutSecs) * time.Second
+}
+
+// rateLimits returns the upload and download caps in bytes/s, with the
+// command-line flags taking precedence over the config.
+func (m Model) rateLimits() (up, down int64) {
+	up, down = m.cfg.MaxUploadRate, m.cfg.MaxDownloadRate
+	if m.maxUpOverride != nil {
+		up = *m.maxUpOverride
+	}
+	if m.maxDownOverride != nil {
+		down = *m.maxDownOverride
+	}
+	return up, down
+}
+
+// playerName returns the media player to launch: the --player flag, else
+// the configured one, else mpv.
+func (m Model) playerName() string {
+	if m.playerOverride != "" {
+		return m.playerOverride
+	}
+	if m.cfg.Player != "" {
+		return m.cfg.Player
+	}
+	return player.NameMPV
+}
+
+// listenAddr returns the stream server's bind address, honoring --listen.
+func (m Model) listenAddr() string {
+	if m.listenOverride != "" {
+		return m.listenOverride
+	}
+	if m.cfg.StreamListenAddr != "" {
+		return m.cfg.StreamListenAddr
+	}
+	return stream.DefaultListenAddr
+}
+
+// IsTorrentFile reports whether s names an existing .torrent file rather
+// than a magnet URI.
+func IsTorrentFile(s string) bool {
+	if !strings.EqualFold(filepath.Ext(s), ".torrent") {
+		return false
+	}
+	fi, err := os.Stat(s)
+	return err == nil && fi.Mode().IsRegular()
+}
+
+// rateSmoothing is the weight of the newest sample in smoothDown; lower
+// is steadier but slower to follow real changes.
+const rateSmoothing = 0.2
+
+// bufferETA describes how long the current file needs to be fully
+// downloaded at the smoothed rate: "~1m20s to full buffer", "" when it is
+// complete, or "—" when nothing is arriving.
+func (m Model) bufferETA() string {
+	if m.currentFile >= len(m.files) {
+		return ""
+	}
+	f := m.files[m.currentFile]
+	remaining := f.Length() - f.BytesCompleted()
+	if remaining <= 0 {
+		return ""
+	}
+	if m.smoothDown < 1 {
+		return "—"
+	}
+	eta := time.Duration(float64(remaining) / m.smoothDown * float64(time.Second))
+	return fmt.Sprintf("~%s to full buffer", eta.Round(time.Second))
+}
+
+// sampleTransferRate updates the download and upload rates from the
+// playing torrent's payload byte counters.
+func (m *Model) sampleTransferRate(now time.Time) {
+	t := m.currentTorrent()
+	if t == nil {
+		return
+	}
+	stats := t.Stats()
+	read := stats.BytesReadData.Int64()
+	written := stats.BytesWrittenData.Int64()
+	if !m.rateSampleAt.IsZero() {
+		if dt := now.Sub(m.rateSampleAt).Seconds(); dt > 0 {
+			// Counters restart when the playing torrent changes; never
+			// show a negative rate for that one sample.
+			m.downRate = max(float64(read-m.bytesRead)/dt, 0)
+			m.upRate = max(float64(written-m.bytesWritten)/dt, 0)
+			m.smoothDown += rateSmoothing * (m.downRate - m.smoothDown)
+		}
+	}
+	m.bytesRead = read
+	m.bytesWritten = written
+	m.rateSampleAt = now
+}
+
+// startupLatency describes time-to-first-byte from the stream server and
+// time-to-first-frame in mpv, both measured from file selection.
+func (m Model) startupLatency() string {
+	ttfb := "—"
+	if srv := m.shared.getServer(); srv != nil {
+		if at := srv.FirstByteAt(); !at.IsZero() {
+			ttfb = at.Sub(m.startTime).Round(10 * time.Millisecond).String()
+		}
+	}
+	frame := "—"
+	if m.firstFrame > 0 {
+		frame = m.firstFrame.Round(10 * time.Millisecond).String()
 	}
+	mode := ""
+	if m.cfg.LowLatencyStart {
+		mode = "  (low-latency)"
+	}
+	return fmt.Sprintf("first byte %s  first frame %s%s", ttfb, frame, mode)
+}
+
+// fileProgressInterval throttles the file list's per-file completion
+// scan, which walks every piece of the torrent.
+const fileProgressInterval = 2 * time.Second
+
+func cmdProgressTick() tea.Cmd {
+	return tea.Tick(fileProgressInterval, func(t time.Time) tea.Msg {
+		return progressTickMsg(t)
+	})
+}
+
+// refreshFileProgress recomputes the completion shown next to each file.
+func (m *Model) refreshFileProgress() {
+	if len(m.torrents) == 0 {
+		return
+	}
+	pct := make(map[*torrent.File]float64, len(m.files))
+	for _, f := range m.sessionFiles() {
+		pct[f] = filePercent(f)
+	}
+	m.filePct = pct
 }
 
 func (m Model) cmdTick() tea.Cmd {
@@ -830,10 +2257,31 @@ func (m Model) cmdTick() tea.Cmd {
 // ──────────────────────────────────────────────
 
 func (m Model) beginPlayback(fileIdx int, all bool) (tea.Model, tea.Cmd) {
+	m.playlistFirst = 0
+	return m.startPlayback(fileIdx, all)
+}
+
+// beginPlaylistFrom streams the files from first through the end of the
+// list as one playlist, e.g. to pick a season back up mid-way.
+func (m Model) beginPlaylistFrom(first int) (tea.Model, tea.Cmd) {
+	m.playlistFirst = first
+	return m.startPlayback(first, true)
+}
+
+func (m Model) startPlayback(fileIdx int, all bool) (tea.Model, tea.Cmd) {
+	// Undo stopSeeding from an earlier playback.
+	for _, t := range m.torrents {
+		t.AllowDataUpload()
+	}
 	m.screen = screenPlaying
+	m.bumpPlayGen()
 	m.currentFile = fileIdx
 	m.streamAll = all
 	m.startTime = time.Now()
+	m.firstFrame = 0
+	m.failed = nil
+	m.playPos, m.playDur = 0, 0
+	m.resetStall()
 	if all {
 		m.totalFiles = len(m.files)
 	} else {
@@ -842,67 +2290,433 @@ func (m Model) beginPlayback(fileIdx int, all bool) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(
 		m.cmdStartPlayback(),
 		m.cmdTick(),
+		m.cmdSaveLastFile(fileIdx),
 	)
 }
 
+// rebuildFileList reapplies the media filter and sort order to the
+// session's files, keeping the cursor on the same file when it is still
+// listed. Each torrent's files form a group, in the order the torrents
+// joined, so a torrent joining never moves the files listed before it.
+// Every file-list view option goes through here so the list is never
+// re-fetched.
+func (m *Model) rebuildFileList() {
+	if len(m.torrents) == 0 {
+		return
+	}
+	var current *torrent.File
+	if m.cursor >= 0 && m.cursor < len(m.files) {
+		current = m.files[m.cursor]
+	}
+
+	m.files = nil
+	m.hasMedia = false
+	m.listedTotal = 0
+	for _, t := range m.torrents {
+		m.appendTorrentFiles(t)
+	}
+	files := m.files
+
+	m.cursor = clampCursor(m.cursor, len(files))
+	for i, f := range files {
+		if f == current {
+			m.cursor = i
+			break
+		}
+	}
+}
+
+// appendTorrentFiles lists t's files after the others: its media files,
+// or all of them when it has none, sorted and filtered.
+func (m *Model) appendTorrentFiles(t *torrent.Torrent) {
+	// Copy so sorting never reorders the torrent's own slice.
+	all := append([]*torrent.File(nil), t.Files()...)
+	group := filterMediaFiles(all, mediaExtensionSet(m.cfg))
+	if len(group) > 0 {
+		m.hasMedia = true
+	} else {
+		group = all
+	}
+	m.sortFiles(group)
+	m.listedTotal += len(group)
+	m.files = append(m.files, filterFiles(group, m.filter)...)
+}
+
 // setPriorities updates torrent piece priorities for the current file.
 func (m *Model) setPriorities(fileIdx int) {
 	if fileIdx >= len(m.files) {
 		return
 	}
-	for i, f := range m.files {
+	preload := 0
+	if m.streamAll {
+		preload = m.cfg.Preload()
+	}
+	prioritizeFiles(m.files, fileIdx, preload, m.cfg.LowLatencyStart, m.cfg.StartupBoostPercent, m.memStore)
+}
+
+// prioritizeFiles downloads files[fileIdx], its start first, and nothing
+// else but the start of the preload files after it in the playlist, so the
+// player moves on to the next episode without waiting. The preloaded
+// starts get readahead priority, below the pieces the player is waiting
+// on. Those ranges and the playing file are protected from eviction.
+// files may span torrents; each file's pieces are set on its own torrent.
+func prioritizeFiles(files []*torrent.File, fileIdx, preload int, lowLatency bool, boostPct int, memStore *memstorage.MemoryStorage) {
+	// Every listed torrent gets an entry, so one the playlist has moved
+	// on from is unprotected.
+	protect := make(map[*torrent.Torrent][][2]int)
+	for i, f := range files {
 		if i == fileIdx {
 			f.SetPriority(torrent.PiecePriorityNormal)
 		} else {
 			f.SetPriority(torrent.PiecePriorityNone)
 		}
+		protect[f.Torrent()] = nil
 	}
 
-	// Boost first 5% of new file.
-	f := m.files[fileIdx]
-	first := f.BeginPieceIndex()
-	end := f.EndPieceIndex()
-	boost := first + (end-first)/20
-	if boost <= first {
-		boost = first + 1
-	}
+	f := files[fileIdx]
+	t := f.Torrent()
+	first, boost := startupBoost(f, lowLatency, boostPct)
 	for i := first; i < boost; i++ {
-		m.torrent.Piece(i).SetPriority(torrent.PiecePriorityNow)
+		t.Piece(i).SetPriority(torrent.PiecePriorityNow)
 	}
+	protect[t] = append(protect[t], [2]int{f.BeginPieceIndex(), f.EndPieceIndex()})
+
+	for _, next := range files[fileIdx+1 : min(fileIdx+1+preload, len(files))] {
+		nt := next.Torrent()
+		first, boost := startupBoost(next, lowLatency, boostPct)
+		for i := first; i < boost; i++ {
+			nt.Piece(i).SetPriority(torrent.PiecePriorityReadahead)
+		}
+		protect[nt] = append(protect[nt], [2]int{first, boost})
+	}
+
+	if memStore != nil {
+		for t, ranges := range protect {
+			if len(ranges) == 0 {
+				memStore.Unprotect(t.InfoHash())
+			} else {
+				memStore.Protect(t.InfoHash(), ranges...)
+			}
+		}
+	}
+}
+
+// defaultStartupBoostPercent is the share of a file boosted at startup
+// when the config leaves it unset.
+const defaultStartupBoostPercent = 5
+
+// startupBoost returns the piece range [first, end) at the start of f
+// that is fetched ahead of everything else: the first percent of it
+// (defaultStartupBoostPercent when 0) normally, or the first 1% (at least
+// two pieces) in low-latency mode, which lets mpv start on less data at
+// the cost of an earlier rebuffer on slow swarms.
+func startupBoost(f *torrent.File, lowLatency bool, percent int) (first, end int) {
+	if percent <= 0 {
+		percent = defaultStartupBoostPercent
+	}
+	first = f.BeginPieceIndex()
+	n := f.EndPieceIndex() - first
+	end = first + n*percent/100
+	minPieces := 1
+	if lowLatency {
+		end = first + n/100
+		minPieces = 2
+	}
+	if end < first+minPieces {
+		end = first + minPieces
+	}
+	if end > f.EndPieceIndex() {
+		end = f.EndPieceIndex()
+	}
+	return first, end
 }
 
 func (m *Model) freeEpisodeRAM(fileIdx int) {
-	if fileIdx >= len(m.files) {
+	if fileIdx >= len(m.files) || m.memStore == nil {
 		return
 	}
 	f := m.files[fileIdx]
-	ih := m.torrent.InfoHash()
-	mt := m.memStore.GetTorrent(ih)
+	mt := m.memStore.GetTorrent(f.Torrent().InfoHash())
 	if mt != nil {
 		mt.FreePieces(f.BeginPieceIndex(), f.EndPieceIndex())
 	}
 }
 
-func (m *Model) cleanupPlayback() {
+// ramUsage describes how much piece data is held in memory, against the
+// configured ceiling when there is one.
+func (m Model) ramUsage() string {
+	used := humanSize(m.memStore.BytesInUse())
+	if limit := m.memStore.Limit(); limit > 0 {
+		return fmt.Sprintf("%s / %s", used, humanSize(limit))
+	}
+	return used
+}
+
+// stopSeeding stops downloading and uploading once playback is over, for
+// users who turned SeedAfterPlayback off.
+func (m *Model) stopSeeding() {
+	for _, t := range m.torrents {
+		for _, f := range t.Files() {
+			f.SetPriority(torrent.PiecePriorityNone)
+		}
+		t.DisallowDataUpload()
+	}
+}
+
+// resumeKey identifies f for the resume store.
+func resumeKey(f *torrent.File) string {
+	t := f.Torrent()
+	return config.ResumeKey(t.InfoHash().HexString(), torrentFileIndex(t, f))
+}
+
+// torrentFileIndex returns f's index in the torrent's own file order,
+// which doesn't change with list sorting or filtering.
+func torrentFileIndex(t *torrent.Torrent, f *torrent.File) int {
+	return fileIndex(t.Files(), f)
+}
+
+// fileIndex returns f's index in files, or 0 if it isn't there.
+func fileIndex(files []*torrent.File, f *torrent.File) int {
+	for i, ff := range files {
+		if ff == f {
+			return i
+		}
+	}
+	return 0
+}
+
+// sessionFiles returns the files of every torrent in the session, each
+// torrent's in its own order, torrents in the order they joined. Joining
+// torrents only append, so an index into it keeps naming the same file.
+func (m Model) sessionFiles() []*torrent.File {
+	var files []*torrent.File
+	for _, t := range m.torrents {
+		files = append(files, t.Files()...)
+	}
+	return files
+}
+
+// currentTorrent returns the torrent of the file playing, or last
+// played, else the session's first; nil before any metadata arrived.
+func (m Model) currentTorrent() *torrent.Torrent {
+	if m.currentFile >= 0 && m.currentFile < len(m.files) {
+		return m.files[m.currentFile].Torrent()
+	}
+	if len(m.torrents) > 0 {
+		return m.torrents[0]
+	}
+	return nil
+}
+
+// sessionName heads the file list: the torrent's name, or the first
+// one's and how many more the session holds.
+func (m Model) sessionName() string {
+	switch len(m.torrents) {
+	case 0:
+		return ""
+	case 1:
+		return m.torrents[0].Name()
+	}
+	return fmt.Sprintf("%s and %d more", m.torrents[0].Name(), len(m.torrents)-1)
+}
+
+// restoreLastFile puts the cursor on the file last played from t, if it
+// is listed.
+func (m *Model) restoreLastFile(t *torrent.Torrent) {
+	idx, ok := config.LastFile(t.InfoHash().HexString())
+	if !ok {
+		return
+	}
+	all := t.Files()
+	if idx < 0 || idx >= len(all) {
+		return
+	}
+	for i, f := range m.files {
+		if f == all[idx] {
+			m.cursor = i
+			return
+		}
+	}
+}
+
+// cmdSaveLastFile remembers files[fileIdx] as its torrent's last played
+// file. Failures only cost the cursor position and are dropped.
+func (m Model) cmdSaveLastFile(fileIdx int) tea.Cmd {
+	if fileIdx < 0 || fileIdx >= len(m.files) {
+		return nil
+	}
+	f := m.files[fileIdx]
+	ih := f.Torrent().InfoHash().HexString()
+	idx := torrentFileIndex(f.Torrent(), f)
+	return func() tea.Msg {
+		if err := config.SaveLastFile(ih, idx); err != nil {
+			slog.Warn("save last played file", "err", err)
+		}
+		return nil
+	}
+}
+
+// saveResumePosition stores where playback of the current file stopped.
+// In stream-all mode the playlist position, offset by the file the
+// playlist starts at, picks the file; otherwise it is the single file
+// that was launched.
+func saveResumePosition(files []*torrent.File, streamAll bool, first, startIdx int, mpv player.Controller) {
+	if !mpv.HasIPC() {
+		// Nothing is known about where playback stopped; keep whatever
+		// position was saved before.
+		return
+	}
+	idx := startIdx
+	if streamAll {
+		idx = first + mpv.PlaylistPos()
+	}
+	if idx < 0 || idx >= len(files) {
+		return
+	}
+	pos, dur := mpv.TimePos()
+	if pos <= 0 {
+		// Stopped before mpv reported a position, e.g. while a resumed
+		// file was still buffering: keep the saved one.
+		return
+	}
+	// Resume is best-effort; a failed write must not disturb playback.
+	if err := config.SavePosition(resumeKey(files[idx]), pos, dur); err != nil {
+		slog.Warn("save resume position", "err", err)
+	}
+}
+
+// bumpPlayGen starts a new playback generation, so messages from the
+// player of an earlier one are ignored.
+func (m *Model) bumpPlayGen() {
+	m.playGen++
 	m.shared.mu.Lock()
-	defer m.shared.mu.Unlock()
-	if m.shared.mpv != nil {
-		m.shared.mpv.Kill()
-		m.shared.mpv = nil
+	m.shared.playGen = m.playGen
+	m.shared.mu.Unlock()
+}
+
+// backToFiles ends playback and returns to the file list with the cursor
+// on the file that was playing.
+func (m *Model) backToFiles() tea.Cmd {
+	cmd := m.cleanupPlayback(true)
+	m.queueing = false
+	if !m.cfg.SeedsAfterPlayback() {
+		m.stopSeeding()
 	}
-	if m.shared.server != nil {
-		m.shared.server.Close()
-		m.shared.server = nil
+	m.screen = screenFiles
+	if m.currentFile < len(m.files) {
+		m.cursor = m.currentFile
 	}
+	m.refreshFileProgress()
+	return cmd
 }
 
-func (m *Model) cleanup() {
-	m.cleanupPlayback()
+// serverShutdownGrace bounds how long a normal playback transition waits
+// for in-flight stream responses before closing them.
+const serverShutdownGrace = time.Second
+
+// cleanupPlayback stops mpv and the stream server. When graceful is set,
+// in-flight stream responses get serverShutdownGrace to finish, in the
+// background, instead of being cut off mid-read. The returned command
+// saves the player's last volume.
+func (m *Model) cleanupPlayback(graceful bool) tea.Cmd {
 	m.shared.mu.Lock()
-	defer m.shared.mu.Unlock()
-	if m.shared.client != nil {
-		m.shared.client.Close()
-		m.shared.client = nil
+	mpv, server := m.shared.mpv, m.shared.server
+	m.shared.mpv, m.shared.server = nil, nil
+	if mpv != nil {
+		saveResumePosition(m.files, m.streamAll, m.playlistFirst, m.currentFile, mpv)
+	}
+	m.shared.mu.Unlock()
+
+	// Nothing plays until the next playback starts sampling again.
+	m.downRate, m.upRate, m.smoothDown = 0, 0, 0
+	m.rateSampleAt = time.Time{}
+
+	var cmd tea.Cmd
+	if mpv != nil {
+		if vol, ok := mpv.Volume(); ok {
+			cmd = m.rememberVolume(vol)
+		}
+		mpv.Kill()
+	}
+	if server != nil {
+		server.SetPlaybackState(-1, 0, 0)
+		if graceful {
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), serverShutdownGrace)
+				defer cancel()
+				if err := server.Shutdown(ctx); err != nil {
+					slog.Warn("stop stream server", "err", err)
+				}
+			}()
+		} else if err := server.Close(); err != nil {
+			slog.Warn("stop stream server", "err", err)
+		}
+	}
+	return cmd
+}
+
+// rememberVolume makes vol the starting volume of the next launch. The
+// returned command saves it if it changed.
+func (m *Model) rememberVolume(vol float64) tea.Cmd {
+	v := player.ClampVolume(int(math.Round(vol)))
+	if m.cfg.DefaultVolume != nil && *m.cfg.DefaultVolume == v {
+		return nil
+	}
+	m.cfg.DefaultVolume = &v
+	return cmdSaveVolume(v)
+}
+
+// cmdSaveVolume writes default_volume to the config file, leaving the
+// file's other keys as they are on disk.
+func cmdSaveVolume(v int) tea.Cmd {
+	return func() tea.Msg {
+		cfg, err := config.Load()
+		if err == nil {
+			cfg.DefaultVolume = &v
+			err = config.Save(cfg)
+		}
+		if err != nil {
+			slog.Warn("save volume", "err", err)
+		}
+		return nil
+	}
+}
+
+// shutdownGrace bounds how long quitting waits for the client to drop its
+// torrents and send the final "stopped" announces. Trackers that are slower
+// than this simply time the peer out on their own.
+const shutdownGrace = time.Second
+
+// gracefulShutdown stops playback, drops every torrent so trackers get a
+// stopped announce, then closes the client and memory store. It never
+// blocks the UI for longer than shutdownGrace.
+func (m *Model) gracefulShutdown() {
+	if save := m.cleanupPlayback(false); save != nil {
+		// The program quits next, before a command would run.
+		save()
+	}
+	m.shared.mu.Lock()
+	client := m.shared.client
+	m.shared.client = nil
+	m.shared.mu.Unlock()
+	if client == nil {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, t := range client.Torrents() {
+			t.Drop()
+		}
+		client.Close()
+		if m.memStore != nil {
+			m.memStore.Close()
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(shutdownGrace):
 	}
 }
 
@@ -912,11 +2726,15 @@ func (m *Model) cleanup() {
 
 // configureProxy sets up the torrent client config to route traffic
 // through a SOCKS5 or HTTP proxy.
-func configureProxy(cfg *torrent.ClientConfig, rawURL string) error {
+func configureProxy(cfg *torrent.ClientConfig, rawURL string, bypass []string) error {
 	u, err := url.Parse(rawURL)
 	if err != nil {
 		return fmt.Errorf("parse proxy URL: %w", err)
 	}
+	skip, err := parseProxyBypass(bypass)
+	if err != nil {
+		return err
+	}
 
 	switch u.Scheme {
 	case "socks5", "socks5h":
@@ -940,22 +2758,31 @@ func configureProxy(cfg *torrent.ClientConfig, rawURL string) error {
 		}
 
 		// Route HTTP tracker announces through SOCKS5.
-		cfg.HTTPProxy = http.ProxyURL(u)
+		cfg.HTTPProxy = skip.proxyFunc(u)
 		// Route tracker TCP connections through SOCKS5.
-		cfg.TrackerDialContext = ctxDialer.DialContext
+		cfg.TrackerDialContext = skip.dialContext(ctxDialer.DialContext)
 		// Route webseed HTTP connections through SOCKS5.
 		cfg.HTTPDialContext = ctxDialer.DialContext
 
-		// DHT uses UDP which SOCKS5 cannot proxy; disable it.
+		// The client's DHT would send UDP around the proxy; disable it.
+		// With proxy_udp, addSOCKSDHT relays a DHT through the proxy.
 		cfg.NoDHT = true
 		// Disable local peer discovery (not useful through proxy).
 		cfg.DisablePEX = true
 
 	case "http", "https":
-		// HTTP proxy: only useful for HTTP tracker announces.
-		cfg.HTTPProxy = http.ProxyURL(u)
-		// Cannot proxy peer TCP connections or DHT through HTTP proxy,
-		// but HTTP trackers will be routed through the proxy.
+		// HTTP proxy: route HTTP tracker announces through it.
+		cfg.HTTPProxy = skip.proxyFunc(u)
+		// Tunnel webseed connections with CONNECT. The webseed transport
+		// is given no Proxy func of its own so requests are not sent to
+		// the proxy a second time inside the tunnel.
+		dialer := &httpConnectDialer{proxy: u}
+		cfg.HTTPDialContext = dialer.DialContext
+		cfg.WebTransport = &http.Transport{
+			DialContext:     dialer.DialContext,
+			MaxConnsPerHost: 10,
+		}
+		// Cannot proxy peer TCP connections or DHT through HTTP proxy.
 
 	default:
 		return fmt.Errorf("unsupported proxy scheme %q (use socks5:// or http://)", u.Scheme)
@@ -964,40 +2791,299 @@ func configureProxy(cfg *torrent.ClientConfig, rawURL string) error {
 	return nil
 }
 
+// httpConnectDialer opens TCP connections through an HTTP proxy using the
+// CONNECT method.
+type httpConnectDialer struct {
+	proxy *url.URL
+}
+
+func (d *httpConnectDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	var nd net.Dialer
+	conn, err := nd.DialContext(ctx, "tcp", d.proxy.Host)
+	if err != nil {
+		return nil, fmt.Errorf("dial proxy: %w", err)
+	}
+	if d.proxy.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: d.proxy.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("proxy TLS handshake: %w", err)
+		}
+		conn = tlsConn
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if d.proxy.User != nil {
+		password, _ := d.proxy.User.Password()
+		creds := base64.StdEncoding.EncodeToString([]byte(d.proxy.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+creds)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("send CONNECT: %w", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("read CONNECT response: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT %s: %s", addr, resp.Status)
+	}
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn returns bytes the proxy sent right after its CONNECT
+// response before reading from the connection again.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
 // ──────────────────────────────────────────────
 // Helpers
 // ──────────────────────────────────────────────
 
-func filterMediaFiles(files []*torrent.File) []*torrent.File {
-	exts := map[string]bool{
-		".mkv": true, ".mp4": true, ".avi": true, ".webm": true,
-		".m4v": true, ".mov": true, ".ts": true, ".flv": true,
-		".ogv": true, ".wmv": true,
+// mediaExtensions are the video containers listed by default; the
+// MediaExtensions setting replaces them.
+var mediaExtensions = map[string]bool{
+	".mkv": true, ".mp4": true, ".avi": true, ".webm": true,
+	".m4v": true, ".mov": true, ".ts": true, ".flv": true,
+	".ogv": true, ".wmv": true,
+}
+
+// playableExtensions are formats mpv can open that aren't listed by
+// default. They only matter when a torrent has no regular media files.
+var playableExtensions = map[string]bool{
+	".mpg": true, ".mpeg": true, ".m2ts": true, ".mts": true,
+	".3gp": true, ".divx": true, ".vob": true, ".rmvb": true,
+	".mp3": true, ".flac": true, ".m4a": true, ".ogg": true,
+	".opus": true, ".wav": true,
+}
+
+// mediaExtensionSet returns the extensions listed as media: the
+// configured MediaExtensions, or the defaults when that is empty, plus
+// ExtraMediaExtensions.
+func mediaExtensionSet(c *config.Config) map[string]bool {
+	set := map[string]bool{}
+	if base := normalizeExtensions(c.MediaExtensions); len(base) > 0 {
+		for _, ext := range base {
+			set[ext] = true
+		}
+	} else {
+		for ext := range mediaExtensions {
+			set[ext] = true
+		}
+	}
+	for _, ext := range normalizeExtensions(c.ExtraMediaExtensions) {
+		set[ext] = true
+	}
+	return set
+}
+
+// normalizeExtensions lowercases extensions and gives them a leading dot,
+// dropping empty entries and duplicates.
+func normalizeExtensions(list []string) []string {
+	var out []string
+	for _, ext := range list {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" || ext == "." {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if !slices.Contains(out, ext) {
+			out = append(out, ext)
+		}
 	}
+	return out
+}
+
+// isMediaFile reports whether path has one of the extensions in exts.
+func isMediaFile(path string, exts map[string]bool) bool {
+	return exts[strings.ToLower(filepath.Ext(path))]
+}
+
+// looksPlayable reports whether mpv can likely play path, including
+// formats not in the media list.
+func (m Model) looksPlayable(path string) bool {
+	return isMediaFile(path, mediaExtensionSet(m.cfg)) || playableExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+func filterMediaFiles(files []*torrent.File, exts map[string]bool) []*torrent.File {
 	var media []*torrent.File
 	for _, f := range files {
-		path := strings.ToLower(f.DisplayPath())
-		for ext := range exts {
-			if strings.HasSuffix(path, ext) {
-				media = append(media, f)
-				break
-			}
+		if isMediaFile(f.DisplayPath(), exts) {
+			media = append(media, f)
 		}
 	}
 	return media
 }
 
+// sortMode is the file list order, cycled with s.
+type sortMode int
+
+const (
+	sortByName sortMode = iota
+	sortBySizeDesc
+	sortBySizeAsc
+	sortByProgress
+	numSortModes
+)
+
+func (s sortMode) String() string {
+	switch s {
+	case sortBySizeDesc:
+		return "largest first"
+	case sortBySizeAsc:
+		return "smallest first"
+	case sortByProgress:
+		return "most downloaded"
+	}
+	return "name"
+}
+
+// sortFiles orders files by the current sort mode. Ties, and the name
+// mode itself, fall back to natural name order.
+func (m *Model) sortFiles(files []*torrent.File) {
+	sortFilesByName(files)
+	switch m.sortMode {
+	case sortBySizeDesc:
+		sort.SliceStable(files, func(i, j int) bool { return files[i].Length() > files[j].Length() })
+	case sortBySizeAsc:
+		sort.SliceStable(files, func(i, j int) bool { return files[i].Length() < files[j].Length() })
+	case sortByProgress:
+		if m.filePct == nil {
+			m.refreshFileProgress()
+		}
+		sort.SliceStable(files, func(i, j int) bool { return m.filePct[files[i]] > m.filePct[files[j]] })
+	}
+}
+
+// sortFilesByName orders files the way a person would read them, so
+// "Show - 9.mkv" plays before "Show - 10.mkv".
 func sortFilesByName(files []*torrent.File) {
 	sort.Slice(files, func(i, j int) bool {
-		return files[i].DisplayPath() < files[j].DisplayPath()
+		return naturalLess(files[i].DisplayPath(), files[j].DisplayPath())
 	})
 }
 
+// naturalLess compares a and b with runs of digits compared by numeric
+// value ("S01E09" < "S01E10", "2" < "10"). Ties fall back to a plain
+// string comparison so the order is total.
+func naturalLess(a, b string) bool {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if isDigit(a[i]) && isDigit(b[j]) {
+			si, sj := i, j
+			for i < len(a) && isDigit(a[i]) {
+				i++
+			}
+			for j < len(b) && isDigit(b[j]) {
+				j++
+			}
+			na := strings.TrimLeft(a[si:i], "0")
+			nb := strings.TrimLeft(b[sj:j], "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			continue
+		}
+		if a[i] != b[j] {
+			return a[i] < b[j]
+		}
+		i++
+		j++
+	}
+	if len(a)-i != len(b)-j {
+		return len(a)-i < len(b)-j
+	}
+	return a < b
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// filePercent is how much of f is downloaded, 0-100. It counts bytes,
+// including those of pieces still in flight, so it moves smoothly instead
+// of in whole-piece steps.
+func filePercent(f *torrent.File) float64 {
+	return fraction(f.BytesCompleted(), f.Length()) * 100
+}
+
+// fraction is done/total clamped to 0-1, and 0 when total is.
+func fraction(done, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return min(max(float64(done)/float64(total), 0), 1)
+}
+
+// fileProgress counts the complete pieces in f's piece range.
+func fileProgress(t *torrent.Torrent, f *torrent.File) (completed, total int) {
+	total = f.EndPieceIndex() - f.BeginPieceIndex()
+	for i := f.BeginPieceIndex(); i < f.EndPieceIndex(); i++ {
+		if t.PieceState(i).Complete {
+			completed++
+		}
+	}
+	return completed, total
+}
+
+// clampCursor keeps a list cursor within [0, n).
+func clampCursor(cursor, n int) int {
+	if cursor >= n {
+		cursor = n - 1
+	}
+	if cursor < 0 {
+		cursor = 0
+	}
+	return cursor
+}
+
 func shortName(path string) string {
 	parts := strings.Split(path, "/")
 	return parts[len(parts)-1]
 }
 
+// clock formats seconds as h:mm:ss, or m:ss under an hour.
+func clock(seconds float64) string {
+	d := time.Duration(seconds) * time.Second
+	h := int(d.Hours())
+	mins := int(d.Minutes()) % 60
+	secs := int(d.Seconds()) % 60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, mins, secs)
+	}
+	return fmt.Sprintf("%d:%02d", mins, secs)
+}
+
 func humanSize(bytes int64) string {
 	switch {
 	case bytes >= 1<<30:
@@ -0,0 +1,341 @@
+package tui
+
+import (
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+
+	"github.com/enrell/just-stream/config"
+	"github.com/enrell/just-stream/player"
+	memstorage "github.com/enrell/just-stream/storage"
+)
+
+func TestSessionIdentity(t *testing.T) {
+	const prefix = "-JS0100-"
+	var sh shared
+
+	id, port := sh.sessionIdentity(prefix, 0)
+	if len(id) != 20 || !strings.HasPrefix(id, prefix) {
+		t.Fatalf("peer ID %q: want 20 bytes starting with %q", id, prefix)
+	}
+	if port != 0 {
+		t.Errorf("port before any client bound = %d, want 0 (pick one)", port)
+	}
+
+	// The first client's port is kept; later ones can't replace it.
+	sh.rememberListenPort(42001)
+	sh.rememberListenPort(42002)
+	for i := 0; i < 3; i++ {
+		again, port := sh.sessionIdentity(prefix, 0)
+		if again != id {
+			t.Errorf("client %d: peer ID changed to %q", i, again)
+		}
+		if port != 42001 {
+			t.Errorf("client %d: port %d, want 42001", i, port)
+		}
+	}
+
+	// A configured port always wins, with the same peer ID.
+	if again, port := sh.sessionIdentity(prefix, 6881); again != id || port != 6881 {
+		t.Errorf("with listen_port set: %q, %d; want %q, 6881", again, port, id)
+	}
+
+	var other shared
+	if otherID, _ := other.sessionIdentity(prefix, 0); otherID == id {
+		t.Error("two sessions got the same peer ID")
+	}
+}
+
+func TestParseMagnetDisplayName(t *testing.T) {
+	const hash = "magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567"
+	tests := []struct {
+		name, uri, want string
+	}{
+		{"plain", hash + "&dn=Show", "Show"},
+		{"percent-encoded", hash + "&dn=Show%20%5B1080p%5D", "Show [1080p]"},
+		{"plus for space", hash + "&dn=Show+S01", "Show S01"},
+		{"utf-8", hash + "&dn=%E9%AD%94%E5%A5%B3", "魔女"},
+		{"trimmed", hash + "&dn=+Show+", "Show"},
+		{"dn first", "magnet:?dn=Show&xt=urn:btih:0123456789abcdef0123456789abcdef01234567", "Show"},
+		{"upper-case scheme", "MAGNET:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567&dn=Show", "Show"},
+		{"no dn", hash, ""},
+		{"empty dn", hash + "&dn=", ""},
+		{"bad escape", hash + "&dn=Show%zz", ""},
+		{"http URL", "https://example.com/a.torrent?dn=Show", ""},
+		{"info hash", "0123456789abcdef0123456789abcdef01234567", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseMagnetDisplayName(tt.uri); got != tt.want {
+				t.Errorf("parseMagnetDisplayName(%q) = %q, want %q", tt.uri, got, tt.want)
+			}
+		})
+	}
+}
+
+// newTestTorrent adds a torrent of the given files, name to size, to a
+// client that never touches the network. order is the files' order in
+// the torrent.
+func newTestTorrent(t *testing.T, files map[string]int64, order ...string) *torrent.Torrent {
+	t.Helper()
+	const pieceLen = 16 << 10
+	info := metainfo.Info{Name: "show", PieceLength: pieceLen}
+	var total int64
+	for _, name := range order {
+		info.Files = append(info.Files, metainfo.FileInfo{Path: []string{name}, Length: files[name]})
+		total += files[name]
+	}
+	info.Pieces = make([]byte, 20*((total+pieceLen-1)/pieceLen))
+	infoBytes, err := bencode.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := torrent.NewDefaultClientConfig()
+	cfg.DataDir = t.TempDir()
+	cfg.DefaultStorage = memstorage.NewMemory()
+	cfg.ListenPort = 0
+	cfg.NoDHT = true
+	cfg.DisableTCP = true
+	cfg.DisableUTP = true
+	cfg.NoDefaultPortForwarding = true
+	client, err := torrent.NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	tor, err := client.AddTorrent(&metainfo.MetaInfo{InfoBytes: infoBytes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tor
+}
+
+func listedNames(files []*torrent.File) []string {
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = shortName(f.DisplayPath())
+	}
+	return names
+}
+
+func TestRebuildFileList(t *testing.T) {
+	order := []string{"Show - 10.mkv", "notes.txt", "Show - 9.mkv", "Show - 2.mkv"}
+	tor := newTestTorrent(t, map[string]int64{
+		"Show - 10.mkv": 300 << 10,
+		"notes.txt":     1 << 10,
+		"Show - 9.mkv":  100 << 10,
+		"Show - 2.mkv":  200 << 10,
+	}, order...)
+	m := &Model{cfg: &config.Config{}, torrents: []*torrent.Torrent{tor}}
+
+	check := func(step string, want []string, cursor int) {
+		t.Helper()
+		if got := listedNames(m.files); !slices.Equal(got, want) {
+			t.Errorf("%s: files %q, want %q", step, got, want)
+		}
+		if m.cursor != cursor {
+			t.Errorf("%s: cursor %d, want %d", step, m.cursor, cursor)
+		}
+	}
+
+	m.rebuildFileList()
+	check("by name", []string{"Show - 2.mkv", "Show - 9.mkv", "Show - 10.mkv"}, 0)
+	if !m.hasMedia || m.listedTotal != 3 {
+		t.Errorf("hasMedia %v, listedTotal %d; want true, 3", m.hasMedia, m.listedTotal)
+	}
+
+	// The cursor follows its file when the order changes.
+	m.cursor = 1
+	m.sortMode = sortBySizeDesc
+	m.rebuildFileList()
+	check("by size", []string{"Show - 10.mkv", "Show - 2.mkv", "Show - 9.mkv"}, 2)
+
+	// A filter narrows the list but not the total, and the cursor is
+	// clamped when its file drops out.
+	m.filter = "10"
+	m.rebuildFileList()
+	check("filtered", []string{"Show - 10.mkv"}, 0)
+	if m.listedTotal != 3 {
+		t.Errorf("filtered: listedTotal %d, want 3", m.listedTotal)
+	}
+	m.filter = "nothing matches"
+	m.rebuildFileList()
+	check("no match", []string{}, 0)
+
+	// The torrent's own file order is never touched.
+	if got := listedNames(tor.Files()); !slices.Equal(got, order) {
+		t.Errorf("torrent files reordered to %q", got)
+	}
+
+	// Without media files the list falls back to every file.
+	m.cfg = &config.Config{MediaExtensions: []string{".iso"}}
+	m.filter = ""
+	m.sortMode = sortByName
+	m.rebuildFileList()
+	check("no media", []string{"Show - 2.mkv", "Show - 9.mkv", "Show - 10.mkv", "notes.txt"}, 0)
+	if m.hasMedia || m.listedTotal != 4 {
+		t.Errorf("no media: hasMedia %v, listedTotal %d; want false, 4", m.hasMedia, m.listedTotal)
+	}
+}
+
+func TestRebuildFileListGroupsTorrents(t *testing.T) {
+	first := newTestTorrent(t, map[string]int64{
+		"A - 2.mkv": 96 << 10,
+		"A - 1.mkv": 48 << 10,
+	}, "A - 2.mkv", "A - 1.mkv")
+	second := newTestTorrent(t, map[string]int64{
+		"B - 1.mkv":  320 << 10,
+		"readme.txt": 16 << 10,
+	}, "B - 1.mkv", "readme.txt")
+	m := &Model{cfg: &config.Config{}, torrents: []*torrent.Torrent{first}}
+	m.rebuildFileList()
+	m.cursor = 1
+
+	// A torrent joining is listed after the files already there, which
+	// keep their places.
+	m.joinSession(second)
+	if got, want := listedNames(m.files), []string{"A - 1.mkv", "A - 2.mkv", "B - 1.mkv"}; !slices.Equal(got, want) {
+		t.Fatalf("joined: files %q, want %q", got, want)
+	}
+	if m.cursor != 1 || m.listedTotal != 3 {
+		t.Errorf("joined: cursor %d, listedTotal %d; want 1, 3", m.cursor, m.listedTotal)
+	}
+
+	// Sorting applies within each torrent, never across them.
+	m.sortMode = sortBySizeDesc
+	m.rebuildFileList()
+	if got, want := listedNames(m.files), []string{"A - 2.mkv", "A - 1.mkv", "B - 1.mkv"}; !slices.Equal(got, want) {
+		t.Errorf("by size: files %q, want %q", got, want)
+	}
+
+	// n jumps to the next torrent's files and wraps around.
+	m.cursor = 0
+	m.cursorToNextTorrent()
+	if m.cursor != 2 {
+		t.Errorf("next torrent: cursor %d, want 2", m.cursor)
+	}
+	m.cursorToNextTorrent()
+	if m.cursor != 0 {
+		t.Errorf("wrapped: cursor %d, want 0", m.cursor)
+	}
+
+	// Stream URLs index the session's files, which joining only extends.
+	if got := fileIndex(m.sessionFiles(), second.Files()[0]); got != 2 {
+		t.Errorf("stream index of the second torrent's first file = %d, want 2", got)
+	}
+
+	// Playing the first torrent's last file preloads the start of the
+	// next torrent's first, each on its own torrent.
+	prioritizeFiles(m.files, 1, 1, false, 0, nil)
+	playing, next := m.files[1], m.files[2]
+	if got := first.PieceState(playing.BeginPieceIndex()).Priority; got != torrent.PiecePriorityNow {
+		t.Errorf("playing file's first piece priority %v, want now", got)
+	}
+	if got := second.PieceState(next.BeginPieceIndex()).Priority; got != torrent.PiecePriorityReadahead {
+		t.Errorf("next torrent's first piece priority %v, want readahead", got)
+	}
+	if got := second.PieceState(next.EndPieceIndex() - 1).Priority; got != torrent.PiecePriorityNone {
+		t.Errorf("next torrent's last piece priority %v, want none", got)
+	}
+}
+
+func TestRebuildFileListWithoutTorrent(t *testing.T) {
+	m := &Model{cfg: &config.Config{}, cursor: 3}
+	m.rebuildFileList()
+	if m.files != nil || m.cursor != 3 {
+		t.Errorf("files %v, cursor %d; want nil, 3", m.files, m.cursor)
+	}
+}
+
+func TestNaturalLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"Show - 01.mkv", "Show - 09.mkv", true},
+		{"Show - 09.mkv", "Show - 10.mkv", true},
+		{"Show - 10.mkv", "Show - 100.mkv", true},
+		{"Show - 100.mkv", "Show - 10.mkv", false},
+		{"S01E09.mkv", "S01E10.mkv", true},
+		{"S01E10.mkv", "S01E09.mkv", false},
+		{"S01E10.mkv", "S02E01.mkv", true},
+		{"2", "10", true},
+		{"a", "b", true},
+		{"Show", "Show - 01.mkv", true},
+		// Equal numbers with different padding still have an order.
+		{"Show - 1.mkv", "Show - 01.mkv", false},
+		{"Show - 01.mkv", "Show - 1.mkv", true},
+		{"same", "same", false},
+	}
+	for _, tt := range tests {
+		if got := naturalLess(tt.a, tt.b); got != tt.want {
+			t.Errorf("naturalLess(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	names := []string{"Show - 100.mkv", "Show - 10.mkv", "Show - 01.mkv", "Show - 09.mkv"}
+	slices.SortFunc(names, func(a, b string) int {
+		if naturalLess(a, b) {
+			return -1
+		}
+		if naturalLess(b, a) {
+			return 1
+		}
+		return 0
+	})
+	want := []string{"Show - 01.mkv", "Show - 09.mkv", "Show - 10.mkv", "Show - 100.mkv"}
+	if !slices.Equal(names, want) {
+		t.Errorf("sorted %q, want %q", names, want)
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	if err := CheckConfig(&config.Config{}); err != nil {
+		t.Fatalf("zero config: %v", err)
+	}
+	for _, f := range settingFields {
+		if !slices.Contains(config.Keys(), f.key) {
+			t.Errorf("setting %q: key %q is not a config key", f.label, f.key)
+		}
+	}
+
+	neg, loud := -1, player.MaxVolume+1
+	tests := []struct {
+		key  string
+		edit func(c *config.Config)
+	}{
+		{"readahead_percent", func(c *config.Config) { c.ReadaheadPercent = 101 }},
+		{"startup_boost_percent", func(c *config.Config) { c.StartupBoostPercent = -5 }},
+		{"readahead_bytes", func(c *config.Config) { c.ReadaheadBytes = -1 }},
+		{"readahead_bytes", func(c *config.Config) { c.MemoryLimitMB, c.ReadaheadBytes = 64, 64<<20 }},
+		{"preload_count", func(c *config.Config) { c.PreloadCount = &neg }},
+		{"default_volume", func(c *config.Config) { c.DefaultVolume = &loud }},
+		{"piece_hashers", func(c *config.Config) { c.PieceHashers = maxPieceHashers + 1 }},
+		{"max_conns_per_torrent", func(c *config.Config) { c.MaxConnsPerTorrent = maxConnsPerTorrent + 1 }},
+		{"stream_listen_addr", func(c *config.Config) { c.StreamListenAddr = "localhost" }},
+		{"proxy_bypass", func(c *config.Config) { c.ProxyBypass = []string{"10.0.0.0/33"} }},
+		{"theme", func(c *config.Config) { c.Theme = "neon" }},
+	}
+	if runtime.GOOS != "windows" {
+		tests = append(tests, struct {
+			key  string
+			edit func(c *config.Config)
+		}{"ipc_dir", func(c *config.Config) { c.IPCDir = filepath.Join(t.TempDir(), "missing") }})
+	}
+	for _, tt := range tests {
+		var c config.Config
+		tt.edit(&c)
+		err := CheckConfig(&c)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.key+":") {
+			t.Errorf("%s: got %v, want an error naming the key", tt.key, err)
+		}
+	}
+}
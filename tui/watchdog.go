@@ -0,0 +1,127 @@
+package tui
+
+import (
+	"time"
+
+	"github.com/anacrolix/torrent"
+)
+
+// defaultStallTimeout applies when StallTimeoutSecs is unset.
+const defaultStallTimeout = 30 * time.Second
+
+// stallBoostPieces is how many of the next missing pieces a recovery
+// raises to PiecePriorityNow.
+const stallBoostPieces = 8
+
+// stallTimeout returns the configured stall threshold, or 0 if the
+// watchdog is disabled.
+func (m Model) stallTimeout() time.Duration {
+	switch secs := m.cfg.StallTimeoutSecs; {
+	case secs < 0:
+		return 0
+	case secs == 0:
+		return defaultStallTimeout
+	default:
+		return time.Duration(secs) * time.Second
+	}
+}
+
+// stallWarnTicks is how many ticks in a row the buffer may sit still
+// before the playback screen warns about it.
+const stallWarnTicks = 5
+
+// resetStall restarts stall tracking, e.g. when the playing file changes.
+func (m *Model) resetStall() {
+	m.stallPieces = -1
+	m.stallSince = time.Time{}
+	m.stallKicks = 0
+	m.warnPieces = -1
+	m.stallTicks = 0
+}
+
+// trackStallWarning counts ticks on which the current file gained no
+// pieces while still incomplete. Unlike checkStall it runs even with the
+// watchdog disabled, and with no peers at all, which is when a stall is
+// most likely.
+func (m *Model) trackStallWarning() {
+	if m.currentFile >= len(m.files) {
+		return
+	}
+	f := m.files[m.currentFile]
+	completed, total := fileProgress(f.Torrent(), f)
+	if completed != m.warnPieces || completed >= total {
+		m.warnPieces = completed
+		m.stallTicks = 0
+		return
+	}
+	m.stallTicks++
+}
+
+// stalled reports whether the buffer has been stuck long enough to warn.
+func (m Model) stalled() bool {
+	return m.stallTicks >= stallWarnTicks
+}
+
+// checkStall runs on every tick. If the current file's buffer hasn't grown
+// for the stall timeout while peers are connected, the download is wedged
+// ("stuck at X%"): re-prioritize the pieces playback needs next and look
+// for fresh peers.
+func (m *Model) checkStall(now time.Time) {
+	timeout := m.stallTimeout()
+	if timeout == 0 || m.currentFile >= len(m.files) {
+		return
+	}
+	f := m.files[m.currentFile]
+	t := f.Torrent()
+	completed, total := fileProgress(t, f)
+	if completed != m.stallPieces || completed >= total {
+		m.stallPieces = completed
+		m.stallSince = now
+		return
+	}
+	if t.Stats().ActivePeers == 0 || now.Sub(m.stallSince) < timeout {
+		return
+	}
+
+	m.stallKicks++
+	m.stallSince = now
+	m.setPriorities(m.currentFile)
+	boostMissingPieces(t, f, stallBoostPieces)
+
+	m.shared.mu.Lock()
+	client := m.shared.client
+	m.shared.mu.Unlock()
+	if client != nil {
+		reannounce(client, t)
+	}
+}
+
+// boostMissingPieces raises the first n incomplete pieces of f to
+// PiecePriorityNow.
+func boostMissingPieces(t *torrent.Torrent, f *torrent.File, n int) {
+	for i := f.BeginPieceIndex(); i < f.EndPieceIndex() && n > 0; i++ {
+		if !t.PieceState(i).Complete {
+			t.Piece(i).SetPriority(torrent.PiecePriorityNow)
+			n--
+		}
+	}
+}
+
+// reannounce searches the DHT for fresh peers. The torrent library has no
+// way to force a tracker re-announce, so the DHT is the only lever; the
+// lookup is bounded so repeated stalls don't pile up traversals.
+func reannounce(client *torrent.Client, t *torrent.Torrent) {
+	for _, s := range client.DhtServers() {
+		done, stop, err := t.AnnounceToDht(s)
+		if err != nil {
+			continue
+		}
+		go func() {
+			select {
+			case <-done:
+			case <-time.After(time.Minute):
+				stop()
+			}
+		}()
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+)
+
+// Build metadata, set at build time with
+// -ldflags "-X main.version=... -X main.commit=... -X main.date=...".
+// Unset values fall back to what the Go toolchain embedded.
+var (
+	version = "dev"
+	commit  = ""
+	date    = ""
+)
+
+// buildInfo returns the version, commit and build date, filling gaps from
+// the module and VCS information of `go install` and `go build` builds.
+func buildInfo() (ver, rev, built string) {
+	ver, rev, built = version, commit, date
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ver, rev, built
+	}
+	if ver == "dev" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		ver = info.Main.Version
+	}
+	var vcsRev, vcsTime string
+	dirty := false
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			vcsRev = s.Value
+		case "vcs.time":
+			vcsTime = s.Value
+		case "vcs.modified":
+			dirty = s.Value == "true"
+		}
+	}
+	if rev == "" && vcsRev != "" {
+		rev = vcsRev
+		if dirty {
+			rev += "-dirty"
+		}
+	}
+	if built == "" {
+		built = vcsTime
+	}
+	return ver, rev, built
+}
+
+// printVersion writes the build information users should include in bug
+// reports.
+func printVersion(w io.Writer) {
+	ver, rev, built := buildInfo()
+	if rev == "" {
+		rev = "unknown"
+	}
+	if built == "" {
+		built = "unknown"
+	}
+	fmt.Fprintf(w, "just-stream %s\n", ver)
+	fmt.Fprintf(w, "commit:  %s\n", rev)
+	fmt.Fprintf(w, "built:   %s\n", built)
+	fmt.Fprintf(w, "go:      %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}